@@ -0,0 +1,237 @@
+package framer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// AssetAlarm is an AWS/ALARM composite model of an asset
+type AssetAlarm struct {
+	AssetId         string
+	AssetName       string
+	Name            string
+	StatePropertyId string
+}
+
+func (a AssetAlarm) entryId() string {
+	return *util.GetEntryIdFromAssetProperty(a.AssetId, a.StatePropertyId)
+}
+
+// AssetAlarms is the current state of the alarms. Activations is the state history in the time range,
+// newest first, of the triggered alarms.
+type AssetAlarms struct {
+	Alarms      []AssetAlarm
+	Responses   []*iotsitewise.BatchGetAssetPropertyValueOutput
+	Activations []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput
+}
+
+// AssetAlarmHistory is every state change of the alarms in the time range
+type AssetAlarmHistory struct {
+	Alarms    []AssetAlarm
+	Responses []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput
+}
+
+type alarmFields struct {
+	Time             *data.Field
+	AssetName        *data.Field
+	Name             *data.Field
+	State            *data.Field
+	Severity         *data.Field
+	TriggeredTime    *data.Field
+	AcknowledgedTime *data.Field
+	AssetId          *data.Field
+}
+
+func newAlarmFields() *alarmFields {
+	return &alarmFields{
+		Time:             fields.TimeField(0),
+		AssetName:        fields.AssetNameField(0),
+		Name:             fields.NameField(0),
+		State:            fields.StatusStateField(0),
+		Severity:         fields.SeverityField(0),
+		TriggeredTime:    fields.TriggeredTimeField(0),
+		AcknowledgedTime: fields.AcknowledgedTimeField(0),
+		AssetId:          fields.AssetIdField(0),
+	}
+}
+
+func (f *alarmFields) append(alarm AssetAlarm, ts time.Time, state *models.AlarmState, times alarmTimes) {
+	f.Time.Append(ts)
+	f.AssetName.Append(alarm.AssetName)
+	f.Name.Append(alarm.Name)
+	f.State.Append(state.StateName)
+	f.Severity.Append(state.Severity)
+	f.TriggeredTime.Append(times.triggered)
+	f.AcknowledgedTime.Append(times.acknowledged)
+	f.AssetId.Append(alarm.AssetId)
+}
+
+func (f *alarmFields) frame(name string) *data.Frame {
+	return data.NewFrame(name,
+		f.Time,
+		f.AssetName,
+		f.Name,
+		f.State,
+		f.Severity,
+		f.TriggeredTime,
+		f.AcknowledgedTime,
+		f.AssetId,
+	)
+}
+
+// alarmTimes tracks when the current activation of an alarm was triggered and acknowledged
+type alarmTimes struct {
+	triggered    *time.Time
+	acknowledged *time.Time
+}
+
+func (t *alarmTimes) next(state *models.AlarmState, ts time.Time) {
+	if !state.Triggered() {
+		t.triggered, t.acknowledged = nil, nil
+		return
+	}
+	if state.StateName == models.AlarmStateActive && t.triggered == nil {
+		t.triggered = &ts
+	}
+	if state.Acknowledged() && t.acknowledged == nil {
+		t.acknowledged = &ts
+	}
+}
+
+// activationTimes replays the state history of an alarm, newest first, from its last state in which
+// it was not raised up to its current state
+func activationTimes(alarm AssetAlarm, history []iotsitewisetypes.AssetPropertyValue, state *models.AlarmState, ts time.Time) alarmTimes {
+	start := len(history)
+	for i, value := range history {
+		if previous, err := parseAlarmStateValue(alarm, value); err == nil && !previous.Triggered() {
+			start = i
+			break
+		}
+	}
+
+	times := alarmTimes{}
+	for i := start - 1; i >= 0; i-- {
+		value := history[i]
+		if value.Timestamp == nil || getTime(value.Timestamp).After(ts) {
+			continue
+		}
+		if previous, err := parseAlarmStateValue(alarm, value); err == nil {
+			times.next(previous, getTime(value.Timestamp))
+		}
+	}
+	times.next(state, ts)
+	return times
+}
+
+func parseAlarmStateValue(alarm AssetAlarm, value iotsitewisetypes.AssetPropertyValue) (*models.AlarmState, error) {
+	if value.Value == nil || value.Value.StringValue == nil {
+		return nil, fmt.Errorf("%s %s: missing alarm state", alarm.AssetName, alarm.Name)
+	}
+	state, err := models.ParseAlarmState(*value.Value.StringValue)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: invalid alarm state: %w", alarm.AssetName, alarm.Name, err)
+	}
+	return state, nil
+}
+
+func (a AssetAlarms) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	values := map[string]iotsitewisetypes.AssetPropertyValue{}
+	var notices []data.Notice
+	for _, r := range a.Responses {
+		for _, s := range r.SuccessEntries {
+			if s.AssetPropertyValue != nil {
+				values[*s.EntryId] = *s.AssetPropertyValue
+			}
+		}
+		for _, e := range r.ErrorEntries {
+			notices = append(notices, data.Notice{Severity: data.NoticeSeverityError, Text: util.Dereference(e.ErrorMessage)})
+		}
+	}
+	history := map[string][]iotsitewisetypes.AssetPropertyValue{}
+	for _, r := range a.Activations {
+		for _, s := range r.SuccessEntries {
+			history[*s.EntryId] = append(history[*s.EntryId], s.AssetPropertyValueHistory...)
+		}
+		for _, e := range r.ErrorEntries {
+			notices = append(notices, data.Notice{Severity: data.NoticeSeverityError, Text: util.Dereference(e.ErrorMessage)})
+		}
+	}
+
+	alarmFields := newAlarmFields()
+	for _, alarm := range a.Alarms {
+		value, ok := values[alarm.entryId()]
+		if !ok || value.Timestamp == nil {
+			continue
+		}
+		state, err := parseAlarmStateValue(alarm, value)
+		if err != nil {
+			notices = append(notices, data.Notice{Severity: data.NoticeSeverityWarning, Text: err.Error()})
+			continue
+		}
+
+		ts := getTime(value.Timestamp)
+		alarmFields.append(alarm, ts, state, activationTimes(alarm, history[alarm.entryId()], state, ts))
+	}
+
+	frame := alarmFields.frame("alarms")
+	if len(notices) > 0 {
+		frame.Meta = &data.FrameMeta{Notices: notices}
+	}
+
+	return data.Frames{frame}, nil
+}
+
+func (a AssetAlarmHistory) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	history := map[string][]iotsitewisetypes.AssetPropertyValue{}
+	var notices []data.Notice
+	truncated := false
+	for _, r := range a.Responses {
+		for _, s := range r.SuccessEntries {
+			history[*s.EntryId] = append(history[*s.EntryId], s.AssetPropertyValueHistory...)
+		}
+		for _, e := range r.ErrorEntries {
+			notices = append(notices, data.Notice{Severity: data.NoticeSeverityError, Text: util.Dereference(e.ErrorMessage)})
+		}
+		truncated = truncated || util.Dereference(r.NextToken) != ""
+	}
+
+	alarmFields := newAlarmFields()
+	for _, alarm := range a.Alarms {
+		times := alarmTimes{}
+		for _, value := range history[alarm.entryId()] {
+			if value.Timestamp == nil {
+				continue
+			}
+			state, err := parseAlarmStateValue(alarm, value)
+			if err != nil {
+				notices = append(notices, data.Notice{Severity: data.NoticeSeverityWarning, Text: err.Error()})
+				continue
+			}
+
+			ts := getTime(value.Timestamp)
+			times.next(state, ts)
+			alarmFields.append(alarm, ts, state, times)
+		}
+	}
+
+	frame := alarmFields.frame("alarms")
+	frame.Meta = &data.FrameMeta{
+		Notices: notices,
+		Custom: models.SitewiseCustomMeta{
+			Resolution: models.PropertyQueryResolutionRaw,
+			Truncated:  truncated,
+		},
+	}
+
+	return data.Frames{frame}, nil
+}
@@ -0,0 +1,62 @@
+package framer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+)
+
+// AssetHierarchyNode is an asset of the hierarchy
+type AssetHierarchyNode struct {
+	Id        string
+	Name      string
+	ModelName string
+	Status    string
+}
+
+// AssetHierarchyEdge links a parent asset to a child asset through one of the parent hierarchies
+type AssetHierarchyEdge struct {
+	Source        string
+	Target        string
+	HierarchyId   string
+	HierarchyName string
+}
+
+// AssetHierarchy is framed as the nodes and edges frames of the node graph panel
+type AssetHierarchy struct {
+	Nodes []AssetHierarchyNode
+	Edges []AssetHierarchyEdge
+}
+
+func (a AssetHierarchy) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	nodeId := fields.IdField(len(a.Nodes))
+	title := fields.TitleField(len(a.Nodes))
+	subtitle := fields.SubtitleField(len(a.Nodes))
+	nodeMainStat := fields.MainStatField(len(a.Nodes))
+	for i, node := range a.Nodes {
+		nodeId.Set(i, node.Id)
+		title.Set(i, node.Name)
+		subtitle.Set(i, node.ModelName)
+		nodeMainStat.Set(i, node.Status)
+	}
+
+	edgeId := fields.IdField(len(a.Edges))
+	source := fields.SourceField(len(a.Edges))
+	target := fields.TargetField(len(a.Edges))
+	edgeMainStat := fields.MainStatField(len(a.Edges))
+	for i, edge := range a.Edges {
+		// the hierarchy keeps the ids unique when the assets are linked through several hierarchies
+		edgeId.Set(i, fmt.Sprintf("%s-%s-%s", edge.Source, edge.HierarchyId, edge.Target))
+		source.Set(i, edge.Source)
+		target.Set(i, edge.Target)
+		edgeMainStat.Set(i, edge.HierarchyName)
+	}
+
+	return data.Frames{
+		data.NewFrame("nodes", nodeId, title, subtitle, nodeMainStat).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}),
+		data.NewFrame("edges", edgeId, source, target, edgeMainStat).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}),
+	}, nil
+}
@@ -4,12 +4,136 @@ import (
 	"context"
 
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+const (
+	propertyTypeAttribute   = "ATTRIBUTE"
+	propertyTypeMeasurement = "MEASUREMENT"
+	propertyTypeTransform   = "TRANSFORM"
+	propertyTypeMetric      = "METRIC"
 )
 
 type AssetProperty iotsitewise.DescribeAssetPropertyOutput
 
+// AssetPropertyDescriptions is framed as one row per property
+type AssetPropertyDescriptions []AssetProperty
+
+type describeAssetPropertyFields struct {
+	AssetId           *data.Field
+	AssetName         *data.Field
+	PropertyId        *data.Field
+	Name              *data.Field
+	DataType          *data.Field
+	Unit              *data.Field
+	Alias             *data.Field
+	NotificationState *data.Field
+	NotificationTopic *data.Field
+	PropertyType      *data.Field
+	Expression        *data.Field
+	Variables         *data.Field
+}
+
+func (f *describeAssetPropertyFields) fields() data.Fields {
+	return data.Fields{
+		f.AssetId,
+		f.AssetName,
+		f.PropertyId,
+		f.Name,
+		f.DataType,
+		f.Unit,
+		f.Alias,
+		f.NotificationState,
+		f.NotificationTopic,
+		f.PropertyType,
+		f.Expression,
+		f.Variables,
+	}
+}
+
+func newDescribeAssetPropertyFields(length int) *describeAssetPropertyFields {
+	return &describeAssetPropertyFields{
+		AssetId:           fields.AssetIdField(length),
+		AssetName:         fields.AssetNameField(length),
+		PropertyId:        fields.PropertyIdField(length),
+		Name:              fields.NameField(length),
+		DataType:          fields.DataTypeField(length),
+		Unit:              fields.UnitField(length),
+		Alias:             fields.AliasField(length),
+		NotificationState: fields.NotificationStateField(length),
+		NotificationTopic: fields.NotificationTopicField(length),
+		PropertyType:      fields.PropertyTypeField(length),
+		Expression:        fields.ExpressionField(length),
+		Variables:         fields.VariablesField(length),
+	}
+}
+
 func (ap AssetProperty) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
-	panic("implement me!!!")
+	return AssetPropertyDescriptions{ap}.Frames(ctx, resources)
+}
+
+func (ap AssetPropertyDescriptions) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	propertyFields := newDescribeAssetPropertyFields(len(ap))
+
+	for i, p := range ap {
+		description := iotsitewise.DescribeAssetPropertyOutput(p)
+		property := description.AssetProperty
+		if util.IsComponentProperty(&description) {
+			property = description.CompositeModel.AssetProperty
+		}
+		if property == nil {
+			property = &iotsitewisetypes.Property{}
+		}
+
+		propertyFields.AssetId.Set(i, util.Dereference(p.AssetId))
+		propertyFields.AssetName.Set(i, util.Dereference(p.AssetName))
+		propertyFields.PropertyId.Set(i, util.Dereference(property.Id))
+		propertyFields.Name.Set(i, util.Dereference(property.Name))
+		propertyFields.DataType.Set(i, string(property.DataType))
+		propertyFields.Unit.Set(i, property.Unit)
+		propertyFields.Alias.Set(i, util.Dereference(property.Alias))
+
+		if n := property.Notification; n != nil {
+			state := string(n.State)
+			propertyFields.NotificationState.Set(i, &state)
+			propertyFields.NotificationTopic.Set(i, n.Topic)
+		}
+
+		propertyType, expression, variables := describePropertyType(property.Type)
+		propertyFields.PropertyType.Set(i, propertyType)
+		propertyFields.Expression.Set(i, expression)
+		if variables != nil {
+			serialized, err := serialize(variables)
+			if err != nil {
+				return nil, err
+			}
+			propertyFields.Variables.Set(i, &serialized)
+		}
+	}
+
+	frame := data.NewFrame("", propertyFields.fields()...)
+
+	return data.Frames{frame}, nil
+}
+
+// describePropertyType returns the kind of the property, and the formula of transforms and metrics
+func describePropertyType(t *iotsitewisetypes.PropertyType) (string, *string, []iotsitewisetypes.ExpressionVariable) {
+	switch {
+	case t == nil:
+		return "", nil, nil
+	case t.Transform != nil:
+		return propertyTypeTransform, t.Transform.Expression, t.Transform.Variables
+	case t.Metric != nil:
+		return propertyTypeMetric, t.Metric.Expression, t.Metric.Variables
+	case t.Measurement != nil:
+		return propertyTypeMeasurement, nil, nil
+	case t.Attribute != nil:
+		return propertyTypeAttribute, nil, nil
+	default:
+		return "", nil, nil
+	}
 }
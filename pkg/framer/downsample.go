@@ -0,0 +1,121 @@
+package framer
+
+import (
+	"math"
+	"slices"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// downsample reduces the raw values to the max data points of the query with its downsample mode, it
+// returns the original number of values, or 0 when the values are kept as is. Only numeric values
+// are downsampled, in the order of the query.
+func downsample(query models.AssetPropertyValueQuery, h []iotsitewisetypes.AssetPropertyValue) ([]iotsitewisetypes.AssetPropertyValue, int) {
+	threshold := int(query.MaxDataPoints)
+	if threshold < 3 || len(h) <= threshold {
+		return h, 0
+	}
+
+	x := make([]float64, len(h))
+	y := make([]float64, len(h))
+	for i, v := range h {
+		value, ok := numericValue(v.Value)
+		if !ok || v.Timestamp == nil {
+			return h, 0
+		}
+		x[i] = float64(getTime(v.Timestamp).Sub(getTime(h[0].Timestamp)))
+		y[i] = value
+	}
+
+	var keep []int
+	switch query.Downsample {
+	case models.DownsampleLTTB:
+		keep = lttb(x, y, threshold)
+	case models.DownsampleMinMax:
+		keep = minMax(x, y, threshold/2)
+	default:
+		return h, 0
+	}
+
+	downsampled := make([]iotsitewisetypes.AssetPropertyValue, len(keep))
+	for i, idx := range keep {
+		downsampled[i] = h[idx]
+	}
+	return downsampled, len(h)
+}
+
+// lttb selects threshold points with the Largest-Triangle-Three-Buckets algorithm: the first and last points,
+// and the point of each bucket forming the largest triangle with the previous selected point and the
+// average of the next bucket
+func lttb(x, y []float64, threshold int) []int {
+	n := len(x)
+	every := float64(n-2) / float64(threshold-2)
+	keep := make([]int, 0, threshold)
+	keep = append(keep, 0)
+
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		// the average of the next bucket, which is the last point for the last bucket
+		nextStart := int(float64(i+1)*every) + 1
+		nextEnd := min(int(float64(i+2)*every)+1, n)
+		avgX, avgY := 0.0, 0.0
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x[j]
+			avgY += y[j]
+		}
+		count := float64(nextEnd - nextStart)
+		avgX /= count
+		avgY /= count
+
+		start := int(float64(i)*every) + 1
+		end := int(float64(i+1)*every) + 1
+		maxArea, next := -1.0, start
+		for j := start; j < end; j++ {
+			area := math.Abs((x[a]-avgX)*(y[j]-y[a]) - (x[a]-x[j])*(avgY-y[a]))
+			if area > maxArea {
+				maxArea, next = area, j
+			}
+		}
+		keep = append(keep, next)
+		a = next
+	}
+	return append(keep, n-1)
+}
+
+// minMax keeps the minimum and maximum of each of the time buckets between the first and last points
+func minMax(x, y []float64, buckets int) []int {
+	span := math.Abs(x[len(x)-1])
+	if buckets < 1 || span == 0 {
+		return []int{0, len(x) - 1}
+	}
+
+	minIdx := make([]int, buckets)
+	maxIdx := make([]int, buckets)
+	for b := range minIdx {
+		minIdx[b], maxIdx[b] = -1, -1
+	}
+	for i := range x {
+		b := min(int(math.Abs(x[i])/span*float64(buckets)), buckets-1)
+		if minIdx[b] < 0 || y[i] < y[minIdx[b]] {
+			minIdx[b] = i
+		}
+		if maxIdx[b] < 0 || y[i] > y[maxIdx[b]] {
+			maxIdx[b] = i
+		}
+	}
+
+	keep := make([]int, 0, 2*buckets)
+	for b := range minIdx {
+		if minIdx[b] < 0 {
+			continue
+		}
+		keep = append(keep, minIdx[b])
+		if maxIdx[b] != minIdx[b] {
+			keep = append(keep, maxIdx[b])
+		}
+	}
+	slices.Sort(keep)
+	return keep
+}
@@ -27,4 +27,24 @@ const (
 	TimeSeriesId  	 = "timeSeriesId"
 	TimeSeriesCreationDate = "timeSeriesCreationDate"
 	TimeSeriesLastUpdateDate = "timeSeriesLastUpdateDate"
+	TimeEnd          = "timeEnd"
+	Text             = "text"
+	Tags             = "tags"
+	AssetName        = "assetName"
+	Severity         = "severity"
+	TriggeredTime    = "triggeredTime"
+	AcknowledgedTime = "acknowledgedTime"
+	Title            = "title"
+	Subtitle         = "subtitle"
+	MainStat         = "mainstat"
+	Source           = "source"
+	Target           = "target"
+	Unit             = "unit"
+	NotificationState = "notificationState"
+	NotificationTopic = "notificationTopic"
+	PropertyType     = "propertyType"
+	Expression       = "expression"
+	Variables        = "variables"
+	Shift            = "shift"
+	Stale            = "stale"
 )
@@ -105,6 +105,14 @@ func AggregationField(length int, name string) *data.Field {
 	return NewFieldWithName(name, data.FieldTypeFloat64, length)
 }
 
+func ShiftField(shifts []string) *data.Field {
+	return data.NewField(Shift, nil, shifts)
+}
+
+func StaleField(length int) *data.Field {
+	return NewFieldWithName(Stale, data.FieldTypeBool, length)
+}
+
 func AnomalyScoreField(length int) *data.Field {
 	return NewFieldWithName(AnomalyScore, data.FieldTypeFloat64, length)
 }
@@ -154,3 +162,83 @@ func TimeSeriesCreationDateField(length int) *data.Field {
 func TimeSeriesLastUpdateDateField(length int) *data.Field {
 	return NewFieldWithName(TimeSeriesLastUpdateDate, data.FieldTypeTime, length)
 }
+
+// for annotations
+
+func TimeEndField(length int) *data.Field {
+	return NewFieldWithName(TimeEnd, data.FieldTypeTime, length)
+}
+
+func TextField(length int) *data.Field {
+	return NewFieldWithName(Text, data.FieldTypeString, length)
+}
+
+func TagsField(length int) *data.Field {
+	return NewFieldWithName(Tags, data.FieldTypeJSON, length)
+}
+
+// for alarms
+
+func AssetNameField(length int) *data.Field {
+	return NewFieldWithName(AssetName, data.FieldTypeString, length)
+}
+
+func SeverityField(length int) *data.Field {
+	return NewFieldWithName(Severity, data.FieldTypeNullableInt64, length)
+}
+
+func TriggeredTimeField(length int) *data.Field {
+	return NewFieldWithName(TriggeredTime, data.FieldTypeNullableTime, length)
+}
+
+func AcknowledgedTimeField(length int) *data.Field {
+	return NewFieldWithName(AcknowledgedTime, data.FieldTypeNullableTime, length)
+}
+
+// for node graphs
+
+func TitleField(length int) *data.Field {
+	return NewFieldWithName(Title, data.FieldTypeString, length)
+}
+
+func SubtitleField(length int) *data.Field {
+	return NewFieldWithName(Subtitle, data.FieldTypeString, length)
+}
+
+func MainStatField(length int) *data.Field {
+	return NewFieldWithName(MainStat, data.FieldTypeString, length)
+}
+
+func SourceField(length int) *data.Field {
+	return NewFieldWithName(Source, data.FieldTypeString, length)
+}
+
+func TargetField(length int) *data.Field {
+	return NewFieldWithName(Target, data.FieldTypeString, length)
+}
+
+// for property descriptions
+
+func UnitField(length int) *data.Field {
+	return NewFieldWithName(Unit, data.FieldTypeNullableString, length)
+}
+
+func NotificationStateField(length int) *data.Field {
+	return NewFieldWithName(NotificationState, data.FieldTypeNullableString, length)
+}
+
+func NotificationTopicField(length int) *data.Field {
+	return NewFieldWithName(NotificationTopic, data.FieldTypeNullableString, length)
+}
+
+func PropertyTypeField(length int) *data.Field {
+	return NewFieldWithName(PropertyType, data.FieldTypeString, length)
+}
+
+func ExpressionField(length int) *data.Field {
+	return NewFieldWithName(Expression, data.FieldTypeNullableString, length)
+}
+
+func VariablesField(length int) *data.Field {
+	return NewFieldWithName(Variables, data.FieldTypeNullableString, length)
+}
@@ -0,0 +1,160 @@
+package framer
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// fillGap is how long before the next value a gap is filled up to, so the filled row draws flat into it
+const fillGap = time.Millisecond
+
+// applyFill fills the gaps between the values of the frame with the fill of the query, and adds the stale field
+// with a staleness threshold. A gap longer than the threshold is stale: the value after it, and the rows
+// filled into it, are marked stale. Without a threshold, FillPrevious fills every gap.
+func applyFill(query models.AssetPropertyValueQuery, frame *data.Frame) *data.Frame {
+	threshold, _ := query.Staleness()
+	timeIdx := timeFieldIndex(frame)
+	if timeIdx < 0 || (threshold == 0 && query.Fill != models.FillPrevious) {
+		return frame
+	}
+	qualityIdx := qualityFieldIndex(frame)
+	if query.Fill == models.FillNull {
+		nullableValueFields(frame, qualityIdx)
+	}
+
+	times := frame.Fields[timeIdx]
+	rows := frame.Rows()
+	descending := rows > 1 && times.At(0).(time.Time).After(times.At(rows-1).(time.Time))
+
+	filled := frame.EmptyCopy()
+	filled.Meta = frame.Meta
+	for j, field := range frame.Fields {
+		filled.Fields[j].Config = field.Config
+	}
+	stale := []bool{}
+
+	for i := 0; i < rows; i++ {
+		if i == 0 {
+			appendRowCopy(filled, frame, i)
+			stale = append(stale, false)
+			continue
+		}
+		earlier, later := i-1, i
+		if descending {
+			earlier, later = i, i-1
+		}
+		from, to := times.At(earlier).(time.Time), times.At(later).(time.Time)
+		gapStale := threshold > 0 && to.Sub(from) > threshold
+
+		fill := []time.Time{}
+		switch query.Fill {
+		case models.FillPrevious:
+			if threshold == 0 || gapStale {
+				fill = append(fill, to.Add(-fillGap))
+			}
+		case models.FillNull:
+			if gapStale {
+				fill = append(fill, from.Add(threshold))
+			}
+		case models.FillZero:
+			if gapStale {
+				fill = append(fill, from.Add(threshold), to.Add(-fillGap))
+			}
+		}
+		fill = fillTimesBetween(fill, from, to)
+
+		rowStale := gapStale
+		if descending {
+			// the value after the gap was the previous row
+			stale[len(stale)-1] = gapStale
+			rowStale = false
+			for k := len(fill) - 1; k >= 0; k-- {
+				appendFilledRow(query.Fill, filled, frame, earlier, timeIdx, qualityIdx, fill[k])
+				stale = append(stale, true)
+			}
+		} else {
+			for _, t := range fill {
+				appendFilledRow(query.Fill, filled, frame, earlier, timeIdx, qualityIdx, t)
+				stale = append(stale, true)
+			}
+		}
+		appendRowCopy(filled, frame, i)
+		stale = append(stale, rowStale)
+	}
+
+	if threshold > 0 {
+		staleField := fields.StaleField(len(stale))
+		for i, s := range stale {
+			staleField.Set(i, s)
+		}
+		filled.Fields = append(filled.Fields, staleField)
+	}
+	return filled
+}
+
+// fillTimesBetween keeps the fill times strictly between the values around the gap, in ascending order
+func fillTimesBetween(fill []time.Time, from, to time.Time) []time.Time {
+	kept := []time.Time{}
+	for _, t := range fill {
+		if t.After(from) && t.Before(to) && (len(kept) == 0 || t.After(kept[len(kept)-1])) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func timeFieldIndex(frame *data.Frame) int {
+	for i, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime {
+			return i
+		}
+	}
+	return -1
+}
+
+func appendRowCopy(filled *data.Frame, frame *data.Frame, row int) {
+	for j, field := range frame.Fields {
+		filled.Fields[j].Append(field.CopyAt(row))
+	}
+}
+
+// appendFilledRow appends a row at t filled from the row before the gap: the value is held for FillPrevious,
+// null for FillNull and zero for FillZero, other fields and the values that are not numbers hold
+func appendFilledRow(fill string, filled *data.Frame, frame *data.Frame, row int, timeIdx int, qualityIdx int, t time.Time) {
+	idx := filled.Rows()
+	for j, field := range frame.Fields {
+		filled.Fields[j].Extend(1)
+		switch {
+		case j == timeIdx:
+			filled.Fields[j].Set(idx, t)
+		case isValueField(frame, j, qualityIdx) && fill == models.FillNull:
+			// the nullable field extends with a null
+		case isValueField(frame, j, qualityIdx) && fill == models.FillZero && field.Type().Numeric():
+			filled.Fields[j].SetConcrete(idx, data.NewFieldFromFieldType(field.Type().NonNullableType(), 1).At(0))
+		default:
+			filled.Fields[j].Set(idx, field.CopyAt(row))
+		}
+	}
+}
+
+// nullableValueFields replaces the value fields by nullable fields with the same values
+func nullableValueFields(frame *data.Frame, qualityIdx int) {
+	for j, field := range frame.Fields {
+		if !isValueField(frame, j, qualityIdx) || field.Type().Nullable() {
+			continue
+		}
+		nullable := data.NewFieldFromFieldType(field.Type().NullableType(), field.Len())
+		nullable.Name = field.Name
+		nullable.Labels = field.Labels
+		nullable.Config = field.Config
+		for i := 0; i < field.Len(); i++ {
+			if v, ok := field.ConcreteAt(i); ok {
+				nullable.SetConcrete(i, v)
+			}
+		}
+		frame.Fields[j] = nullable
+	}
+}
@@ -0,0 +1,202 @@
+package framer
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// flattenJson parses the string values as JSON documents and returns a field of each JSON path of the query,
+// or of the paths discovered from the first documents, sorted. A field is a number, bool, time (RFC 3339
+// strings) or string field depending on its values, and is null where a document misses its path. It
+// returns false when none of the values is a JSON object or array.
+func flattenJson(query models.AssetPropertyValueQuery, h []iotsitewisetypes.AssetPropertyValue) ([]*data.Field, bool) {
+	documents := make([]any, len(h))
+	discovery := []any{}
+	for i, v := range h {
+		if v.Value == nil || v.Value.StringValue == nil {
+			continue
+		}
+		var document any
+		if err := json.Unmarshal([]byte(*v.Value.StringValue), &document); err != nil {
+			continue
+		}
+		switch document.(type) {
+		case map[string]any, []any:
+			documents[i] = document
+			if len(discovery) < query.JsonDiscoveryRowCount() {
+				discovery = append(discovery, document)
+			}
+		}
+	}
+	if len(discovery) == 0 {
+		return nil, false
+	}
+
+	paths := query.JsonPaths
+	if len(paths) == 0 {
+		paths = discoverJsonPaths(discovery)
+	}
+
+	jsonFields := make([]*data.Field, 0, len(paths))
+	for _, path := range paths {
+		keys := splitJsonPath(path)
+		values := make([]any, len(documents))
+		for i, document := range documents {
+			values[i] = lookupJsonPath(document, keys)
+		}
+		jsonFields = append(jsonFields, jsonField(path, values))
+	}
+	return jsonFields, true
+}
+
+// splitJsonPath splits a path like $.readings.0.value in its keys
+func splitJsonPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupJsonPath returns the value at the keys of the document, nil when it is missing
+func lookupJsonPath(document any, keys []string) any {
+	for _, key := range keys {
+		switch node := document.(type) {
+		case map[string]any:
+			document = node[key]
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil
+			}
+			document = node[idx]
+		default:
+			return nil
+		}
+	}
+	return document
+}
+
+// discoverJsonPaths returns the paths of the scalar values of the documents, the keys that only some
+// documents have included, sorted by key with the array indices in numeric order
+func discoverJsonPaths(documents []any) []string {
+	found := map[string][]string{}
+	var walk func(node any, keys []string)
+	walk = func(node any, keys []string) {
+		switch node := node.(type) {
+		case map[string]any:
+			for key, child := range node {
+				walk(child, append(slices.Clone(keys), key))
+			}
+		case []any:
+			for idx, child := range node {
+				walk(child, append(slices.Clone(keys), strconv.Itoa(idx)))
+			}
+		default:
+			if len(keys) > 0 {
+				found[strings.Join(keys, ".")] = keys
+			}
+		}
+	}
+	for _, document := range documents {
+		walk(document, nil)
+	}
+
+	keys := make([][]string, 0, len(found))
+	for _, k := range found {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, compareJsonPaths)
+
+	paths := make([]string, len(keys))
+	for i, k := range keys {
+		paths[i] = strings.Join(k, ".")
+	}
+	return paths
+}
+
+func compareJsonPaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aErr := strconv.Atoi(a[i])
+		bi, bErr := strconv.Atoi(b[i])
+		if aErr == nil && bErr == nil {
+			return ai - bi
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return len(a) - len(b)
+}
+
+// jsonField returns a field of the type shared by the values, a string field when their types differ
+func jsonField(path string, values []any) *data.Field {
+	fieldType := data.FieldTypeUnknown
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		t := jsonValueType(v)
+		if fieldType == data.FieldTypeUnknown {
+			fieldType = t
+		} else if fieldType != t {
+			fieldType = data.FieldTypeNullableString
+			break
+		}
+	}
+	if fieldType == data.FieldTypeUnknown {
+		fieldType = data.FieldTypeNullableString
+	}
+
+	field := data.NewFieldFromFieldType(fieldType, len(values))
+	field.Name = path
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		switch fieldType {
+		case data.FieldTypeNullableFloat64:
+			field.SetConcrete(i, v.(float64))
+		case data.FieldTypeNullableBool:
+			field.SetConcrete(i, v.(bool))
+		case data.FieldTypeNullableTime:
+			t, _ := time.Parse(time.RFC3339Nano, v.(string))
+			field.SetConcrete(i, t)
+		default:
+			field.SetConcrete(i, jsonString(v))
+		}
+	}
+	return field
+}
+
+func jsonValueType(v any) data.FieldType {
+	switch v := v.(type) {
+	case float64:
+		return data.FieldTypeNullableFloat64
+	case bool:
+		return data.FieldTypeNullableBool
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return data.FieldTypeNullableTime
+		}
+	}
+	return data.FieldTypeNullableString
+}
+
+// jsonString returns strings as is and the other values, like the objects of a path, as JSON
+func jsonString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,93 @@
+package framer
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// syntheticBuckets holds the synthetic aggregates of the raw values of an entry, by bucket start
+type syntheticBuckets struct {
+	buckets []RollupBucket
+	values  map[int64]map[string]float64
+}
+
+// syntheticAggregates computes the synthetic aggregates of the numeric raw values of each bucket. The buckets
+// are the ones of the grid, so that buckets without raw values have a row as well, followed by the buckets
+// of the raw values outside of the grid.
+func syntheticAggregates(history []iotsitewisetypes.AssetPropertyValue, rollup Rollup, grid []RollupBucket, aggregateTypes []string) syntheticBuckets {
+	buckets := slices.Clone(grid)
+	samples := map[int64][]float64{}
+	for _, bucket := range grid {
+		samples[bucket.Start.UnixNano()] = nil
+	}
+	for _, v := range history {
+		value, ok := numericValue(v.Value)
+		if !ok || v.Timestamp == nil {
+			continue
+		}
+		bucket, ok := rollup(getTime(v.Timestamp))
+		if !ok {
+			continue
+		}
+		key := bucket.Start.UnixNano()
+		if _, ok := samples[key]; !ok {
+			buckets = append(buckets, bucket)
+		}
+		samples[key] = append(samples[key], value)
+	}
+
+	synthetic := syntheticBuckets{buckets: buckets, values: map[int64]map[string]float64{}}
+	for key, values := range samples {
+		slices.Sort(values)
+		synthetic.values[key] = map[string]float64{}
+		for _, aggregateType := range aggregateTypes {
+			if p, ok := models.SyntheticAggregatePercentiles[aggregateType]; ok && len(values) > 0 {
+				synthetic.values[key][aggregateType] = percentile(values, p)
+			}
+		}
+	}
+	return synthetic
+}
+
+// percentile interpolates linearly between the closest ranks of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func numericValue(v *iotsitewisetypes.Variant) (float64, bool) {
+	switch {
+	case v == nil:
+		return 0, false
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, true
+	case v.IntegerValue != nil:
+		return float64(*v.IntegerValue), true
+	}
+	return 0, false
+}
+
+// syntheticFields returns a field of each synthetic aggregate for the bucket starts, null where a bucket has no raw values
+func (b syntheticBuckets) syntheticFields(starts []time.Time, aggregateTypes []string) []*data.Field {
+	fields := make([]*data.Field, 0, len(aggregateTypes))
+	for _, aggregateType := range aggregateTypes {
+		field := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, len(starts))
+		field.Name = strings.ToLower(aggregateType)
+		for i, start := range starts {
+			if value, ok := b.values[start.UnixNano()][aggregateType]; ok {
+				field.Set(i, &value)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -2,6 +2,12 @@ package framer
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
@@ -13,9 +19,29 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
 )
 
+// Limits the raw values of the synthetic aggregates are capped by
+const (
+	RawTruncatedDataPoints = "dataPoints"
+	RawTruncatedTimeout    = "timeout"
+)
+
 type AssetPropertyAggregatesBatch struct {
 	Requests  []iotsitewise.BatchGetAssetPropertyAggregatesInput
 	Responses []iotsitewise.BatchGetAssetPropertyAggregatesOutput
+	Query     models.AssetPropertyValueQuery
+	// Rollup re-aggregates the native aggregates of the requests into the buckets of the query resolution
+	Rollup Rollup
+	// RawValues are the raw values the synthetic aggregates are computed from, in the RawBuckets of the
+	// RawResolution. RawGrid holds every bucket of the time range. RawTruncation is the limit the raw
+	// values were capped by, empty when they are complete.
+	RawValues     []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput
+	RawBuckets    Rollup
+	RawGrid       []RollupBucket
+	RawResolution string
+	RawTruncation string
+	// RawLastObservations are the last raw values before the time range, by entry id, the time
+	// weighted aggregates hold them from the start of the first bucket
+	RawLastObservations map[string]iotsitewisetypes.AssetPropertyValue
 }
 
 // getAggregationFields enforces ordering of aggregate fields
@@ -104,41 +130,155 @@ func (a AssetPropertyAggregatesBatch) Frames(ctx context.Context, resources reso
 		return nil, err
 	}
 
+	synthetic := a.syntheticAggregates()
+
 	for i, r := range a.Responses {
 		request := a.Requests[i]
 		for j, e := range r.SuccessEntries {
 			property := properties[*e.EntryId]
-			frame, err := a.Frame(ctx, property, e.AggregatedValues)
+			values := e.AggregatedValues
+			resolution := util.Dereference(request.Entries[j].Resolution)
+			aggregateTypes := request.Entries[j].AggregateTypes
+			var shifts []string
+			if a.Rollup != nil {
+				values, shifts = rollupAggregates(values, a.Rollup, a.Query.AggregateTypes)
+				resolution = fmt.Sprintf("%s (derived from %s)", a.Query.Resolution, resolution)
+			}
+			if a.Rollup != nil || synthetic != nil {
+				aggregateTypes = a.Query.AggregateTypes
+			}
+			frame, err := a.Frame(ctx, property, values)
 			if err != nil {
 				return nil, err
 			}
+			if synthetic != nil && len(values) > 0 {
+				a.appendSyntheticFields(frame, property, synthetic[*e.EntryId], values)
+			}
+			if a.Query.Resolution == models.ResolutionShift && len(values) > 0 {
+				frame.Fields = slices.Insert(frame.Fields, 1, fields.ShiftField(shifts))
+			}
 
 			frame.Meta = &data.FrameMeta{
 				Custom: models.SitewiseCustomMeta{
 					NextToken:  util.Dereference(r.NextToken),
 					EntryId:    *e.EntryId,
-					Resolution: util.Dereference(request.Entries[j].Resolution),
-					Aggregates: aggregateTypesToStrings(request.Entries[j].AggregateTypes),
+					Resolution: resolution,
+					Aggregates: aggregateTypesToStrings(aggregateTypes),
+					Truncated:  (a.Query.AutoPaginate && util.Dereference(r.NextToken) != "") || a.RawTruncation != "",
 				},
 			}
+			a.appendRawTruncatedNotice(frame)
 			frames = append(frames, frame)
 		}
 
 		for _, e := range r.ErrorEntries {
-			property := properties[*e.EntryId]
-			frame := data.NewFrame(getFrameName(property))
-			if e.ErrorMessage != nil {
+			frames = append(frames, errorEntryFrame(properties[*e.EntryId], e.ErrorMessage))
+		}
+	}
+
+	// without native aggregates, the frames only hold the synthetic aggregates of the raw values
+	if len(a.Responses) == 0 {
+		for _, r := range a.RawValues {
+			for _, e := range r.SuccessEntries {
+				property := properties[*e.EntryId]
+				buckets := synthetic[*e.EntryId]
+				values := make([]iotsitewisetypes.AggregatedValue, 0, len(buckets.buckets))
+				shifts := make([]string, 0, len(buckets.buckets))
+				for _, b := range buckets.buckets {
+					values = append(values, iotsitewisetypes.AggregatedValue{Timestamp: aws.Time(b.Start), Value: &iotsitewisetypes.Aggregates{}})
+					shifts = append(shifts, b.Shift)
+				}
+				if a.Query.TimeOrdering == iotsitewisetypes.TimeOrderingDescending {
+					slices.Reverse(values)
+					slices.Reverse(shifts)
+				}
+				frame, err := a.Frame(ctx, property, values)
+				if err != nil {
+					return nil, err
+				}
+				if len(values) > 0 {
+					a.appendSyntheticFields(frame, property, buckets, values)
+				}
+				if a.Query.Resolution == models.ResolutionShift && len(values) > 0 {
+					frame.Fields = slices.Insert(frame.Fields, 1, fields.ShiftField(shifts))
+				}
 				frame.Meta = &data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *e.ErrorMessage}},
+					Custom: models.SitewiseCustomMeta{
+						EntryId:    *e.EntryId,
+						Resolution: fmt.Sprintf("%s (derived from %s)", a.RawResolution, models.PropertyQueryResolutionRaw),
+						Aggregates: aggregateTypesToStrings(a.Query.AggregateTypes),
+						Truncated:  a.RawTruncation != "",
+					},
 				}
+				a.appendRawTruncatedNotice(frame)
+				frames = append(frames, frame)
+			}
+
+			for _, e := range r.ErrorEntries {
+				frames = append(frames, errorEntryFrame(properties[*e.EntryId], e.ErrorMessage))
 			}
-			frames = append(frames, frame)
 		}
 	}
 
 	return frames, nil
 }
 
+func errorEntryFrame(property *iotsitewise.DescribeAssetPropertyOutput, errorMessage *string) *data.Frame {
+	frame := data.NewFrame(getFrameName(property))
+	if errorMessage != nil {
+		frame.Meta = &data.FrameMeta{
+			Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *errorMessage}},
+		}
+	}
+	return frame
+}
+
+// syntheticAggregates computes the synthetic aggregates of the raw values of each entry, it is nil without synthetic aggregates
+func (a AssetPropertyAggregatesBatch) syntheticAggregates() map[string]syntheticBuckets {
+	aggregateTypes := a.Query.SyntheticAggregateTypes()
+	if len(aggregateTypes) == 0 || a.RawBuckets == nil {
+		return nil
+	}
+	synthetic := map[string]syntheticBuckets{}
+	for _, r := range a.RawValues {
+		for _, e := range r.SuccessEntries {
+			buckets := syntheticAggregates(e.AssetPropertyValueHistory, a.RawBuckets, a.RawGrid, aggregateTypes)
+			if a.Query.TimeWeighted() {
+				history := e.AssetPropertyValueHistory
+				if last, ok := a.RawLastObservations[*e.EntryId]; ok {
+					history = append([]iotsitewisetypes.AssetPropertyValue{last}, history...)
+				}
+				buckets.addTimeWeighted(history, a.Query.TimeWeightedInterpolation, a.Query.TimeRange.To)
+			}
+			synthetic[*e.EntryId] = buckets
+		}
+	}
+	return synthetic
+}
+
+func (a AssetPropertyAggregatesBatch) appendSyntheticFields(frame *data.Frame, property *iotsitewise.DescribeAssetPropertyOutput, buckets syntheticBuckets, values []iotsitewisetypes.AggregatedValue) {
+	starts := make([]time.Time, len(values))
+	for i, v := range values {
+		starts[i] = *v.Timestamp
+	}
+	syntheticFields := buckets.syntheticFields(starts, a.Query.SyntheticAggregateTypes())
+	setAssetLabels(a.Query, property, syntheticFields...)
+	frame.Fields = append(frame.Fields, syntheticFields...)
+}
+
+func (a AssetPropertyAggregatesBatch) appendRawTruncatedNotice(frame *data.Frame) {
+	var text string
+	switch a.RawTruncation {
+	case RawTruncatedDataPoints:
+		text = fmt.Sprintf("the synthetic aggregates are computed from the first %d raw values, the last buckets are incomplete", a.Query.RawDataPoints())
+	case RawTruncatedTimeout:
+		text = fmt.Sprintf("the synthetic aggregates are computed from the raw values fetched within %s, the last buckets are incomplete", a.Query.AutoPaginateTimeout())
+	default:
+		return
+	}
+	frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: text})
+}
+
 func (a AssetPropertyAggregatesBatch) Frame(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, v []iotsitewisetypes.AggregatedValue) (*data.Frame, error) {
 
 	length := len(v)
@@ -160,6 +300,7 @@ func (a AssetPropertyAggregatesBatch) Frame(ctx context.Context, property *iotsi
 	for _, aggType := range aggregateTypes {
 		fields = append(fields, aggregateFields[aggType])
 	}
+	setAssetLabels(a.Query, property, fields[1:]...)
 
 	frame := data.NewFrame(
 		getFrameName(property),
@@ -0,0 +1,164 @@
+package framer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// AssetPropertyAnnotations turns the history of string and boolean properties into annotation regions.
+// Consecutive identical values are merged into a single region ending at the next transition.
+type AssetPropertyAnnotations struct {
+	History *AssetPropertyValueHistoryBatch
+	// LastObservations are the last values before the time range, by entry id, the first region
+	// starts with them at the start of the time range
+	LastObservations map[string]iotsitewisetypes.AssetPropertyValue
+}
+
+// annotationRegion is a run of consecutive identical values of a property
+type annotationRegion struct {
+	start time.Time
+	end   time.Time
+	value string
+}
+
+func (a AssetPropertyAnnotations) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
+	properties, err := resources.Properties(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := data.Frames{}
+	for _, r := range a.History.Responses {
+		for _, s := range r.SuccessEntries {
+			property := properties[*s.EntryId]
+			h := s.AssetPropertyValueHistory
+			if last, ok := a.LastObservations[*s.EntryId]; ok {
+				h = slices.Concat([]iotsitewisetypes.AssetPropertyValue{last}, h)
+			}
+			frame := a.Frame(property, h)
+			if frame.Meta == nil {
+				frame.Meta = &data.FrameMeta{}
+			}
+			frame.Meta.Custom = models.SitewiseCustomMeta{
+				NextToken:  util.Dereference(r.NextToken),
+				EntryId:    *s.EntryId,
+				Resolution: models.PropertyQueryResolutionRaw,
+				Truncated:  util.Dereference(r.NextToken) != "",
+			}
+			frames = append(frames, frame)
+		}
+
+		for _, e := range r.ErrorEntries {
+			property := properties[*e.EntryId]
+			frame := data.NewFrame(getFrameName(property))
+			if e.ErrorMessage != nil {
+				frame.Meta = &data.FrameMeta{
+					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *e.ErrorMessage}},
+				}
+			}
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames, nil
+}
+
+func (a AssetPropertyAnnotations) Frame(property *iotsitewise.DescribeAssetPropertyOutput, h []iotsitewisetypes.AssetPropertyValue) *data.Frame {
+	frameName := getFrameName(property)
+
+	dataType := iotsitewisetypes.PropertyDataType("")
+	if util.IsAssetProperty(property) {
+		dataType = property.AssetProperty.DataType
+	}
+	if !isPropertyDataTypeDefined(dataType) && len(h) > 0 && h[0].Value != nil {
+		dataType = getPropertyVariantValueType(h[0].Value)
+	}
+	if dataType != iotsitewisetypes.PropertyDataTypeString && dataType != iotsitewisetypes.PropertyDataTypeBoolean && len(h) > 0 {
+		return data.NewFrame(frameName).SetMeta(&data.FrameMeta{
+			Notices: []data.Notice{{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("annotations require a string or boolean property, %s is %s", frameName, dataType),
+			}},
+		})
+	}
+
+	regions := annotationRegions(h, a.History.Query.TimeRange.From, a.History.Query.TimeRange.To)
+
+	timeField := fields.TimeField(len(regions))
+	timeEndField := fields.TimeEndField(len(regions))
+	textField := fields.TextField(len(regions))
+	tagsField := fields.TagsField(len(regions))
+
+	propertyName := util.GetPropertyName(property)
+	for i, region := range regions {
+		timeField.Set(i, region.start)
+		timeEndField.Set(i, region.end)
+		textField.Set(i, fmt.Sprintf("%s: %s", propertyName, region.value))
+		tagsField.Set(i, annotationTags(util.Dereference(property.AssetName), propertyName, region.value))
+	}
+
+	return data.NewFrame(frameName, timeField, timeEndField, textField, tagsField)
+}
+
+// annotationRegions merges consecutive identical values. A value before the time range opens the first
+// region at its start, and the last region stays open until the end of the time range.
+func annotationRegions(h []iotsitewisetypes.AssetPropertyValue, from time.Time, to time.Time) []annotationRegion {
+	regions := []annotationRegion{}
+	for _, v := range h {
+		if v.Value == nil || v.Timestamp == nil {
+			continue
+		}
+		value := getPropertyVariantValue(v.Value)
+		if value == nil {
+			continue
+		}
+
+		ts := getTime(v.Timestamp)
+		if ts.Before(from) {
+			ts = from
+		}
+		text := fmt.Sprintf("%v", value)
+		if n := len(regions); n > 0 && regions[n-1].start.Equal(ts) {
+			// a value at the start of the time range replaces the value before it
+			regions = regions[:n-1]
+		}
+		if n := len(regions); n > 0 {
+			if regions[n-1].value == text {
+				continue
+			}
+			regions[n-1].end = ts
+		}
+		regions = append(regions, annotationRegion{start: ts, end: ts, value: text})
+	}
+
+	if n := len(regions); n > 0 && to.After(regions[n-1].start) {
+		regions[n-1].end = to
+	}
+
+	return regions
+}
+
+// annotationTags is the JSON list of the non empty tags, Grafana reads the tags of a JSON field as a list
+func annotationTags(tags ...string) json.RawMessage {
+	nonEmpty := []string{}
+	for _, tag := range tags {
+		if tag != "" {
+			nonEmpty = append(nonEmpty, tag)
+		}
+	}
+	// a list of strings always marshals
+	b, _ := json.Marshal(nonEmpty)
+	return b
+}
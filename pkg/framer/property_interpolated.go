@@ -16,6 +16,23 @@ import (
 type InterpolatedAssetPropertyValue struct {
 	Responses map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput
 	Query     models.AssetPropertyValueQuery
+	// Quality labels the values when several qualities are interpolated
+	Quality iotsitewisetypes.Quality
+}
+
+// InterpolatedAssetPropertyValueQualities frames the values interpolated for each quality as separate series
+type InterpolatedAssetPropertyValueQualities []InterpolatedAssetPropertyValue
+
+func (q InterpolatedAssetPropertyValueQualities) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
+	frames := data.Frames{}
+	for _, p := range q {
+		qualityFrames, err := p.Frames(ctx, resources)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, qualityFrames...)
+	}
+	return frames, nil
 }
 
 func (p InterpolatedAssetPropertyValue) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
@@ -52,6 +69,13 @@ func (p InterpolatedAssetPropertyValue) Frame(ctx context.Context, property *iot
 
 	timeField := fields.TimeField(0)
 	valueField := fields.PropertyValueFieldForQuery(p.Query, property, 0)
+	setAssetLabels(p.Query, property, valueField)
+	if p.Quality != "" {
+		if valueField.Labels == nil {
+			valueField.Labels = data.Labels{}
+		}
+		valueField.Labels[fields.Quality] = string(p.Quality)
+	}
 	name := *property.AssetName
 	if name == "" {
 		name = util.GetPropertyName(property)
@@ -84,5 +108,5 @@ func (p InterpolatedAssetPropertyValue) Frame(ctx context.Context, property *iot
 		valueField.Append(value)
 	}
 
-	return frame, nil
+	return applyFill(p.Query, frame), nil
 }
@@ -22,6 +22,7 @@ type AssetPropertyValueBatch struct {
 	Responses       []*iotsitewise.BatchGetAssetPropertyValueOutput
 	AnomalyAssetIds []string
 	SitewiseClient  client.SitewiseAPIClient
+	Query           models.AssetPropertyValueQuery
 }
 
 func (p AssetPropertyValueBatch) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
@@ -49,6 +50,8 @@ func (p AssetPropertyValueBatch) Frames(ctx context.Context, resources resource.
 				if err != nil {
 					return nil, err
 				}
+			} else if jsonFields, ok := p.flattenJson(e.AssetPropertyValue); ok {
+				frame = p.frameJsonPropertyValue(property, e.AssetPropertyValue, jsonFields)
 			} else {
 				frame = p.framePropertyValue(property, e.AssetPropertyValue)
 			}
@@ -76,10 +79,11 @@ func (p AssetPropertyValueBatch) Frames(ctx context.Context, resources resource.
 	return frames, nil
 }
 
-func (AssetPropertyValueBatch) framePropertyValue(property *iotsitewise.DescribeAssetPropertyOutput, assetPropertyValue *iotsitewisetypes.AssetPropertyValue) *data.Frame {
+func (p AssetPropertyValueBatch) framePropertyValue(property *iotsitewise.DescribeAssetPropertyOutput, assetPropertyValue *iotsitewisetypes.AssetPropertyValue) *data.Frame {
 	timeField := fields.TimeField(0)
 	valueField := fields.PropertyValueField(property, 0)
 	qualityField := fields.QualityField(0)
+	setAssetLabels(p.Query, property, valueField)
 
 	frame := data.NewFrame(*property.AssetName, timeField, valueField, qualityField)
 
@@ -91,6 +95,25 @@ func (AssetPropertyValueBatch) framePropertyValue(property *iotsitewise.Describe
 	return frame
 }
 
+func (p AssetPropertyValueBatch) flattenJson(assetPropertyValue *iotsitewisetypes.AssetPropertyValue) ([]*data.Field, bool) {
+	if !p.Query.FlattenJson || assetPropertyValue == nil {
+		return nil, false
+	}
+	return flattenJson(p.Query, []iotsitewisetypes.AssetPropertyValue{*assetPropertyValue})
+}
+
+func (p AssetPropertyValueBatch) frameJsonPropertyValue(property *iotsitewise.DescribeAssetPropertyOutput, assetPropertyValue *iotsitewisetypes.AssetPropertyValue, jsonFields []*data.Field) *data.Frame {
+	timeField := fields.TimeField(0)
+	qualityField := fields.QualityField(0)
+	setAssetLabels(p.Query, property, jsonFields...)
+
+	timeField.Append(getTime(assetPropertyValue.Timestamp))
+	qualityField.Append(string(assetPropertyValue.Quality))
+
+	dataFields := append([]*data.Field{timeField}, jsonFields...)
+	return data.NewFrame(*property.AssetName, append(dataFields, qualityField)...)
+}
+
 func (p AssetPropertyValueBatch) frameL4ePropertyValue(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, assetPropertyValue *iotsitewisetypes.AssetPropertyValue) (*data.Frame, error) {
 	dataFields := []*data.Field{}
 
@@ -18,7 +18,8 @@ type AssetPropertyValueHistory struct {
 
 func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
 
-	length := len(p.AssetPropertyValueHistory)
+	history, originalPointCount := downsample(p.Query, p.AssetPropertyValueHistory)
+	length := len(history)
 	property, err := resources.Property(ctx)
 	if err != nil {
 		return nil, err
@@ -26,7 +27,7 @@ func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resourc
 	// TODO: make this work with the API instead of ad-hoc dataType inference
 	// https://github.com/grafana/iot-sitewise-datasource/issues/98#issuecomment-892947756
 	if util.IsAssetProperty(property) && !isPropertyDataTypeDefined(property.AssetProperty.DataType) {
-		property.AssetProperty.DataType = getPropertyVariantValueType(p.AssetPropertyValueHistory[0].Value)
+		property.AssetProperty.DataType = getPropertyVariantValueType(history[0].Value)
 	}
 
 	timeField := fields.TimeField(length)
@@ -35,12 +36,13 @@ func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resourc
 	frame := data.NewFrame(getFrameName(property), timeField, valueField, qualityField)
 	frame.Meta = &data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{
-			NextToken:  util.Dereference(p.NextToken),
-			Resolution: models.PropertyQueryResolutionRaw,
+			NextToken:          util.Dereference(p.NextToken),
+			Resolution:         models.PropertyQueryResolutionRaw,
+			OriginalPointCount: originalPointCount,
 		},
 	}
 
-	for i, v := range p.AssetPropertyValueHistory {
+	for i, v := range history {
 		if v.Value != nil && getPropertyVariantValue(v.Value) != nil {
 			timeField.Set(i, getTime(v.Timestamp))
 			valueField.Set(i, getPropertyVariantValue(v.Value))
@@ -48,5 +50,9 @@ func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resourc
 		}
 	}
 
-	return data.Frames{frame}, nil
+	frames := applyQualities(p.Query, frame)
+	for i, f := range frames {
+		frames[i] = applyFill(p.Query, f)
+	}
+	return frames, nil
 }
@@ -39,19 +39,24 @@ func (p AssetPropertyValueHistoryBatch) Frames(ctx context.Context, resources re
 
 	for _, r := range p.Responses {
 		for _, s := range r.SuccessEntries {
-			frame, err := p.Frame(ctx, properties[*s.EntryId], s.AssetPropertyValueHistory)
+			history, originalPointCount := downsample(p.Query, s.AssetPropertyValueHistory)
+			frame, err := p.Frame(ctx, properties[*s.EntryId], history)
 			frame.Meta = &data.FrameMeta{
 				Custom: models.SitewiseCustomMeta{
-					NextToken:  util.Dereference(r.NextToken),
-					EntryId:    *s.EntryId,
-					Resolution: models.PropertyQueryResolutionRaw,
+					NextToken:          util.Dereference(r.NextToken),
+					EntryId:            *s.EntryId,
+					Resolution:         models.PropertyQueryResolutionRaw,
+					Truncated:          p.Query.AutoPaginate && util.Dereference(r.NextToken) != "",
+					OriginalPointCount: originalPointCount,
 				},
 			}
 			if err != nil {
 				return nil, err
 			}
 			if frame != nil {
-				frames = append(frames, frame)
+				for _, f := range applyQualities(p.Query, frame) {
+					frames = append(frames, applyFill(p.Query, f))
+				}
 			}
 		}
 
@@ -85,9 +90,13 @@ func (p AssetPropertyValueHistoryBatch) Frame(ctx context.Context, property *iot
 	assetId := property.AssetId
 	if assetId != nil && slices.Contains(p.AnomalyAssetIds, *assetId) {
 		return p.frameL4ePropertyValues(ctx, property, h)
-	} else {
-		return p.framePropertyValues(property, h)
 	}
+	if p.Query.FlattenJson {
+		if jsonFields, ok := flattenJson(p.Query, h); ok {
+			return p.frameJsonPropertyValues(property, h, jsonFields), nil
+		}
+	}
+	return p.framePropertyValues(property, h)
 }
 
 // framePropertyValues creates a frame for a property value history.
@@ -97,6 +106,7 @@ func (p AssetPropertyValueHistoryBatch) framePropertyValues(property *iotsitewis
 	timeField := fields.TimeField(length)
 	valueField := fields.PropertyValueFieldForQuery(p.Query, property, length)
 	qualityField := fields.QualityField(length)
+	setAssetLabels(p.Query, property, valueField)
 	frameName := ""
 	if models.QueryTypePropertyAggregate == p.Query.QueryType {
 		frameName = getFrameName(property)
@@ -120,6 +130,29 @@ func (p AssetPropertyValueHistoryBatch) framePropertyValues(property *iotsitewis
 	return frame, nil
 }
 
+// frameJsonPropertyValues creates a frame for a property value history with the JSON paths of the values flatten.
+func (p AssetPropertyValueHistoryBatch) frameJsonPropertyValues(property *iotsitewise.DescribeAssetPropertyOutput, h []iotsitewisetypes.AssetPropertyValue, jsonFields []*data.Field) *data.Frame {
+	length := len(h)
+
+	timeField := fields.TimeField(length)
+	qualityField := fields.QualityField(length)
+	setAssetLabels(p.Query, property, jsonFields...)
+	frameName := ""
+	if models.QueryTypePropertyAggregate == p.Query.QueryType {
+		frameName = getFrameName(property)
+	} else {
+		frameName = *property.AssetName
+	}
+
+	for i, v := range h {
+		timeField.Set(i, getTime(v.Timestamp))
+		qualityField.Set(i, string(v.Quality))
+	}
+
+	dataFields := append([]*data.Field{timeField}, jsonFields...)
+	return data.NewFrame(frameName, append(dataFields, qualityField)...)
+}
+
 // frameL4ePropertyValues creates a frame for a property value history with L4E fields flatten.
 func (p AssetPropertyValueHistoryBatch) frameL4ePropertyValues(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, h []iotsitewisetypes.AssetPropertyValue) (*data.Frame, error) {
 	frameName := ""
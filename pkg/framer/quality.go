@@ -0,0 +1,115 @@
+package framer
+
+import (
+	"slices"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// applyQualities drops the values of the qualities that are requested but not selected, then
+// splits the frame in one frame per quality, or nulls out the values that are not GOOD.
+// The frame holds the raw values of a property with their quality.
+func applyQualities(query models.AssetPropertyValueQuery, frame *data.Frame) data.Frames {
+	qualityIdx := qualityFieldIndex(frame)
+	if qualityIdx < 0 {
+		return data.Frames{frame}
+	}
+
+	if query.FiltersQualities() {
+		selected := query.SelectedQualities()
+		frame = filterRows(frame, func(i int) bool {
+			return slices.Contains(selected, iotsitewisetypes.Quality(frame.Fields[qualityIdx].At(i).(string)))
+		})
+	}
+
+	switch query.QualityMode {
+	case models.QualityModeSplit:
+		return splitByQuality(frame, qualityIdx)
+	case models.QualityModeNullNotGood:
+		return data.Frames{nullNotGood(frame, qualityIdx)}
+	default:
+		return data.Frames{frame}
+	}
+}
+
+func qualityFieldIndex(frame *data.Frame) int {
+	for i, field := range frame.Fields {
+		if field.Name == fields.Quality && field.Type() == data.FieldTypeString {
+			return i
+		}
+	}
+	return -1
+}
+
+// isValueField returns true for the fields holding the values of the property
+func isValueField(frame *data.Frame, idx int, qualityIdx int) bool {
+	return idx != qualityIdx && frame.Fields[idx].Type() != data.FieldTypeTime
+}
+
+// filterRows copies the rows of the frame kept by the predicate
+func filterRows(frame *data.Frame, keep func(i int) bool) *data.Frame {
+	filtered := frame.EmptyCopy()
+	filtered.Meta = frame.Meta
+	for j, field := range frame.Fields {
+		filtered.Fields[j].Config = field.Config
+	}
+	for i := 0; i < frame.Rows(); i++ {
+		if keep(i) {
+			filtered.AppendRow(frame.RowCopy(i)...)
+		}
+	}
+	return filtered
+}
+
+// splitByQuality returns a frame for each quality of the values, the value fields are labelled with the quality
+func splitByQuality(frame *data.Frame, qualityIdx int) data.Frames {
+	frames := data.Frames{}
+	for _, quality := range iotsitewisetypes.Quality("").Values() {
+		split := filterRows(frame, func(i int) bool {
+			return frame.Fields[qualityIdx].At(i).(string) == string(quality)
+		})
+		if split.Rows() == 0 {
+			continue
+		}
+		for j, field := range split.Fields {
+			if !isValueField(split, j, qualityIdx) {
+				continue
+			}
+			if field.Labels == nil {
+				field.Labels = data.Labels{}
+			}
+			field.Labels[fields.Quality] = string(quality)
+		}
+		frames = append(frames, split)
+	}
+	if len(frames) == 0 {
+		return data.Frames{frame}
+	}
+	return frames
+}
+
+// nullNotGood replaces the value fields by nullable fields without the values that are not GOOD
+func nullNotGood(frame *data.Frame, qualityIdx int) *data.Frame {
+	for j, field := range frame.Fields {
+		if !isValueField(frame, j, qualityIdx) {
+			continue
+		}
+		nullable := data.NewFieldFromFieldType(field.Type().NullableType(), field.Len())
+		nullable.Name = field.Name
+		nullable.Labels = field.Labels
+		nullable.Config = field.Config
+		for i := 0; i < field.Len(); i++ {
+			if frame.Fields[qualityIdx].At(i).(string) != string(iotsitewisetypes.QualityGood) {
+				continue
+			}
+			if v, ok := field.ConcreteAt(i); ok {
+				nullable.SetConcrete(i, v)
+			}
+		}
+		frame.Fields[j] = nullable
+	}
+	return frame
+}
@@ -0,0 +1,122 @@
+package framer
+
+import (
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+)
+
+// RollupBucket is the bucket a native aggregate is re-aggregated into
+type RollupBucket struct {
+	Start time.Time
+	End   time.Time
+	// Shift is the name of the shift occurrence of a SHIFT resolution bucket
+	Shift string
+}
+
+// Rollup returns the bucket of the native aggregate starting at the time,
+// and false when the aggregate is outside of every bucket
+type Rollup func(time.Time) (RollupBucket, bool)
+
+// rollupBucket accumulates the native aggregates of a bucket
+type rollupBucket struct {
+	RollupBucket
+	count      float64
+	sum        float64
+	mean       float64 // sum of the averages weighted by the counts
+	squares    float64 // sum of (n-1)s² + nμ² over the native aggregates
+	minimum    *float64
+	maximum    *float64
+	hasAverage bool
+	hasSum     bool
+}
+
+func (b *rollupBucket) add(aggs *iotsitewisetypes.Aggregates) {
+	n := aws.ToFloat64(aggs.Count)
+	b.count += n
+	if aggs.Sum != nil {
+		b.hasSum = true
+		b.sum += *aggs.Sum
+	}
+	if aggs.Average != nil {
+		b.hasAverage = true
+		b.mean += n * *aggs.Average
+		b.squares += n * *aggs.Average * *aggs.Average
+		if aggs.StandardDeviation != nil && n > 1 {
+			b.squares += (n - 1) * *aggs.StandardDeviation * *aggs.StandardDeviation
+		}
+	}
+	if aggs.Minimum != nil && (b.minimum == nil || *aggs.Minimum < *b.minimum) {
+		b.minimum = aws.Float64(*aggs.Minimum)
+	}
+	if aggs.Maximum != nil && (b.maximum == nil || *aggs.Maximum > *b.maximum) {
+		b.maximum = aws.Float64(*aggs.Maximum)
+	}
+}
+
+func (b *rollupBucket) aggregates(aggregateTypes []iotsitewisetypes.AggregateType) *iotsitewisetypes.Aggregates {
+	aggs := &iotsitewisetypes.Aggregates{}
+	var mean *float64
+	if b.hasAverage && b.count > 0 {
+		mean = aws.Float64(b.mean / b.count)
+	}
+	for _, aggregateType := range aggregateTypes {
+		switch aggregateType {
+		case iotsitewisetypes.AggregateTypeAverage:
+			aggs.Average = mean
+		case iotsitewisetypes.AggregateTypeCount:
+			aggs.Count = aws.Float64(b.count)
+		case iotsitewisetypes.AggregateTypeMinimum:
+			aggs.Minimum = b.minimum
+		case iotsitewisetypes.AggregateTypeMaximum:
+			aggs.Maximum = b.maximum
+		case iotsitewisetypes.AggregateTypeSum:
+			if b.hasSum {
+				aggs.Sum = aws.Float64(b.sum)
+			}
+		case iotsitewisetypes.AggregateTypeStandardDeviation:
+			if mean != nil && b.count > 1 {
+				variance := (b.squares - b.count**mean**mean) / (b.count - 1)
+				aggs.StandardDeviation = aws.Float64(math.Sqrt(math.Max(variance, 0)))
+			}
+		}
+	}
+	return aggs
+}
+
+// rollupAggregates re-aggregates native aggregates into the buckets of the rollup, and returns the
+// shift names of the buckets. The buckets keep the time ordering of the values and only hold the
+// aggregate types of the query.
+func rollupAggregates(values []iotsitewisetypes.AggregatedValue, rollup Rollup, aggregateTypes []iotsitewisetypes.AggregateType) ([]iotsitewisetypes.AggregatedValue, []string) {
+	buckets := []*rollupBucket{}
+	byStart := map[int64]*rollupBucket{}
+	for _, v := range values {
+		if v.Timestamp == nil || v.Value == nil {
+			continue
+		}
+		bucket, ok := rollup(*v.Timestamp)
+		if !ok {
+			continue
+		}
+		b, ok := byStart[bucket.Start.UnixNano()]
+		if !ok {
+			b = &rollupBucket{RollupBucket: bucket}
+			byStart[bucket.Start.UnixNano()] = b
+			buckets = append(buckets, b)
+		}
+		b.add(v.Value)
+	}
+
+	rolledUp := make([]iotsitewisetypes.AggregatedValue, 0, len(buckets))
+	shifts := make([]string, 0, len(buckets))
+	for _, b := range buckets {
+		rolledUp = append(rolledUp, iotsitewisetypes.AggregatedValue{
+			Timestamp: aws.Time(b.Start),
+			Value:     b.aggregates(aggregateTypes),
+		})
+		shifts = append(shifts, b.Shift)
+	}
+	return rolledUp, shifts
+}
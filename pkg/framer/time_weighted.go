@@ -0,0 +1,89 @@
+package framer
+
+import (
+	"sort"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+type timedValue struct {
+	time  time.Time
+	value float64
+}
+
+// addTimeWeighted computes the time weighted aggregates of the buckets from the ascending raw values, which
+// may start with the last value before the time range. The values are held (step) or interpolated linearly
+// between the samples, and the last value before a bucket is held into it, including into buckets without
+// raw values, until the end of the time range.
+func (b syntheticBuckets) addTimeWeighted(history []iotsitewisetypes.AssetPropertyValue, interpolation string, to time.Time) {
+	points := make([]timedValue, 0, len(history))
+	for _, v := range history {
+		value, ok := numericValue(v.Value)
+		if !ok || v.Timestamp == nil {
+			continue
+		}
+		points = append(points, timedValue{time: getTime(v.Timestamp), value: value})
+	}
+	if len(points) == 0 {
+		return
+	}
+
+	for _, bucket := range b.buckets {
+		from := bucket.Start
+		if from.Before(points[0].time) {
+			from = points[0].time
+		}
+		until := bucket.End
+		if !to.IsZero() && to.Before(until) {
+			until = to
+		}
+		if !until.After(from) {
+			continue
+		}
+		integral := integrate(points, from, until, interpolation == models.TimeWeightedLinear)
+		values, ok := b.values[bucket.Start.UnixNano()]
+		if !ok {
+			values = map[string]float64{}
+			b.values[bucket.Start.UnixNano()] = values
+		}
+		values[models.AggregateTimeWeightedIntegral] = integral
+		values[models.AggregateTimeWeightedAverage] = integral / until.Sub(from).Seconds()
+	}
+}
+
+// integrate integrates the values from the first point at or after from until, in value-seconds
+func integrate(points []timedValue, from, until time.Time, linear bool) float64 {
+	// the last point at or before from holds the value at from
+	k := sort.Search(len(points), func(i int) bool { return points[i].time.After(from) }) - 1
+	if k < 0 {
+		k = 0
+	}
+
+	integral := 0.0
+	for ; k < len(points) && points[k].time.Before(until); k++ {
+		start, end := points[k].time, until
+		if start.Before(from) {
+			start = from
+		}
+		if k+1 < len(points) && points[k+1].time.Before(until) {
+			end = points[k+1].time
+		}
+		if !linear || k+1 == len(points) {
+			integral += points[k].value * end.Sub(start).Seconds()
+			continue
+		}
+		integral += (valueAt(points[k], points[k+1], start) + valueAt(points[k], points[k+1], end)) / 2 * end.Sub(start).Seconds()
+	}
+	return integral
+}
+
+// valueAt interpolates linearly between two points, and holds the value of the first one when they share their time
+func valueAt(a, b timedValue, t time.Time) float64 {
+	if !b.time.After(a.time) {
+		return a.value
+	}
+	return a.value + (b.value-a.value)*t.Sub(a.time).Seconds()/b.time.Sub(a.time).Seconds()
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
@@ -110,3 +112,17 @@ func getFrameName(property *iotsitewise.DescribeAssetPropertyOutput) string {
 
 	return ""
 }
+
+// setAssetLabels adds the asset name to the labels of the fields when the query is expanded to the
+// assets of a model or of a hierarchy
+func setAssetLabels(query models.AssetPropertyValueQuery, property *iotsitewise.DescribeAssetPropertyOutput, fields ...*data.Field) {
+	if query.AssetModelId == "" && query.PropertyName == "" {
+		return
+	}
+	for _, field := range fields {
+		if field.Labels == nil {
+			field.Labels = data.Labels{}
+		}
+		field.Labels["asset"] = util.Dereference(property.AssetName)
+	}
+}
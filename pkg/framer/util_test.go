@@ -0,0 +1,28 @@
+package framer
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAssetLabels(t *testing.T) {
+	property := &iotsitewise.DescribeAssetPropertyOutput{AssetName: aws.String("Turbine 1")}
+
+	t.Run("keeps the labels of the fields", func(t *testing.T) {
+		field := data.NewField("value", data.Labels{"quality": "GOOD"}, []float64{1})
+		setAssetLabels(models.AssetPropertyValueQuery{AssetModelId: "turbine-model"}, property, field)
+		assert.Equal(t, data.Labels{"quality": "GOOD", "asset": "Turbine 1"}, field.Labels)
+	})
+
+	t.Run("only labels expanded queries", func(t *testing.T) {
+		field := data.NewField("value", nil, []float64{1})
+		setAssetLabels(models.AssetPropertyValueQuery{}, property, field)
+		assert.Nil(t, field.Labels)
+	})
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	// the timezones of the aggregate queries do not depend on the zoneinfo of the host
+	_ "time/tzdata"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
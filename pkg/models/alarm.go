@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	AlarmCompositeModelType = "AWS/ALARM"
+	AlarmStatePropertyName  = "AWS/ALARM_STATE"
+)
+
+// Alarm states reported by the AWS/ALARM_STATE property
+const (
+	AlarmStateDisabled       = "Disabled"
+	AlarmStateNormal         = "Normal"
+	AlarmStateActive         = "Active"
+	AlarmStateAcknowledged   = "Acknowledged"
+	AlarmStateSnoozeDisabled = "SnoozeDisabled"
+	AlarmStateLatched        = "Latched"
+)
+
+const AlarmActionAcknowledge = "ACKNOWLEDGE"
+
+// AlarmQuery lists the alarms of the assets, or of every asset in their hierarchy when LoadAllChildren is set.
+// The current state is returned for ListAlarms and every state change of the time range for AlarmHistory.
+type AlarmQuery struct {
+	AssetPropertyValueQuery
+	LoadAllChildren bool `json:"loadAllChildren,omitempty"`
+}
+
+// AlarmState is the JSON value of an AWS/ALARM_STATE property. The states written by AWS IoT Events
+// carry no severity, it is only set by the alarms of external sources that report one.
+type AlarmState struct {
+	StateName      string               `json:"stateName"`
+	Severity       *int64               `json:"severity,omitempty"`
+	CustomerAction *AlarmCustomerAction `json:"customerAction,omitempty"`
+}
+
+// AlarmCustomerAction is the last action of an operator on the alarm, like
+// {"actionType":"ACKNOWLEDGE","acknowledge":{"note":"..."}}
+type AlarmCustomerAction struct {
+	ActionType string `json:"actionType"`
+}
+
+func GetAlarmQuery(dq *backend.DataQuery) (*AlarmQuery, error) {
+	valueQuery, err := GetAssetPropertyValueQuery(dq)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &AlarmQuery{}
+	if err := json.Unmarshal(dq.JSON, query); err != nil {
+		return nil, err
+	}
+	query.AssetPropertyValueQuery = *valueQuery
+
+	return query, nil
+}
+
+// ParseAlarmState parses the JSON value of an AWS/ALARM_STATE property
+func ParseAlarmState(value string) (*AlarmState, error) {
+	state := &AlarmState{}
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Triggered is true while the alarm is raised, whether or not it was acknowledged
+func (s *AlarmState) Triggered() bool {
+	return s.StateName == AlarmStateActive || s.StateName == AlarmStateAcknowledged || s.StateName == AlarmStateLatched
+}
+
+// Acknowledged is true once an operator acknowledged the alarm
+func (s *AlarmState) Acknowledged() bool {
+	if s.StateName == AlarmStateAcknowledged {
+		return true
+	}
+	return s.CustomerAction != nil && s.CustomerAction.ActionType == AlarmActionAcknowledge
+}
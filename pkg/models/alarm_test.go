@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlarmState(t *testing.T) {
+	// an AWS/ALARM_STATE value as written by AWS IoT Events
+	state, err := ParseAlarmState(`{
+		"stateName": "Latched",
+		"customerAction": {
+			"actionType": "ACKNOWLEDGE",
+			"acknowledge": {"note": "Checking the inverter"}
+		},
+		"eventEvaluation": {
+			"ruleEvaluation": {
+				"simpleRule": {"inputProperty": 108, "operator": "GREATER", "threshold": 100}
+			}
+		}
+	}`)
+	require.NoError(t, err)
+
+	assert.Equal(t, AlarmStateLatched, state.StateName)
+	require.NotNil(t, state.CustomerAction)
+	assert.Equal(t, AlarmActionAcknowledge, state.CustomerAction.ActionType)
+	assert.True(t, state.Triggered())
+	assert.True(t, state.Acknowledged())
+
+	state, err = ParseAlarmState(`{"stateName":"Active","eventEvaluation":{"ruleEvaluation":{"simpleRule":{"inputProperty":108,"operator":"GREATER","threshold":100}}}}`)
+	require.NoError(t, err)
+	assert.True(t, state.Triggered())
+	assert.False(t, state.Acknowledged())
+}
@@ -36,6 +36,20 @@ type ListAssociatedAssetsQuery struct {
 	// TraversalDirection is implied from the existence of HierarchyId
 }
 
+const (
+	// DefaultAssetHierarchyDepth is the number of levels walked when the query does not set a depth
+	DefaultAssetHierarchyDepth = 3
+	// MaxAssetHierarchyDepth bounds the number of SiteWise calls of a single hierarchy query
+	MaxAssetHierarchyDepth = 10
+)
+
+// AssetHierarchyQuery walks the hierarchy below (CHILD) or above (PARENT) the assets
+type AssetHierarchyQuery struct {
+	BaseQuery
+	Depth              int                                 `json:"depth,omitempty"`
+	TraversalDirection iotsitewisetypes.TraversalDirection `json:"traversalDirection,omitempty"`
+}
+
 func GetDescribeAssetQuery(dq *backend.DataQuery) (*DescribeAssetQuery, error) {
 	query := &DescribeAssetQuery{}
 	if err := json.Unmarshal(dq.JSON, query); err != nil {
@@ -51,6 +65,21 @@ func GetDescribeAssetQuery(dq *backend.DataQuery) (*DescribeAssetQuery, error) {
 	return query, nil
 }
 
+func GetDescribeAssetPropertyQuery(dq *backend.DataQuery) (*DescribeAssetPropertyQuery, error) {
+	query := &DescribeAssetPropertyQuery{}
+	if err := json.Unmarshal(dq.JSON, query); err != nil {
+		return nil, err
+	}
+
+	// AssetId, PropertyId, PropertyAlias <--> lists backward compatibility
+	query.MigrateAssetProperty()
+
+	// add on the DataQuery params
+	query.QueryType = dq.QueryType
+
+	return query, nil
+}
+
 func GetListAssetPropertiesQuery(dq *backend.DataQuery) (*ListAssetPropertiesQuery, error) {
 	query := &ListAssetPropertiesQuery{}
 	if err := json.Unmarshal(dq.JSON, query); err != nil {
@@ -105,3 +134,28 @@ func GetListAssociatedAssetsQuery(dq *backend.DataQuery) (*ListAssociatedAssetsQ
 	query.QueryType = dq.QueryType
 	return query, nil
 }
+
+func GetAssetHierarchyQuery(dq *backend.DataQuery) (*AssetHierarchyQuery, error) {
+	query := &AssetHierarchyQuery{}
+	if err := json.Unmarshal(dq.JSON, query); err != nil {
+		return nil, err
+	}
+
+	// AssetId <--> AssetIds backward compatibility
+	query.MigrateAssetProperty()
+
+	if query.Depth < 1 {
+		query.Depth = DefaultAssetHierarchyDepth
+	}
+	if query.Depth > MaxAssetHierarchyDepth {
+		query.Depth = MaxAssetHierarchyDepth
+	}
+
+	if query.TraversalDirection == "" {
+		query.TraversalDirection = iotsitewisetypes.TraversalDirectionChild
+	}
+
+	// add on the DataQuery params
+	query.QueryType = dq.QueryType
+	return query, nil
+}
@@ -0,0 +1,59 @@
+package models
+
+// Result of probing a single SiteWise API during the health check
+const (
+	HealthProbePass    = "pass"
+	HealthProbeFail    = "fail"
+	HealthProbeDenied  = "denied"
+	HealthProbeSkipped = "skipped"
+	// HealthProbeUnknown is a probe throttled or failing on the server side, which does not tell
+	// whether the credentials are allowed to call the API
+	HealthProbeUnknown = "unknown"
+)
+
+// Overall status of the health check report
+const (
+	HealthReportOk       = "ok"
+	HealthReportDegraded = "degraded"
+	HealthReportError    = "error"
+)
+
+// HealthProbe is the outcome of calling one SiteWise API with the datasource credentials
+type HealthProbe struct {
+	API     string `json:"api"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthReport is returned as the JSON details of the datasource health check
+type HealthReport struct {
+	Status string        `json:"status"`
+	APIs   []HealthProbe `json:"apis"`
+	// InvalidSettings are the errors of the optional settings, which only fail the queries using them
+	InvalidSettings []string `json:"invalidSettings,omitempty"`
+}
+
+// MissingAccess returns the names of the APIs the credentials are not allowed to call
+func (r *HealthReport) MissingAccess() []string {
+	return r.withStatus(HealthProbeDenied)
+}
+
+// Failing returns the names of the APIs the datasource is not able to call for another reason
+func (r *HealthReport) Failing() []string {
+	return r.withStatus(HealthProbeFail)
+}
+
+// Unknown returns the names of the APIs that were throttled or failed on the server side
+func (r *HealthReport) Unknown() []string {
+	return r.withStatus(HealthProbeUnknown)
+}
+
+func (r *HealthReport) withStatus(status string) []string {
+	apis := []string{}
+	for _, probe := range r.APIs {
+		if probe.Status == status {
+			apis = append(apis, probe.API)
+		}
+	}
+	return apis
+}
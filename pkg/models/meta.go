@@ -6,4 +6,8 @@ type SitewiseCustomMeta struct {
 	EntryId    string   `json:"entryId,omitempty"`
 	Resolution string   `json:"resolution,omitempty"`
 	Aggregates []string `json:"aggregates,omitempty"`
+	// Truncated is set when an auto paginated query stopped before the time range was complete
+	Truncated bool `json:"truncated,omitempty"`
+	// OriginalPointCount is the number of raw values before they were downsampled
+	OriginalPointCount int `json:"originalPointCount,omitempty"`
 }
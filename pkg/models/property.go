@@ -2,6 +2,11 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -11,6 +16,46 @@ const (
 	PropertyQueryResolutionRaw = "RAW"
 )
 
+const (
+	// QualityAny selects the data of every quality
+	QualityAny = "ANY"
+	// QualityModeSplit frames the values of each quality as a separate series
+	QualityModeSplit = "split"
+	// QualityModeNullNotGood nulls out the values that are not GOOD and keeps their quality
+	QualityModeNullNotGood = "nullNotGood"
+)
+
+const (
+	// DownsampleLTTB keeps the points of the Largest-Triangle-Three-Buckets algorithm
+	DownsampleLTTB = "lttb"
+	// DownsampleMinMax keeps the minimum and maximum of each pixel wide time bucket
+	DownsampleMinMax = "minMax"
+)
+
+const (
+	// FillNone keeps the gaps of the raw and interpolated values
+	FillNone = "none"
+	// FillPrevious holds the previous value until the next one
+	FillPrevious = "previous"
+	// FillNull breaks the series once the previous value is older than the staleness threshold
+	FillNull = "null"
+	// FillZero drops the series to zero once the previous value is older than the staleness threshold
+	FillZero = "zero"
+)
+
+const (
+	// DefaultAutoPaginateTimeout is the wall-clock budget of an auto paginated query
+	DefaultAutoPaginateTimeout = 30 * time.Second
+	// DefaultAutoPaginateMaxDataPoints is the data point budget of an auto paginated query
+	DefaultAutoPaginateMaxDataPoints = 1000000
+	// DefaultMaxPropertyAliasMatches is the number of data streams a property alias pattern is expanded to
+	DefaultMaxPropertyAliasMatches = 100
+	// DefaultMaxRawDataPoints is the number of raw values fetched for the synthetic aggregates of a query
+	DefaultMaxRawDataPoints = 200000
+	// DefaultJsonDiscoveryRows is the number of JSON documents the flattened JSON paths are discovered from
+	DefaultJsonDiscoveryRows = 100
+)
+
 type ListAssetPropertiesQuery struct {
 	BaseQuery
 }
@@ -26,6 +71,89 @@ type AssetPropertyValueQuery struct {
 	LastObservation bool                             `json:"lastObservation,omitempty"`
 	TimeOrdering    iotsitewisetypes.TimeOrdering    `json:"timeOrdering,omitempty"`
 	FlattenL4e      bool                             `json:"flattenL4e,omitempty"`
+
+	JsonOptions
+
+	// Qualities selects several qualities, Quality is used when it is empty
+	Qualities []iotsitewisetypes.Quality `json:"qualities,omitempty"`
+	// QualityMode applies to the raw values of history queries, see QualityModeSplit and QualityModeNullNotGood
+	QualityMode string `json:"qualityMode,omitempty"`
+	// Downsample reduces the numeric raw values of history queries to MaxDataPoints, see DownsampleLTTB and DownsampleMinMax
+	Downsample string `json:"downsample,omitempty"`
+	FillOptions
+
+	// InterpolationType is LINEAR_INTERPOLATION (default) or LOCF_INTERPOLATION, and
+	// IntervalWindowInSeconds bounds the data used for each linear interpolated value
+	InterpolationType       string `json:"interpolationType,omitempty"`
+	IntervalWindowInSeconds int64  `json:"intervalWindowInSeconds,omitempty"`
+
+	// Timezone and DayStartOffset align the 1d and 1w aggregates to local production days,
+	// which start DayStartOffset (for example 6h) after midnight in the IANA timezone
+	Timezone       string `json:"timezone,omitempty"`
+	DayStartOffset string `json:"dayStartOffset,omitempty"`
+	// ShiftCalendar of the SHIFT resolution, the calendar of the datasource settings is used when it is nil
+	ShiftCalendar *ShiftCalendar `json:"shiftCalendar,omitempty"`
+
+	// AssetModelId queries the property of every asset of the asset model, the property
+	// is selected with PropertyIds or by PropertyName
+	AssetModelId string `json:"assetModelId,omitempty"`
+	// PropertyName without an AssetModelId queries the properties matching the name or glob pattern
+	// of the AssetIds and their descendants, down to HierarchyDepth levels (DefaultAssetHierarchyDepth when unset)
+	PropertyName   string `json:"propertyName,omitempty"`
+	HierarchyDepth int    `json:"hierarchyDepth,omitempty"`
+
+	// PropertyAliasPattern queries the data streams whose alias matches the glob pattern,
+	// or the regular expression when PropertyAliasRegex is set
+	PropertyAliasPattern    string `json:"propertyAliasPattern,omitempty"`
+	PropertyAliasRegex      bool   `json:"propertyAliasRegex,omitempty"`
+	MaxPropertyAliasMatches int    `json:"maxPropertyAliasMatches,omitempty"`
+	// PropertyAliasMatchesTruncated is set when the pattern matched more data streams than the cap
+	PropertyAliasMatchesTruncated bool `json:"-"`
+
+	PaginationOptions
+
+	TimeShiftOptions
+
+	SyntheticAggregateOptions
+}
+
+// PaginationOptions make the backend follow the next tokens until the time range is complete
+// or one of the budgets is exhausted
+type PaginationOptions struct {
+	AutoPaginate              bool  `json:"autoPaginate,omitempty"`
+	AutoPaginateTimeoutMs     int64 `json:"autoPaginateTimeoutMs,omitempty"`
+	AutoPaginateMaxDataPoints int64 `json:"autoPaginateMaxDataPoints,omitempty"`
+}
+
+// FillOptions fill the gaps longer than the StalenessThreshold (for example 5m) of history and interpolated
+// queries, see FillPrevious, FillNull and FillZero. The values after such a gap are marked stale.
+type FillOptions struct {
+	Fill               string `json:"fill,omitempty"`
+	StalenessThreshold string `json:"stalenessThreshold,omitempty"`
+}
+
+// JsonOptions flatten the JSON documents of string and struct properties into a field per JSON path,
+// for example status.code or readings.0.value. The JsonPaths are discovered from the first
+// JsonDiscoveryRows documents when they are empty.
+type JsonOptions struct {
+	FlattenJson       bool     `json:"flattenJson,omitempty"`
+	JsonPaths         []string `json:"jsonPaths,omitempty"`
+	JsonDiscoveryRows int      `json:"jsonDiscoveryRows,omitempty"`
+}
+
+// TimeShiftOptions query the time range shifted by TimeShift (for example -7d or -1y), its values are moved
+// back onto the time range of the query. TimeShiftCompare queries both the time range and the shifted one.
+type TimeShiftOptions struct {
+	TimeShift        string `json:"timeShift,omitempty"`
+	TimeShiftCompare bool   `json:"timeShiftCompare,omitempty"`
+}
+
+// SyntheticAggregateOptions apply to the aggregates computed from the raw values
+type SyntheticAggregateOptions struct {
+	// MaxRawDataPoints caps the raw values the synthetic aggregates are computed from
+	MaxRawDataPoints int64 `json:"maxRawDataPoints,omitempty"`
+	// TimeWeightedInterpolation is TimeWeightedStep (default) or TimeWeightedLinear
+	TimeWeightedInterpolation string `json:"timeWeightedInterpolation,omitempty"`
 }
 
 // Track the assetId, propertyId, and property alias of a data stream
@@ -50,6 +178,26 @@ func GetAssetPropertyValueQuery(dq *backend.DataQuery) (*AssetPropertyValueQuery
 		query.TimeOrdering = "ASCENDING"
 	}
 
+	if query.HierarchyDepth < 1 {
+		query.HierarchyDepth = DefaultAssetHierarchyDepth
+	}
+	if query.HierarchyDepth > MaxAssetHierarchyDepth {
+		query.HierarchyDepth = MaxAssetHierarchyDepth
+	}
+
+	if err := query.FillOptions.Validate(); err != nil {
+		return nil, err
+	}
+	if err := query.JsonOptions.Validate(); err != nil {
+		return nil, err
+	}
+	if err := query.TimeShiftOptions.Validate(); err != nil {
+		return nil, err
+	}
+	if err := query.SyntheticAggregateOptions.Validate(); err != nil {
+		return nil, err
+	}
+
 	// default to 1 if unset
 	if query.MaxPageAggregations < 1 {
 		query.MaxPageAggregations = 1
@@ -63,3 +211,166 @@ func GetAssetPropertyValueQuery(dq *backend.DataQuery) (*AssetPropertyValueQuery
 
 	return query, nil
 }
+
+// AutoPaginateTimeout returns the wall-clock budget for following next tokens
+func (options PaginationOptions) AutoPaginateTimeout() time.Duration {
+	if options.AutoPaginateTimeoutMs <= 0 {
+		return DefaultAutoPaginateTimeout
+	}
+	return time.Duration(options.AutoPaginateTimeoutMs) * time.Millisecond
+}
+
+// Staleness returns the staleness threshold of the gaps, it is 0 without a threshold
+func (options FillOptions) Staleness() (time.Duration, error) {
+	if options.StalenessThreshold == "" {
+		return 0, nil
+	}
+	threshold, err := time.ParseDuration(options.StalenessThreshold)
+	if err != nil || threshold <= 0 {
+		return 0, fmt.Errorf("invalid staleness threshold %s, expected a positive duration", options.StalenessThreshold)
+	}
+	return threshold, nil
+}
+
+// Validate checks the fill and the staleness threshold
+func (options FillOptions) Validate() error {
+	threshold, err := options.Staleness()
+	if err != nil {
+		return err
+	}
+	switch options.Fill {
+	case "", FillNone, FillPrevious:
+	case FillNull, FillZero:
+		if threshold == 0 {
+			return fmt.Errorf("the %s fill requires a staleness threshold", options.Fill)
+		}
+	default:
+		return fmt.Errorf("invalid fill %s, expected one of %s, %s, %s or %s", options.Fill, FillNone, FillPrevious, FillNull, FillZero)
+	}
+	return nil
+}
+
+// AutoPaginateDataPoints returns the data point budget for following next tokens
+func (options PaginationOptions) AutoPaginateDataPoints() int {
+	if options.AutoPaginateMaxDataPoints <= 0 {
+		return DefaultAutoPaginateMaxDataPoints
+	}
+	return int(options.AutoPaginateMaxDataPoints)
+}
+
+// PropertyAliasMatches returns the number of data streams a property alias pattern is expanded to
+func (query *AssetPropertyValueQuery) PropertyAliasMatches() int {
+	if query.MaxPropertyAliasMatches <= 0 {
+		return DefaultMaxPropertyAliasMatches
+	}
+	return query.MaxPropertyAliasMatches
+}
+
+// SelectedQualities returns the qualities of the query: GOOD when none is set, and every quality for ANY
+func (query *AssetPropertyValueQuery) SelectedQualities() []iotsitewisetypes.Quality {
+	qualities := query.Qualities
+	if len(qualities) == 0 && query.Quality != "" {
+		qualities = []iotsitewisetypes.Quality{query.Quality}
+	}
+	if len(qualities) == 0 {
+		return []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
+	}
+
+	selected := []iotsitewisetypes.Quality{}
+	for _, quality := range iotsitewisetypes.Quality("").Values() {
+		if slices.Contains(qualities, quality) || slices.Contains(qualities, QualityAny) {
+			selected = append(selected, quality)
+		}
+	}
+	if len(selected) == 0 {
+		return []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
+	}
+	return selected
+}
+
+// RequestQualities returns the quality filter of the SiteWise requests, which accept a single quality.
+// It is nil when several qualities are selected, the values are then filtered by FiltersQualities.
+func (query *AssetPropertyValueQuery) RequestQualities() []iotsitewisetypes.Quality {
+	selected := query.SelectedQualities()
+	if len(selected) == 1 {
+		return selected
+	}
+	return nil
+}
+
+// FiltersQualities returns true when the values of every quality are requested, but only some are selected
+func (query *AssetPropertyValueQuery) FiltersQualities() bool {
+	selected := query.SelectedQualities()
+	return len(selected) > 1 && len(selected) < len(iotsitewisetypes.Quality("").Values())
+}
+
+// RawDataPoints returns the number of raw values fetched for the synthetic aggregates
+func (options SyntheticAggregateOptions) RawDataPoints() int {
+	if options.MaxRawDataPoints <= 0 {
+		return DefaultMaxRawDataPoints
+	}
+	return int(options.MaxRawDataPoints)
+}
+
+// Validate checks the time weighted interpolation
+func (options SyntheticAggregateOptions) Validate() error {
+	switch options.TimeWeightedInterpolation {
+	case "", TimeWeightedStep, TimeWeightedLinear:
+		return nil
+	}
+	return fmt.Errorf("invalid time weighted interpolation %s, expected %s or %s", options.TimeWeightedInterpolation, TimeWeightedStep, TimeWeightedLinear)
+}
+
+// JsonDiscoveryRowCount returns the number of JSON documents the flattened JSON paths are discovered from
+func (options JsonOptions) JsonDiscoveryRowCount() int {
+	if options.JsonDiscoveryRows <= 0 {
+		return DefaultJsonDiscoveryRows
+	}
+	return options.JsonDiscoveryRows
+}
+
+// Validate checks the time shift
+func (options TimeShiftOptions) Validate() error {
+	if options.TimeShift == "" {
+		return nil
+	}
+	_, err := ParseTimeShift(options.TimeShift)
+	return err
+}
+
+// Validate checks that the JSON paths are not empty
+func (options JsonOptions) Validate() error {
+	for _, path := range options.JsonPaths {
+		if strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".") == "" {
+			return fmt.Errorf("invalid JSON path %q, expected for example status.code", path)
+		}
+	}
+	return nil
+}
+
+// NativeAggregateTypes returns the aggregate types of the query that are requested from SiteWise
+func (query *AssetPropertyValueQuery) NativeAggregateTypes() []iotsitewisetypes.AggregateType {
+	native := []iotsitewisetypes.AggregateType{}
+	for _, aggregateType := range query.AggregateTypes {
+		if !IsSyntheticAggregate(string(aggregateType)) {
+			native = append(native, aggregateType)
+		}
+	}
+	return native
+}
+
+// SyntheticAggregateTypes returns the aggregate types of the query that are computed from the raw values
+func (query *AssetPropertyValueQuery) SyntheticAggregateTypes() []string {
+	synthetic := []string{}
+	for _, aggregateType := range query.AggregateTypes {
+		if IsSyntheticAggregate(string(aggregateType)) {
+			synthetic = append(synthetic, string(aggregateType))
+		}
+	}
+	return synthetic
+}
+
+// TimeWeighted returns true when the query has time weighted aggregates
+func (query *AssetPropertyValueQuery) TimeWeighted() bool {
+	return slices.Contains(query.AggregateTypes, AggregateTimeWeightedAverage) || slices.Contains(query.AggregateTypes, AggregateTimeWeightedIntegral)
+}
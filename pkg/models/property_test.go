@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAssetPropertyValueQueryOptions(t *testing.T) {
+	query, err := GetAssetPropertyValueQuery(&backend.DataQuery{JSON: []byte(`{
+		"autoPaginate": true,
+		"autoPaginateMaxDataPoints": 5000,
+		"fill": "null",
+		"stalenessThreshold": "5m",
+		"flattenJson": true,
+		"jsonPaths": ["status.code"],
+		"timeShift": "-1M",
+		"timeShiftCompare": true,
+		"maxRawDataPoints": 1000,
+		"timeWeightedInterpolation": "LINEAR"
+	}`)})
+	require.NoError(t, err)
+
+	assert.Equal(t, PaginationOptions{AutoPaginate: true, AutoPaginateMaxDataPoints: 5000}, query.PaginationOptions)
+	assert.Equal(t, FillOptions{Fill: FillNull, StalenessThreshold: "5m"}, query.FillOptions)
+	assert.Equal(t, JsonOptions{FlattenJson: true, JsonPaths: []string{"status.code"}}, query.JsonOptions)
+	assert.Equal(t, TimeShiftOptions{TimeShift: "-1M", TimeShiftCompare: true}, query.TimeShiftOptions)
+	assert.Equal(t, SyntheticAggregateOptions{MaxRawDataPoints: 1000, TimeWeightedInterpolation: TimeWeightedLinear}, query.SyntheticAggregateOptions)
+}
+
+func TestGetAssetPropertyValueQueryValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		json          string
+		expectedError string
+	}{
+		{
+			name:          "unknown fill",
+			json:          `{"fill":"linear"}`,
+			expectedError: "invalid fill linear, expected one of none, previous, null or zero",
+		},
+		{
+			name:          "zero fill without a staleness threshold",
+			json:          `{"fill":"zero"}`,
+			expectedError: "the zero fill requires a staleness threshold",
+		},
+		{
+			name:          "invalid staleness threshold",
+			json:          `{"fill":"previous","stalenessThreshold":"soon"}`,
+			expectedError: "invalid staleness threshold soon, expected a positive duration",
+		},
+		{
+			name:          "empty JSON path",
+			json:          `{"flattenJson":true,"jsonPaths":["status.code","$."]}`,
+			expectedError: `invalid JSON path "$.", expected for example status.code`,
+		},
+		{
+			name:          "invalid time shift",
+			json:          `{"timeShift":"1 month ago"}`,
+			expectedError: "invalid time shift 1 month ago, expected for example -7d or -1y",
+		},
+		{
+			name:          "unknown time weighted interpolation",
+			json:          `{"timeWeightedInterpolation":"CUBIC"}`,
+			expectedError: "invalid time weighted interpolation CUBIC, expected STEP or LINEAR",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := GetAssetPropertyValueQuery(&backend.DataQuery{JSON: []byte(tc.json)})
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
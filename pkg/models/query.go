@@ -7,18 +7,23 @@ import (
 )
 
 const (
-	QueryTypePropertyValueHistory = "PropertyValueHistory"
-	QueryTypePropertyValue        = "PropertyValue"
-	QueryTypePropertyAggregate    = "PropertyAggregate"
-	QueryTypePropertyInterpolated = "PropertyInterpolated"
-	QueryTypeListAssetModels      = "ListAssetModels"
-	QueryTypeListAssets           = "ListAssets"
-	QueryTypeListAssociatedAssets = "ListAssociatedAssets"
-	QueryTypeDescribeAsset        = "DescribeAsset"
-	QueryTypeDescribeAssetModel   = "DescribeAssetModel"
-	QueryTypeListAssetProperties  = "ListAssetProperties"
-	QueryTypeListTimeSeries       = "ListTimeSeries"
-	QueryTypeExecuteQuery         = "ExecuteQuery"
+	QueryTypePropertyValueHistory  = "PropertyValueHistory"
+	QueryTypePropertyValue         = "PropertyValue"
+	QueryTypePropertyAggregate     = "PropertyAggregate"
+	QueryTypePropertyInterpolated  = "PropertyInterpolated"
+	QueryTypeListAssetModels       = "ListAssetModels"
+	QueryTypeListAssets            = "ListAssets"
+	QueryTypeListAssociatedAssets  = "ListAssociatedAssets"
+	QueryTypeDescribeAsset         = "DescribeAsset"
+	QueryTypeDescribeAssetModel    = "DescribeAssetModel"
+	QueryTypeListAssetProperties   = "ListAssetProperties"
+	QueryTypeListTimeSeries        = "ListTimeSeries"
+	QueryTypeExecuteQuery          = "ExecuteQuery"
+	QueryTypePropertyAnnotations   = "PropertyAnnotations"
+	QueryTypeListAlarms            = "ListAlarms"
+	QueryTypeAlarmHistory          = "AlarmHistory"
+	QueryTypeAssetHierarchy        = "AssetHierarchy"
+	QueryTypeDescribeAssetProperty = "DescribeAssetProperty"
 )
 
 const (
@@ -30,9 +35,49 @@ const (
 	AggregateSum    = "SUM"
 )
 
+// Synthetic aggregates are not offered by SiteWise, they are computed from the raw values.
+// The 50th percentile is AggregateMedian.
+const (
+	AggregateMedian = "MEDIAN"
+	AggregateP90    = "P90"
+	AggregateP95    = "P95"
+	AggregateP99    = "P99"
+
+	// AggregateTimeWeightedAverage weights the values by how long they hold, and
+	// AggregateTimeWeightedIntegral integrates the values over time in value-seconds
+	AggregateTimeWeightedAverage  = "TIME_WEIGHTED_AVERAGE"
+	AggregateTimeWeightedIntegral = "TIME_WEIGHTED_INTEGRAL"
+)
+
+const (
+	// TimeWeightedStep holds each value until the next one
+	TimeWeightedStep = "STEP"
+	// TimeWeightedLinear interpolates linearly between the values
+	TimeWeightedLinear = "LINEAR"
+)
+
+// LastObservationLookback is how far before the time range the last observation is looked up
+const LastObservationLookback = 8760 * time.Hour // 1 year
+
+// SyntheticAggregatePercentiles maps the synthetic aggregates to their percentile
+var SyntheticAggregatePercentiles = map[string]float64{
+	AggregateMedian: 50,
+	AggregateP90:    90,
+	AggregateP95:    95,
+	AggregateP99:    99,
+}
+
+// IsSyntheticAggregate returns true for the aggregate types computed from the raw values
+func IsSyntheticAggregate(aggregateType string) bool {
+	_, percentile := SyntheticAggregatePercentiles[aggregateType]
+	return percentile || aggregateType == AggregateTimeWeightedAverage || aggregateType == AggregateTimeWeightedIntegral
+}
+
 type BaseQuery struct {
 	// General
 	AwsRegion string `json:"region,omitempty"`
+	// AwsRegions fans the property value queries out to each region, the frames are labelled with their region
+	AwsRegions []string `json:"regions,omitempty"`
 
 	QueryType string `json:"-"`
 
@@ -13,12 +13,19 @@ const EDGE_AUTH_MODE_DEFAULT string = "default"
 const EDGE_AUTH_MODE_LDAP string = "ldap"
 const EDGE_AUTH_MODE_LINUX string = "linux"
 
+// DefaultMaxConcurrentQueries is the number of queries of a datasource instance executed at the same time
+const DefaultMaxConcurrentQueries = 8
+
 type AWSSiteWiseDataSourceSetting struct {
 	awsds.AWSDatasourceSettings
 	Cert         string `json:"-"`
 	EdgeAuthMode string `json:"edgeAuthMode"`
 	EdgeAuthUser string `json:"edgeAuthUser"`
 	EdgeAuthPass string `json:"-"`
+
+	MaxConcurrentQueries int `json:"maxConcurrentQueries,omitempty"`
+	// ShiftCalendar is used by the SHIFT aggregates of the queries without a shift calendar
+	ShiftCalendar *ShiftCalendar `json:"shiftCalendar,omitempty"`
 }
 
 func (s *AWSSiteWiseDataSourceSetting) Load(config backend.DataSourceInstanceSettings) error {
@@ -32,6 +39,10 @@ func (s *AWSSiteWiseDataSourceSetting) Load(config backend.DataSourceInstanceSet
 		s.Region = s.DefaultRegion
 	}
 
+	if s.MaxConcurrentQueries < 1 {
+		s.MaxConcurrentQueries = DefaultMaxConcurrentQueries
+	}
+
 	if s.Profile == "" {
 		s.Profile = config.Database // legacy support (only for cloudwatch?)
 	}
@@ -48,6 +59,8 @@ func (s *AWSSiteWiseDataSourceSetting) Load(config backend.DataSourceInstanceSet
 	return nil
 }
 
+// Validate checks the settings the datasource cannot be created without. The shift calendar is only
+// used by the SHIFT aggregates, it is validated by the health check and the queries using it.
 func (s *AWSSiteWiseDataSourceSetting) Validate() error {
 	if s.Region != EDGE_REGION {
 		return nil
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// ResolutionShift aggregates by the occurrences of the shifts of a shift calendar
+const ResolutionShift = "SHIFT"
+
+// ShiftCalendar is a weekly schedule of named shifts in an IANA timezone
+type ShiftCalendar struct {
+	Timezone string  `json:"timezone,omitempty"`
+	Shifts   []Shift `json:"shifts"`
+}
+
+// Shift is a named window from Start to End, in HH:MM wall-clock time, starting on the
+// days of the week (MON, TUE, ...) or every day when Days is empty. A shift ending at or
+// before its start ends on the next day.
+type Shift struct {
+	Name  string   `json:"name"`
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// ShiftOccurrence is a shift on a given day
+type ShiftOccurrence struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+type shiftWindow struct {
+	name       string
+	days       []time.Weekday
+	start, end time.Time
+}
+
+var weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+func (c ShiftCalendar) windows() ([]shiftWindow, *time.Location, error) {
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid shift calendar timezone %s", c.Timezone)
+	}
+	if len(c.Shifts) == 0 {
+		return nil, nil, fmt.Errorf("the shift calendar has no shifts")
+	}
+
+	windows := make([]shiftWindow, 0, len(c.Shifts))
+	for i, shift := range c.Shifts {
+		if shift.Name == "" {
+			return nil, nil, fmt.Errorf("shift %d has no name", i+1)
+		}
+		w := shiftWindow{name: shift.Name}
+		if w.start, err = time.Parse("15:04", shift.Start); err != nil {
+			return nil, nil, fmt.Errorf("invalid start %s of shift %s, expected HH:MM", shift.Start, shift.Name)
+		}
+		if w.end, err = time.Parse("15:04", shift.End); err != nil {
+			return nil, nil, fmt.Errorf("invalid end %s of shift %s, expected HH:MM", shift.End, shift.Name)
+		}
+		for _, day := range shift.Days {
+			idx := slices.Index(weekdays, strings.ToUpper(day))
+			if idx < 0 {
+				return nil, nil, fmt.Errorf("invalid day %s of shift %s, expected one of %s", day, shift.Name, strings.Join(weekdays, ", "))
+			}
+			w.days = append(w.days, time.Weekday(idx))
+		}
+		windows = append(windows, w)
+	}
+	if err := overlappingShifts(windows); err != nil {
+		return nil, nil, err
+	}
+	return windows, location, nil
+}
+
+// overlappingShifts returns an error when two shifts overlap in the wall-clock time of the week,
+// since the data of the overlap would only be aggregated into the first of them
+func overlappingShifts(windows []shiftWindow) error {
+	const week = 7 * 24 * 60
+	type interval struct {
+		name       string
+		start, end int // minutes since Sunday midnight, the end can be in the next week
+	}
+
+	intervals := []interval{}
+	for _, w := range windows {
+		start := w.start.Hour()*60 + w.start.Minute()
+		end := w.end.Hour()*60 + w.end.Minute()
+		if end <= start {
+			end += 24 * 60
+		}
+		days := w.days
+		if len(days) == 0 {
+			days = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+		}
+		for _, day := range days {
+			offset := int(day) * 24 * 60
+			intervals = append(intervals, interval{name: w.name, start: offset + start, end: offset + end})
+		}
+	}
+
+	for i, a := range intervals {
+		for _, b := range intervals[i+1:] {
+			// the shifts of the end of the week overlap the ones of the start of the next week
+			for _, shift := range []int{-week, 0, week} {
+				if a.start < b.end+shift && b.start+shift < a.end {
+					return fmt.Errorf("shifts %s and %s overlap", a.name, b.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the timezone, names, days and times of the shifts, and that the shifts do not overlap
+func (c ShiftCalendar) Validate() error {
+	_, _, err := c.windows()
+	return err
+}
+
+// Occurrences returns the shifts overlapping the time range, ordered by their start.
+// Shifts follow the wall-clock time, so they are shorter or longer across DST transitions.
+func (c ShiftCalendar) Occurrences(timeRange backend.TimeRange) ([]ShiftOccurrence, error) {
+	windows, location, err := c.windows()
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := []ShiftOccurrence{}
+	// a shift started on the day before the time range can overlap it
+	from := timeRange.From.In(location)
+	year, month, day := from.Date()
+	for date := time.Date(year, month, day-1, 0, 0, 0, 0, location); date.Before(timeRange.To); date = date.AddDate(0, 0, 1) {
+		year, month, day := date.Date()
+		for _, w := range windows {
+			if len(w.days) > 0 && !slices.Contains(w.days, date.Weekday()) {
+				continue
+			}
+			endDay := day
+			if !w.end.After(w.start) {
+				endDay++
+			}
+			occurrence := ShiftOccurrence{
+				Name:  w.name,
+				Start: time.Date(year, month, day, w.start.Hour(), w.start.Minute(), 0, 0, location),
+				End:   time.Date(year, month, endDay, w.end.Hour(), w.end.Minute(), 0, 0, location),
+			}
+			if occurrence.End.After(timeRange.From) && occurrence.Start.Before(timeRange.To) {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+
+	slices.SortStableFunc(occurrences, func(a, b ShiftOccurrence) int { return a.Start.Compare(b.Start) })
+	return occurrences, nil
+}
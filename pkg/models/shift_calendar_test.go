@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShiftCalendarValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		shifts        []Shift
+		expectedError string
+	}{
+		{
+			name: "adjacent shifts",
+			shifts: []Shift{
+				{Name: "Day", Start: "06:00", End: "18:00"},
+				{Name: "Night", Start: "18:00", End: "06:00"},
+			},
+		},
+		{
+			name: "shifts on different days",
+			shifts: []Shift{
+				{Name: "Weekday", Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "08:00", End: "20:00"},
+				{Name: "Weekend", Days: []string{"SAT", "SUN"}, Start: "08:00", End: "20:00"},
+			},
+		},
+		{
+			name: "overlapping times",
+			shifts: []Shift{
+				{Name: "Day", Start: "06:00", End: "18:00"},
+				{Name: "Late", Start: "17:00", End: "22:00"},
+			},
+			expectedError: "shifts Day and Late overlap",
+		},
+		{
+			name: "overnight shift overlapping the next day",
+			shifts: []Shift{
+				{Name: "Night", Days: []string{"MON"}, Start: "22:00", End: "06:00"},
+				{Name: "Early", Days: []string{"TUE"}, Start: "05:00", End: "13:00"},
+			},
+			expectedError: "shifts Night and Early overlap",
+		},
+		{
+			name: "overnight shift overlapping the start of the week",
+			shifts: []Shift{
+				{Name: "Early", Days: []string{"SUN"}, Start: "05:00", End: "13:00"},
+				{Name: "Night", Days: []string{"SAT"}, Start: "22:00", End: "06:00"},
+			},
+			expectedError: "shifts Early and Night overlap",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ShiftCalendar{Timezone: "UTC", Shifts: tc.shifts}.Validate()
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DefaultStreamPollInterval is used when a stream does not request a poll interval
+	DefaultStreamPollInterval = 5 * time.Second
+	// MinStreamPollInterval protects the SiteWise API quota from overly aggressive streams
+	MinStreamPollInterval = time.Second
+)
+
+// PropertyValueStreamQuery is sent as the data of a Grafana Live subscription.
+// It selects the latest values of a set of asset/property entries or property aliases.
+type PropertyValueStreamQuery struct {
+	AssetPropertyValueQuery
+	PollIntervalMs int64 `json:"pollIntervalMs,omitempty"`
+}
+
+func GetPropertyValueStreamQuery(raw json.RawMessage) (*PropertyValueStreamQuery, error) {
+	query := &PropertyValueStreamQuery{}
+	if err := json.Unmarshal(raw, query); err != nil {
+		return nil, err
+	}
+
+	// Backward compatibility for asset, property, and property alias string --> list
+	query.MigrateAssetProperty()
+
+	query.QueryType = QueryTypePropertyValue
+
+	return query, nil
+}
+
+// PollInterval returns the requested poll interval, bounded by MinStreamPollInterval
+func (query *PropertyValueStreamQuery) PollInterval() time.Duration {
+	if query.PollIntervalMs <= 0 {
+		return DefaultStreamPollInterval
+	}
+
+	interval := time.Duration(query.PollIntervalMs) * time.Millisecond
+	if interval < MinStreamPollInterval {
+		return MinStreamPollInterval
+	}
+	return interval
+}
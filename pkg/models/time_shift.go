@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TimeShiftPeriod labels the frames of the shifted time range
+const TimeShiftPeriod = "period"
+
+var timeShiftPattern = regexp.MustCompile(`^([+-]?)(\d+)(s|m|h|d|w|M|y)$`)
+
+// TimeShift moves a time range by a number of seconds, minutes, hours, days, weeks, months or years. The
+// days and longer units follow the calendar.
+type TimeShift struct {
+	years, months, days int
+	duration            time.Duration
+}
+
+// ParseTimeShift parses a shift like -7d, -1y or 12h, where M is a month
+func ParseTimeShift(s string) (TimeShift, error) {
+	match := timeShiftPattern.FindStringSubmatch(s)
+	if match == nil {
+		return TimeShift{}, fmt.Errorf("invalid time shift %s, expected for example -7d or -1y", s)
+	}
+	n, err := strconv.Atoi(match[2])
+	if err != nil {
+		return TimeShift{}, fmt.Errorf("invalid time shift %s, expected for example -7d or -1y", s)
+	}
+	if match[1] == "-" {
+		n = -n
+	}
+
+	switch match[3] {
+	case "s":
+		return TimeShift{duration: time.Duration(n) * time.Second}, nil
+	case "m":
+		return TimeShift{duration: time.Duration(n) * time.Minute}, nil
+	case "h":
+		return TimeShift{duration: time.Duration(n) * time.Hour}, nil
+	case "d":
+		return TimeShift{days: n}, nil
+	case "w":
+		return TimeShift{days: 7 * n}, nil
+	case "M":
+		return TimeShift{months: n}, nil
+	default:
+		return TimeShift{years: n}, nil
+	}
+}
+
+// Apply shifts the time. The month and year shifts keep the day of the month, clamped to the last day of the
+// shifted month: Mar 31 shifted by -1M is the last day of February.
+func (s TimeShift) Apply(t time.Time) time.Time {
+	if s.years != 0 || s.months != 0 {
+		year, month, day := t.Date()
+		year, month = year+s.years, month+time.Month(s.months)
+		lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		t = time.Date(year, month, min(day, lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	return t.AddDate(0, 0, s.days).Add(s.duration)
+}
+
+// Offset is the duration moving the times of the range shifted from the given start back onto the
+// original range. Unlike reverting the shift of each time, which is lossy for the month ends and the
+// daylight saving time changes, the offset keeps the shifted values in order and aligns the starts.
+func (s TimeShift) Offset(from time.Time) time.Duration {
+	return from.Sub(s.Apply(from))
+}
+
+// Period returns the label of the frames of the shifted range
+func (s TimeShift) Period() string {
+	if s.years < 0 || s.months < 0 || s.days < 0 || s.duration < 0 {
+		return "previous period"
+	}
+	return "next period"
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeShiftApply(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		shift    string
+		time     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "month end to a shorter month",
+			shift:    "-1M",
+			time:     time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "month end to a leap February",
+			shift:    "1M",
+			time:     time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "leap day to the previous year",
+			shift:    "-1y",
+			time:     time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "months across the year",
+			shift:    "-3M",
+			time:     time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "days keep the local time across daylight saving time",
+			shift:    "-1d",
+			time:     time.Date(2025, 3, 31, 0, 0, 0, 0, berlin),
+			expected: time.Date(2025, 3, 30, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "hours",
+			shift:    "-12h",
+			time:     time.Date(2025, 3, 31, 6, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 3, 30, 18, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shift, err := ParseTimeShift(tc.shift)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(shift.Apply(tc.time)), "expected %s, got %s", tc.expected, shift.Apply(tc.time))
+		})
+	}
+}
+
+func TestTimeShiftOffset(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		shift    string
+		from     time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "month end",
+			shift:    "-1M",
+			from:     time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC),
+			expected: 31 * 24 * time.Hour,
+		},
+		{
+			name:     "year",
+			shift:    "-1y",
+			from:     time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: 365 * 24 * time.Hour,
+		},
+		{
+			// Mar 30 is 23 hours long in Berlin
+			name:     "day before the change to daylight saving time",
+			shift:    "-1d",
+			from:     time.Date(2025, 3, 31, 0, 0, 0, 0, berlin),
+			expected: 23 * time.Hour,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shift, err := ParseTimeShift(tc.shift)
+			require.NoError(t, err)
+
+			offset := shift.Offset(tc.from)
+			assert.Equal(t, tc.expected, offset)
+			// the start of the shifted range is moved back onto the start of the range
+			assert.True(t, tc.from.Equal(shift.Apply(tc.from).Add(offset)))
+		})
+	}
+
+	t.Run("keeps the shifted values in order around the month end", func(t *testing.T) {
+		shift, err := ParseTimeShift("-1M")
+		require.NoError(t, err)
+
+		from := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+		offset := shift.Offset(from)
+		shifted := []time.Time{
+			time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+			time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		}
+		for i := 1; i < len(shifted); i++ {
+			assert.True(t, shifted[i-1].Add(offset).Before(shifted[i].Add(offset)))
+		}
+		assert.True(t, time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC).Equal(shifted[0].Add(offset)))
+		assert.True(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC).Equal(shifted[3].Add(offset)))
+	})
+}
@@ -10,8 +10,11 @@ import (
 )
 
 type Datasource interface {
-	HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) error
+	HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) (*models.HealthReport, error)
 	HandleInterpolatedPropertyValueQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error)
+	HandleAnnotationsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error)
+	HandleListAlarmsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AlarmQuery) (data.Frames, error)
+	HandleAlarmHistoryQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AlarmQuery) (data.Frames, error)
 	HandleGetAssetPropertyValueHistoryQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
 	HandleGetAssetPropertyAggregateQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
 	HandleGetAssetPropertyValueQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
@@ -19,6 +22,8 @@ type Datasource interface {
 	HandleListAssetsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssetsQuery) (data.Frames, error)
 	HandleDescribeAssetQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetQuery) (data.Frames, error)
 	HandleListAssociatedAssetsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssociatedAssetsQuery) (data.Frames, error)
+	HandleAssetHierarchyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AssetHierarchyQuery) (data.Frames, error)
+	HandleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetPropertyQuery) (data.Frames, error)
 	HandleDescribeAssetModelQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetModelQuery) (data.Frames, error)
 	HandleListTimeSeriesQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListTimeSeriesQuery) (data.Frames, error)
 	HandleExecuteQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ExecuteQuery) (data.Frames, error)
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"math"
+	"sync"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -11,10 +12,31 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 )
 
-func processQueries(ctx context.Context, req *backend.QueryDataRequest, handler QueryHandlerFunc) *backend.QueryDataResponse {
+// processQueries runs the queries of a request concurrently, bounded by the worker pool of the datasource instance
+func (s *Server) processQueries(ctx context.Context, req *backend.QueryDataRequest, handler QueryHandlerFunc) *backend.QueryDataResponse {
+	responses := make([]backend.DataResponse, len(req.Queries))
+
+	var wg sync.WaitGroup
+	for i, v := range req.Queries {
+		wg.Add(1)
+		go func(i int, v backend.DataQuery) {
+			defer wg.Done()
+
+			release, err := s.acquireQuerySlot(ctx)
+			if err != nil {
+				responses[i] = DataResponseErrorRequestFailed(err)
+				return
+			}
+			defer release()
+
+			responses[i] = handler(ctx, req, v)
+		}(i, v)
+	}
+	wg.Wait()
+
 	res := backend.Responses{}
-	for _, v := range req.Queries {
-		res[v.RefID] = handler(ctx, req, v)
+	for i, v := range req.Queries {
+		res[v.RefID] = responses[i]
 	}
 
 	return &backend.QueryDataResponse{
@@ -22,52 +44,95 @@ func processQueries(ctx context.Context, req *backend.QueryDataRequest, handler
 	}
 }
 
+// queryPool returns the semaphore limiting the number of queries running at the same time for this datasource instance
+func (s *Server) queryPool() chan struct{} {
+	s.queryPoolOnce.Do(func() {
+		size := s.Datasource.Cfg.MaxConcurrentQueries
+		if size < 1 {
+			size = models.DefaultMaxConcurrentQueries
+		}
+		s.querySlots = make(chan struct{}, size)
+	})
+	return s.querySlots
+}
+
+// acquireQuerySlot waits for a free slot of the query pool, the returned func releases the slot
+func (s *Server) acquireQuerySlot(ctx context.Context) (func(), error) {
+	pool := s.queryPool()
+	select {
+	case pool <- struct{}{}:
+		return func() { <-pool }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (s *Server) HandleInterpolatedPropertyValue(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleInterpolatedPropertyValueQuery), nil
+	return s.processQueries(ctx, req, s.handleInterpolatedPropertyValueQuery), nil
 }
 
 func (s *Server) HandlePropertyValueHistory(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyValueHistoryQuery), nil
+	return s.processQueries(ctx, req, s.handlePropertyValueHistoryQuery), nil
 }
 
 func (s *Server) HandlePropertyAggregate(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyAggregateQuery), nil
+	return s.processQueries(ctx, req, s.handlePropertyAggregateQuery), nil
 }
 
 func (s *Server) HandlePropertyValue(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyValueQuery), nil
+	return s.processQueries(ctx, req, s.handlePropertyValueQuery), nil
 }
 
 func (s *Server) HandleListAssetModels(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetModelsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetModelsQuery), nil
 }
 
 func (s *Server) HandleListAssets(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetsQuery), nil
 }
 
 func (s *Server) HandleDescribeAsset(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleDescribeAssetQuery), nil
+	return s.processQueries(ctx, req, s.handleDescribeAssetQuery), nil
 }
 
 func (s *Server) HandleListTimeSeries(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListTimeSeriesQuery), nil
+	return s.processQueries(ctx, req, s.handleListTimeSeriesQuery), nil
 }
 
 func (s *Server) HandleListAssetProperties(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetPropertiesQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetPropertiesQuery), nil
 }
 
 func (s *Server) HandleListAssociatedAssets(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssociatedAssetsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssociatedAssetsQuery), nil
+}
+
+func (s *Server) HandleAssetHierarchy(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handleAssetHierarchyQuery), nil
+}
+
+func (s *Server) HandleDescribeAssetProperty(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handleDescribeAssetPropertyQuery), nil
 }
 
 func (s *Server) HandleDescribeAssetModel(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleDescribeAssetModelQuery), nil
+	return s.processQueries(ctx, req, s.handleDescribeAssetModelQuery), nil
 }
 
 func (s *Server) HandleExecuteQuery(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleExecuteQuery), nil
+	return s.processQueries(ctx, req, s.handleExecuteQuery), nil
+}
+
+func (s *Server) HandlePropertyAnnotations(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handlePropertyAnnotationsQuery), nil
+}
+
+func (s *Server) HandleListAlarms(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handleListAlarmsQuery), nil
+}
+
+func (s *Server) HandleAlarmHistory(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handleAlarmHistoryQuery), nil
 }
 
 func (s *Server) handleInterpolatedPropertyValueQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
@@ -110,7 +175,7 @@ func (s *Server) handlePropertyValueHistoryQuery(ctx context.Context, req *backe
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
 		for i, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+			wide, err := longToWide(frame)
 			if err == nil {
 				frames[i] = wide
 			}
@@ -123,6 +188,57 @@ func (s *Server) handlePropertyValueHistoryQuery(ctx context.Context, req *backe
 	}
 }
 
+func (s *Server) handlePropertyAnnotationsQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetAssetPropertyValueQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleAnnotationsQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+func (s *Server) handleListAlarmsQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetAlarmQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleListAlarmsQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+func (s *Server) handleAlarmHistoryQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetAlarmQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleAlarmHistoryQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
 func (s *Server) handlePropertyAggregateQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
 
 	query, err := models.GetAssetPropertyValueQuery(&q)
@@ -145,18 +261,39 @@ func (s *Server) handlePropertyAggregateQuery(ctx context.Context, req *backend.
 	}
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
+		frames = timeseriesFrames(query, frames)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+// timeseriesFrames converts the frames to the wide format. The frames of a query expanded to the assets
+// of a model or of a hierarchy each hold an asset, and are all kept. The other queries only return the
+// last converted frame.
+func timeseriesFrames(query *models.AssetPropertyValueQuery, frames data.Frames) data.Frames {
+	if query.AssetModelId == "" && query.PropertyName == "" {
 		for _, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+			wide, err := longToWide(frame)
 			if err == nil {
 				frames = []*data.Frame{wide}
 			}
 		}
+		return frames
 	}
 
-	return backend.DataResponse{
-		Frames: frames,
-		Error:  nil,
+	wideFrames := make(data.Frames, 0, len(frames))
+	for _, frame := range frames {
+		wide, err := longToWide(frame)
+		if err != nil {
+			wideFrames = append(wideFrames, frame)
+			continue
+		}
+		wideFrames = append(wideFrames, wide)
 	}
+	return wideFrames
 }
 
 func (s *Server) handlePropertyValueQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
@@ -172,12 +309,7 @@ func (s *Server) handlePropertyValueQuery(ctx context.Context, req *backend.Quer
 	}
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
-		for _, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
-			if err == nil {
-				frames = []*data.Frame{wide}
-			}
-		}
+		frames = timeseriesFrames(query, frames)
 	}
 
 	return backend.DataResponse{
@@ -276,6 +408,40 @@ func (s *Server) handleDescribeAssetQuery(ctx context.Context, req *backend.Quer
 	}
 }
 
+func (s *Server) handleAssetHierarchyQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetAssetHierarchyQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleAssetHierarchyQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+func (s *Server) handleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetDescribeAssetPropertyQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleDescribeAssetPropertyQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
 func (s *Server) handleListAssetPropertiesQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
 	query, err := models.GetListAssetPropertiesQuery(&q)
 	if err != nil {
@@ -335,3 +501,31 @@ func (s *Server) handleExecuteQuery(ctx context.Context, req *backend.QueryDataR
 		Error:  nil,
 	}
 }
+
+// longToWide converts a long frame to a wide frame. The labels of the long fields,
+// such as the asset of a query on an asset model, are merged with the labels of the factors.
+func longToWide(frame *data.Frame) (*data.Frame, error) {
+	wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range frame.Fields {
+		if len(field.Labels) == 0 {
+			continue
+		}
+		for _, wideField := range wide.Fields {
+			if wideField.Name != field.Name {
+				continue
+			}
+			if wideField.Labels == nil {
+				wideField.Labels = data.Labels{}
+			}
+			for k, v := range field.Labels {
+				wideField.Labels[k] = v
+			}
+		}
+	}
+
+	return wide, nil
+}
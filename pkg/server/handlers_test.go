@@ -3,18 +3,22 @@ package server
 import (
 	"context"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -119,3 +123,37 @@ func TestHandlerExecution(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessQueriesConcurrently(t *testing.T) {
+	server := Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{MaxConcurrentQueries: 2},
+		},
+	}
+
+	req := &backend.QueryDataRequest{}
+	for _, refID := range []string{"A", "B", "C", "D", "E"} {
+		req.Queries = append(req.Queries, backend.DataQuery{RefID: refID})
+	}
+
+	var running, maxRunning atomic.Int32
+	res := server.processQueries(context.Background(), req, func(_ context.Context, _ *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return backend.DataResponse{Frames: data.Frames{data.NewFrame(q.RefID)}}
+	})
+
+	assert.Equal(t, int32(2), maxRunning.Load())
+	require.Len(t, res.Responses, 5)
+	for refID, r := range res.Responses {
+		require.Len(t, r.Frames, 1)
+		assert.Equal(t, refID, r.Frames[0].Name)
+	}
+}
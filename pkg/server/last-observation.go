@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
@@ -15,18 +16,49 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
 )
 
+// lastObservationResult holds the follow-up queries fetching the values around the time range of a query
+type lastObservationResult struct {
+	last, next       backend.DataResponse
+	lastErr, nextErr error
+}
+
 func (s *Server) lastObservation(h handler) handler {
 	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 		resp := &backend.QueryDataResponse{
 			Responses: make(map[string]backend.DataResponse),
 		}
 
-		queries := make(map[string]backend.DataQuery, len(req.Queries))
+		// the follow-up queries run next to the main query, their result is dropped
+		// when the main query turns out not to be the last page of data
+		var wg sync.WaitGroup
+		followUps := map[string]*lastObservationResult{}
 		for _, q := range req.Queries {
-			queries[q.RefID] = q
+			// ensure that this is a supported query type, and that the user requested last observation
+			assetQuery, err := models.GetAssetPropertyValueQuery(&q)
+			if err != nil || !assetQuery.LastObservation {
+				continue
+			}
+			// a following page is not the last page of the first request, its follow-ups were already fetched
+			if assetQuery.NextToken != "" || len(assetQuery.NextTokens) > 0 {
+				continue
+			}
+
+			result := &lastObservationResult{}
+			followUps[q.RefID] = result
+
+			wg.Add(2)
+			go func(query backend.DataQuery) {
+				defer wg.Done()
+				result.last, result.lastErr = s.lastValueQuery(ctx, req, query, iotsitewisetypes.TimeOrderingDescending)
+			}(q)
+			go func(query backend.DataQuery) {
+				defer wg.Done()
+				result.next, result.nextErr = s.lastValueQuery(ctx, req, query, iotsitewisetypes.TimeOrderingAscending)
+			}(q)
 		}
 
 		origResp, err := h(ctx, req)
+		wg.Wait()
 		if err != nil {
 			return nil, err
 		}
@@ -37,7 +69,6 @@ func (s *Server) lastObservation(h handler) handler {
 				continue
 			}
 
-			query := queries[refID]
 			resp.Responses[refID] = res
 
 			// ensure this is the last page of data
@@ -47,26 +78,21 @@ func (s *Server) lastObservation(h handler) handler {
 				}
 			}
 
-			// ensure that this is a supported query type, and that the user requested last observation
-			assetQuery, err := models.GetAssetPropertyValueQuery(&query)
-			if err != nil || !assetQuery.LastObservation {
+			result, ok := followUps[refID]
+			if !ok {
 				continue
 			}
 
-			lastValueRes, err := s.lastValueQuery(ctx, query, iotsitewisetypes.TimeOrderingDescending)
-			if err != nil {
-				log.DefaultLogger.Debug("failed to fetch last observation", "error", err)
-			}
-			if r, ok := resp.Responses[refID]; err == nil && ok {
-				resp.Responses[refID] = mergeLastValueResponse(r, lastValueRes)
+			if result.lastErr != nil {
+				log.DefaultLogger.Debug("failed to fetch last observation", "error", result.lastErr)
+			} else {
+				resp.Responses[refID] = mergeLastValueResponse(resp.Responses[refID], result.last)
 			}
 
-			nextValueRes, err := s.lastValueQuery(ctx, query, iotsitewisetypes.TimeOrderingAscending)
-			if err != nil {
-				log.DefaultLogger.Debug("failed to fetch next observation", "error", err)
-			}
-			if r, ok := resp.Responses[refID]; err == nil && ok {
-				resp.Responses[refID] = mergeLastValueResponse(r, nextValueRes)
+			if result.nextErr != nil {
+				log.DefaultLogger.Debug("failed to fetch next observation", "error", result.nextErr)
+			} else {
+				resp.Responses[refID] = mergeLastValueResponse(resp.Responses[refID], result.next)
 			}
 		}
 
@@ -74,12 +100,31 @@ func (s *Server) lastObservation(h handler) handler {
 	}
 }
 
-func (s *Server) lastValueQuery(ctx context.Context, query backend.DataQuery, timeOrdering iotsitewisetypes.TimeOrdering) (backend.DataResponse, error) {
+// lastValueHandler returns the handler of a single follow-up query. The follow-up queries call it
+// directly, going through QueryData would wait for a second slot of the query pool.
+func (s *Server) lastValueHandler(queryType string) (QueryHandlerFunc, error) {
+	switch queryType {
+	case models.QueryTypePropertyValueHistory:
+		return s.handlePropertyValueHistoryQuery, nil
+	case models.QueryTypePropertyAggregate:
+		return s.handlePropertyAggregateQuery, nil
+	case models.QueryTypePropertyInterpolated:
+		return s.handleInterpolatedPropertyValueQuery, nil
+	}
+	return nil, fmt.Errorf("last observation is not supported by %s queries", queryType)
+}
+
+func (s *Server) lastValueQuery(ctx context.Context, req *backend.QueryDataRequest, query backend.DataQuery, timeOrdering iotsitewisetypes.TimeOrdering) (backend.DataResponse, error) {
+	handle, err := s.lastValueHandler(query.QueryType)
+	if err != nil {
+		return backend.DataResponse{}, err
+	}
+
 	query.MaxDataPoints = 1
 	switch timeOrdering {
 	case iotsitewisetypes.TimeOrderingDescending:
 		query.TimeRange.To = query.TimeRange.From.Add(-1 * time.Second)
-		query.TimeRange.From = query.TimeRange.From.Add(-8760 * time.Hour) // 1 year ago
+		query.TimeRange.From = query.TimeRange.From.Add(-models.LastObservationLookback)
 
 	case iotsitewisetypes.TimeOrderingAscending:
 		query.TimeRange.From = query.TimeRange.To.Add(time.Second)
@@ -93,6 +138,7 @@ func (s *Server) lastValueQuery(ctx context.Context, query backend.DataQuery, ti
 	assetQuery.NextToken = ""
 	assetQuery.TimeOrdering = timeOrdering
 	assetQuery.LastObservation = false
+	assetQuery.AutoPaginate = false
 	assetQuery.MaxDataPoints = 1
 	assetQuery.MaxPageAggregations = 1
 	assetQuery.TimeRange = query.TimeRange
@@ -104,13 +150,14 @@ func (s *Server) lastValueQuery(ctx context.Context, query backend.DataQuery, ti
 		return backend.DataResponse{}, err
 	}
 
-	res, err := s.QueryData(ctx, &backend.QueryDataRequest{Queries: []backend.DataQuery{query}})
+	release, err := s.acquireQuerySlot(ctx)
 	if err != nil {
 		return backend.DataResponse{}, err
 	}
+	defer release()
 
-	dataRes, ok := res.Responses[query.RefID]
-	if !ok || dataRes.Error != nil || len(dataRes.Frames) == 0 || dataRes.Frames[0].Rows() == 0 {
+	dataRes := handle(ctx, &backend.QueryDataRequest{PluginContext: req.PluginContext, Queries: []backend.DataQuery{query}}, query)
+	if dataRes.Error != nil || len(dataRes.Frames) == 0 || dataRes.Frames[0].Rows() == 0 {
 		return backend.DataResponse{}, fmt.Errorf("no response for query %s", query.RefID)
 	}
 
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLastObservationHistory returns a single value for every history call and records
+// the largest number of calls running at the same time
+func mockLastObservationHistory(maxRunning *atomic.Int32) *mocks.SitewiseAPIClient {
+	var running atomic.Int32
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Turbine"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("property-1"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Name:     aws.String("Availability"),
+		},
+	}, nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		}).
+		Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+			SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId: util.GetEntryIdFromAssetProperty("asset-1", "property-1"),
+				AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+					Quality:   iotsitewisetypes.QualityGood,
+					Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(1612207200), OffsetInNanos: aws.Int32(0)},
+					Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(0.9)},
+				}},
+			}},
+		}, nil)
+	return mockSw
+}
+
+func lastObservationServer(mockSw *mocks.SitewiseAPIClient) *Server {
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{
+					Region: "us-west-2",
+				},
+				MaxConcurrentQueries: 1,
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+	}
+	srvr.queryMux = getQueryHandlers(srvr)
+	return srvr
+}
+
+func TestLastObservationFollowUpsTakeQuerySlots(t *testing.T) {
+	var maxRunning atomic.Int32
+	mockSw := mockLastObservationHistory(&maxRunning)
+	srvr := lastObservationServer(mockSw)
+
+	res, err := srvr.QueryData(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValueHistory,
+			TimeRange: backend.TimeRange{From: time.Unix(1612207100, 0), To: time.Unix(1612207300, 0)},
+			JSON:      []byte(`{"assetIds":["asset-1"],"propertyIds":["property-1"],"lastObservation":true}`),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, res.Responses["A"].Error)
+
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 3)
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestLastObservationSkipsFollowingPages(t *testing.T) {
+	var maxRunning atomic.Int32
+	mockSw := mockLastObservationHistory(&maxRunning)
+	srvr := lastObservationServer(mockSw)
+
+	res, err := srvr.QueryData(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValueHistory,
+			TimeRange: backend.TimeRange{From: time.Unix(1612207100, 0), To: time.Unix(1612207300, 0)},
+			JSON:      []byte(`{"assetIds":["asset-1"],"propertyIds":["property-1"],"lastObservation":true,"nextToken":"page-2"}`),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, res.Responses["A"].Error)
+
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 1)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// regionLabel labels the fields of the frames of a query fanned out to several regions
+const regionLabel = "region"
+
+// multiRegion fans the queries with a list of regions out to each region concurrently, and merges the
+// frames of the regions with a region label. The error of a region is a notice of the response, the
+// other regions are still returned.
+func (s *Server) multiRegion(h handler) handler {
+	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+		single := []backend.DataQuery{}
+		fanOut := map[string][]string{}
+		for _, q := range req.Queries {
+			var query struct {
+				Regions []string `json:"regions"`
+			}
+			if err := json.Unmarshal(q.JSON, &query); err != nil || len(query.Regions) == 0 {
+				single = append(single, q)
+				continue
+			}
+			fanOut[q.RefID] = query.Regions
+		}
+		if len(fanOut) == 0 {
+			return h(ctx, req)
+		}
+
+		resp := backend.NewQueryDataResponse()
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		if len(single) > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				singleReq := *req
+				singleReq.Queries = single
+				res, err := h(ctx, &singleReq)
+				mu.Lock()
+				defer mu.Unlock()
+				for _, q := range single {
+					if err != nil {
+						resp.Responses[q.RefID] = DataResponseErrorRequestFailed(err)
+					} else {
+						resp.Responses[q.RefID] = res.Responses[q.RefID]
+					}
+				}
+			}()
+		}
+
+		for _, q := range req.Queries {
+			regions, ok := fanOut[q.RefID]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func(q backend.DataQuery, regions []string) {
+				defer wg.Done()
+				res := s.regionsQuery(ctx, req, q, regions)
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Responses[q.RefID] = res
+			}(q, regions)
+		}
+		wg.Wait()
+
+		return resp, nil
+	}
+}
+
+// regionsQuery runs the query in each of the regions concurrently and merges their frames in the order of the regions
+func (s *Server) regionsQuery(ctx context.Context, req *backend.QueryDataRequest, query backend.DataQuery, regions []string) backend.DataResponse {
+	regionFrames := make([]data.Frames, len(regions))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			res, err := s.regionQuery(ctx, req, query, region)
+			if err == nil {
+				err = res.Error
+			}
+			if err != nil {
+				frame := data.NewFrame("")
+				frame.AppendNotices(data.Notice{
+					Severity: data.NoticeSeverityError,
+					Text:     fmt.Sprintf("region %s: %s", region, err.Error()),
+				})
+				regionFrames[i] = data.Frames{frame}
+				return
+			}
+			for _, frame := range res.Frames {
+				labelRegion(frame, region)
+			}
+			regionFrames[i] = res.Frames
+		}(i, region)
+	}
+	wg.Wait()
+
+	frames := data.Frames{}
+	for _, f := range regionFrames {
+		frames = append(frames, f...)
+	}
+	return backend.DataResponse{Frames: frames}
+}
+
+func (s *Server) regionQuery(ctx context.Context, req *backend.QueryDataRequest, query backend.DataQuery, region string) (backend.DataResponse, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(query.JSON, &raw); err != nil {
+		return backend.DataResponse{}, err
+	}
+	regionJSON, err := json.Marshal(region)
+	if err != nil {
+		return backend.DataResponse{}, err
+	}
+	raw["region"] = regionJSON
+	delete(raw, "regions")
+	// the next tokens of a page belong to a single region
+	delete(raw, "nextToken")
+	delete(raw, "nextTokens")
+	if query.JSON, err = json.Marshal(raw); err != nil {
+		return backend.DataResponse{}, err
+	}
+
+	regionReq := *req
+	regionReq.Queries = []backend.DataQuery{query}
+	res, err := s.QueryData(ctx, &regionReq)
+	if err != nil {
+		return backend.DataResponse{}, err
+	}
+	dataRes, ok := res.Responses[query.RefID]
+	if !ok {
+		return backend.DataResponse{}, fmt.Errorf("no response for query %s", query.RefID)
+	}
+	return dataRes, nil
+}
+
+// labelRegion labels the value fields with the region. The pages of the other regions can not be
+// requested with the next token of a region, so a frame with more pages is truncated instead.
+func labelRegion(frame *data.Frame, region string) {
+	for _, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime || field.Type() == data.FieldTypeNullableTime {
+			continue
+		}
+		if field.Labels == nil {
+			field.Labels = data.Labels{}
+		}
+		field.Labels[regionLabel] = region
+	}
+
+	if frame.Meta == nil {
+		return
+	}
+	if meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta); ok && meta.NextToken != "" {
+		meta.NextToken = ""
+		meta.Truncated = true
+		frame.Meta.Custom = meta
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockRegionClient(value float64) *mocks.SitewiseAPIClient {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Turbine"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("property-1"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Name:     aws.String("Availability"),
+		},
+	}, nil)
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry{{
+			EntryId: util.GetEntryIdFromAssetProperty("asset-1", "property-1"),
+			AssetPropertyValue: &iotsitewisetypes.AssetPropertyValue{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(1612207200), OffsetInNanos: aws.Int32(0)},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(value)},
+			},
+		}},
+	}, nil)
+	return mockSw
+}
+
+func TestMultiRegion(t *testing.T) {
+	regionClients := map[string]client.SitewiseAPIClient{
+		"eu-west-1": mockRegionClient(0.9),
+		"us-east-1": mockRegionClient(0.8),
+	}
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{
+					Region: "us-west-2",
+				},
+			},
+			GetClient: func(_ context.Context, region string) (client.SitewiseAPIClient, error) {
+				if sw, ok := regionClients[region]; ok {
+					return sw, nil
+				}
+				return nil, errors.New("no credentials")
+			},
+		},
+	}
+	srvr.queryMux = getQueryHandlers(srvr)
+
+	res, err := srvr.QueryData(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyValue,
+				JSON:      []byte(`{"regions":["eu-west-1","us-east-1","ap-south-1"],"assetIds":["asset-1"],"propertyIds":["property-1"]}`),
+			},
+		},
+	})
+	require.NoError(t, err)
+	dataRes := res.Responses["A"]
+	require.NoError(t, dataRes.Error)
+	require.Len(t, dataRes.Frames, 3)
+
+	for i, region := range []string{"eu-west-1", "us-east-1"} {
+		frame := dataRes.Frames[i]
+		value, _ := frame.FieldByName("Availability")
+		require.NotNil(t, value)
+		assert.Equal(t, data.Labels{"region": region}, value.Labels)
+		assert.Equal(t, []float64{0.9, 0.8}[i], value.At(0))
+		time, _ := frame.FieldByName("time")
+		assert.Nil(t, time.Labels)
+	}
+
+	assert.Equal(t, []data.Notice{{
+		Severity: data.NoticeSeverityError,
+		Text:     "region ap-south-1: failed to fetch query data: no credentials",
+	}}, dataRes.Frames[2].Meta.Notices)
+}
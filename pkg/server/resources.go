@@ -0,0 +1,414 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// errInvalidResourceRequest is wrapped by the errors of invalid route parameters, they are reported as bad requests
+var errInvalidResourceRequest = errors.New("invalid resource request")
+
+// resourcePage is the JSON envelope for list routes. Pass nextToken back to load the next page.
+type resourcePage[T any] struct {
+	Items     []T    `json:"items"`
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+type hierarchyResource struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	ChildModelId string `json:"childModelId,omitempty"`
+}
+
+type propertyResource struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Alias    string `json:"alias,omitempty"`
+	DataType string `json:"dataType,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+}
+
+type assetModelResource struct {
+	Id          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Status      string              `json:"status,omitempty"`
+	Properties  []propertyResource  `json:"properties,omitempty"`
+	Hierarchies []hierarchyResource `json:"hierarchies,omitempty"`
+}
+
+type assetResource struct {
+	Id          string              `json:"id"`
+	Name        string              `json:"name"`
+	ModelId     string              `json:"modelId"`
+	Status      string              `json:"status,omitempty"`
+	Properties  []propertyResource  `json:"properties,omitempty"`
+	Hierarchies []hierarchyResource `json:"hierarchies,omitempty"`
+}
+
+type timeSeriesResource struct {
+	Id         string `json:"id"`
+	Alias      string `json:"alias,omitempty"`
+	AssetId    string `json:"assetId,omitempty"`
+	PropertyId string `json:"propertyId,omitempty"`
+	DataType   string `json:"dataType,omitempty"`
+}
+
+type resourceError struct {
+	Error string `json:"error"`
+}
+
+// getResourceHandler creates the REST style routes used by the asset browser and variable editors.
+// Every route accepts an optional `region` query parameter.
+func getResourceHandler(s *Server) backend.CallResourceHandler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /models", s.handleResourceModels)
+	mux.HandleFunc("GET /models/{id}", s.handleResourceModel)
+	mux.HandleFunc("GET /assets", s.handleResourceAssets)
+	mux.HandleFunc("GET /assets/{id}", s.handleResourceAsset)
+	mux.HandleFunc("GET /assets/{id}/children", s.handleResourceAssetChildren)
+	mux.HandleFunc("GET /assets/{id}/properties", s.handleResourceAssetProperties)
+	mux.HandleFunc("GET /timeseries", s.handleResourceTimeSeries)
+
+	return httpadapter.New(mux)
+}
+
+// CallResource handles the resource routes registered in getResourceHandler
+func (s *Server) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return s.resourceHandler.CallResource(ctx, req, sender)
+}
+
+func (s *Server) resourceClient(r *http.Request) (client.SitewiseAPIClient, error) {
+	return s.Datasource.Client(r.Context(), r.URL.Query().Get("region"))
+}
+
+// resourceMaxResults parses the optional `maxResults` query parameter, 0 when it is missing
+func resourceMaxResults(r *http.Request) (int32, error) {
+	value := r.URL.Query().Get("maxResults")
+	if value == "" {
+		return 0, nil
+	}
+	maxResults, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || maxResults < 1 {
+		return 0, fmt.Errorf("%w: maxResults must be a positive number", errInvalidResourceRequest)
+	}
+	return int32(maxResults), nil
+}
+
+// resourceBaseQuery maps the common query parameters onto a BaseQuery for the api package
+func resourceBaseQuery(r *http.Request) models.BaseQuery {
+	return models.BaseQuery{
+		AwsRegion: r.URL.Query().Get("region"),
+		NextToken: r.URL.Query().Get("nextToken"),
+	}
+}
+
+func (s *Server) handleResourceModels(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	resp, err := api.ListAssetModels(r.Context(), sw, models.ListAssetModelsQuery{BaseQuery: resourceBaseQuery(r)})
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	page := resourcePage[assetModelResource]{
+		Items:     make([]assetModelResource, 0, len(resp.AssetModelSummaries)),
+		NextToken: util.Dereference(resp.NextToken),
+	}
+	for _, m := range resp.AssetModelSummaries {
+		model := assetModelResource{
+			Id:          util.Dereference(m.Id),
+			Name:        util.Dereference(m.Name),
+			Description: util.Dereference(m.Description),
+		}
+		if m.Status != nil {
+			model.Status = string(m.Status.State)
+		}
+		page.Items = append(page.Items, model)
+	}
+
+	writeResourceJSON(w, page)
+}
+
+func (s *Server) handleResourceModel(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), sitewise.GetCache())
+	m, err := cp.AssetModel(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	model := assetModelResource{
+		Id:          util.Dereference(m.AssetModelId),
+		Name:        util.Dereference(m.AssetModelName),
+		Description: util.Dereference(m.AssetModelDescription),
+		Properties:  make([]propertyResource, 0, len(m.AssetModelProperties)),
+		Hierarchies: make([]hierarchyResource, 0, len(m.AssetModelHierarchies)),
+	}
+	if m.AssetModelStatus != nil {
+		model.Status = string(m.AssetModelStatus.State)
+	}
+	for _, p := range m.AssetModelProperties {
+		model.Properties = append(model.Properties, propertyResource{
+			Id:       util.Dereference(p.Id),
+			Name:     util.Dereference(p.Name),
+			DataType: string(p.DataType),
+			Unit:     util.Dereference(p.Unit),
+		})
+	}
+	for _, h := range m.AssetModelHierarchies {
+		model.Hierarchies = append(model.Hierarchies, hierarchyResource{
+			Id:           util.Dereference(h.Id),
+			Name:         util.Dereference(h.Name),
+			ChildModelId: util.Dereference(h.ChildAssetModelId),
+		})
+	}
+
+	writeResourceJSON(w, model)
+}
+
+func (s *Server) handleResourceAssets(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	resp, err := api.ListAssets(r.Context(), sw, models.ListAssetsQuery{
+		BaseQuery: resourceBaseQuery(r),
+		ModelId:   r.URL.Query().Get("modelId"),
+		Filter:    iotsitewisetypes.ListAssetsFilter(r.URL.Query().Get("filter")),
+	})
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	page := resourcePage[assetResource]{
+		Items:     make([]assetResource, 0, len(resp.AssetSummaries)),
+		NextToken: util.Dereference(resp.NextToken),
+	}
+	for _, a := range resp.AssetSummaries {
+		page.Items = append(page.Items, assetSummaryResource(a.Id, a.Name, a.AssetModelId, a.Status, a.Hierarchies))
+	}
+
+	writeResourceJSON(w, page)
+}
+
+func (s *Server) handleResourceAsset(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), sitewise.GetCache())
+	a, err := cp.Asset(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	asset := assetSummaryResource(a.AssetId, a.AssetName, a.AssetModelId, a.AssetStatus, a.AssetHierarchies)
+	asset.Properties = make([]propertyResource, 0, len(a.AssetProperties))
+	for _, p := range a.AssetProperties {
+		asset.Properties = append(asset.Properties, propertyResource{
+			Id:       util.Dereference(p.Id),
+			Name:     util.Dereference(p.Name),
+			Alias:    util.Dereference(p.Alias),
+			DataType: string(p.DataType),
+			Unit:     util.Dereference(p.Unit),
+		})
+	}
+
+	writeResourceJSON(w, asset)
+}
+
+func (s *Server) handleResourceAssetChildren(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	// the children are listed one hierarchy at a time, the hierarchies are listed by the asset route
+	hierarchyId := r.URL.Query().Get("hierarchyId")
+	if hierarchyId == "" {
+		writeResourceError(w, fmt.Errorf("%w: hierarchyId is required", errInvalidResourceRequest))
+		return
+	}
+	maxResults, err := resourceMaxResults(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	query := models.ListAssociatedAssetsQuery{
+		BaseQuery:   resourceBaseQuery(r),
+		HierarchyId: hierarchyId,
+	}
+	query.AssetIds = []string{r.PathValue("id")}
+
+	resp, err := api.ListChildAssets(r.Context(), sw, query, maxResults)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	page := resourcePage[assetResource]{
+		Items:     make([]assetResource, 0, len(resp.AssetSummaries)),
+		NextToken: util.Dereference(resp.NextToken),
+	}
+	for _, a := range resp.AssetSummaries {
+		page.Items = append(page.Items, assetSummaryResource(a.Id, a.Name, a.AssetModelId, a.Status, a.Hierarchies))
+	}
+
+	writeResourceJSON(w, page)
+}
+
+func (s *Server) handleResourceAssetProperties(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	query := models.ListAssetPropertiesQuery{BaseQuery: resourceBaseQuery(r)}
+	query.AssetIds = []string{r.PathValue("id")}
+
+	resp, err := api.ListAssetProperties(r.Context(), sw, query)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	page := resourcePage[propertyResource]{
+		Items:     make([]propertyResource, 0, len(resp.AssetPropertySummaries)),
+		NextToken: util.Dereference(resp.NextToken),
+	}
+	for _, p := range resp.AssetPropertySummaries {
+		property := propertyResource{
+			Id:    util.Dereference(p.Id),
+			Alias: util.Dereference(p.Alias),
+			Unit:  util.Dereference(p.Unit),
+		}
+		if len(p.Path) > 0 {
+			property.Name = util.Dereference(p.Path[len(p.Path)-1].Name)
+		}
+		page.Items = append(page.Items, property)
+	}
+
+	writeResourceJSON(w, page)
+}
+
+func (s *Server) handleResourceTimeSeries(w http.ResponseWriter, r *http.Request) {
+	sw, err := s.resourceClient(r)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	query := models.ListTimeSeriesQuery{
+		BaseQuery:      resourceBaseQuery(r),
+		AliasPrefix:    r.URL.Query().Get("aliasPrefix"),
+		TimeSeriesType: iotsitewisetypes.ListTimeSeriesType(r.URL.Query().Get("timeSeriesType")),
+	}
+	if assetId := r.URL.Query().Get("assetId"); assetId != "" {
+		query.AssetIds = []string{assetId}
+	}
+
+	resp, err := api.ListTimeSeries(r.Context(), sw, query)
+	if err != nil {
+		writeResourceError(w, err)
+		return
+	}
+
+	page := resourcePage[timeSeriesResource]{
+		Items:     make([]timeSeriesResource, 0, len(resp.TimeSeriesSummaries)),
+		NextToken: util.Dereference(resp.NextToken),
+	}
+	for _, ts := range resp.TimeSeriesSummaries {
+		page.Items = append(page.Items, timeSeriesResource{
+			Id:         util.Dereference(ts.TimeSeriesId),
+			Alias:      util.Dereference(ts.Alias),
+			AssetId:    util.Dereference(ts.AssetId),
+			PropertyId: util.Dereference(ts.PropertyId),
+			DataType:   string(ts.DataType),
+		})
+	}
+
+	writeResourceJSON(w, page)
+}
+
+func assetSummaryResource(id, name, modelId *string, status *iotsitewisetypes.AssetStatus, hierarchies []iotsitewisetypes.AssetHierarchy) assetResource {
+	asset := assetResource{
+		Id:          util.Dereference(id),
+		Name:        util.Dereference(name),
+		ModelId:     util.Dereference(modelId),
+		Hierarchies: make([]hierarchyResource, 0, len(hierarchies)),
+	}
+	if status != nil {
+		asset.Status = string(status.State)
+	}
+	for _, h := range hierarchies {
+		asset.Hierarchies = append(asset.Hierarchies, hierarchyResource{
+			Id:   util.Dereference(h.Id),
+			Name: util.Dereference(h.Name),
+		})
+	}
+	return asset
+}
+
+func writeResourceJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.DefaultLogger.Error("failed to write resource response", "error", err)
+	}
+}
+
+func writeResourceError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	var notFound *iotsitewisetypes.ResourceNotFoundException
+	var invalidRequest *iotsitewisetypes.InvalidRequestException
+	switch {
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+	case errors.As(err, &invalidRequest), errors.Is(err, errInvalidResourceRequest):
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resourceError{Error: err.Error()}); err != nil {
+		log.DefaultLogger.Error("failed to write resource error", "error", err)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func callResource(t *testing.T, s *Server, path string, query string) (int, []byte) {
+	t.Helper()
+
+	var resp *backend.CallResourceResponse
+	err := s.CallResource(context.Background(), &backend.CallResourceRequest{
+		Method: http.MethodGet,
+		Path:   path,
+		URL:    path + "?" + query,
+	}, backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+		resp = r
+		return nil
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	return resp.Status, resp.Body
+}
+
+func TestCallResource(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("ListAssetModels", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetModelsOutput{
+		AssetModelSummaries: []iotsitewisetypes.AssetModelSummary{{
+			Id:     aws.String("model-1"),
+			Name:   aws.String("Turbine"),
+			Status: &iotsitewisetypes.AssetModelStatus{State: iotsitewisetypes.AssetModelStateActive},
+		}},
+		NextToken: aws.String("models-next"),
+	}, nil)
+	mockSw.On("DescribeAssetModel", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetModelOutput{
+		AssetModelId:   aws.String("model-1"),
+		AssetModelName: aws.String("Turbine"),
+		AssetModelProperties: []iotsitewisetypes.AssetModelProperty{{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Wind Speed"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Unit:     aws.String("m/s"),
+		}},
+	}, nil)
+	mockSw.On("ListAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssetsInput) bool {
+		return *input.AssetModelId == "model-1" && *input.NextToken == "assets-page-2"
+	})).Return(&iotsitewise.ListAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssetSummary{{
+			Id:           aws.String("asset-1"),
+			Name:         aws.String("Turbine 1"),
+			AssetModelId: aws.String("model-1"),
+			Hierarchies:  []iotsitewisetypes.AssetHierarchy{{Id: aws.String("h-1"), Name: aws.String("Blades")}},
+		}},
+	}, nil)
+	mockSw.On("ListAssetProperties", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetPropertiesOutput{
+		AssetPropertySummaries: []iotsitewisetypes.AssetPropertySummary{{
+			Id:    aws.String("prop-1"),
+			Alias: aws.String("/turbine/1/wind"),
+			Path:  []iotsitewisetypes.AssetPropertyPathSegment{{Name: aws.String("Turbine 1")}, {Name: aws.String("Wind Speed")}},
+		}},
+	}, nil)
+	mockSw.On("ListTimeSeries", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListTimeSeriesInput) bool {
+		return *input.AliasPrefix == "/turbine"
+	})).Return(&iotsitewise.ListTimeSeriesOutput{
+		TimeSeriesSummaries: []iotsitewisetypes.TimeSeriesSummary{{
+			TimeSeriesId: aws.String("ts-1"),
+			Alias:        aws.String("/turbine/1/wind"),
+			DataType:     iotsitewisetypes.PropertyDataTypeDouble,
+		}},
+	}, nil)
+	mockSw.On("ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+		return *input.AssetId == "asset-1" && *input.HierarchyId == "h-1" && *input.MaxResults == 10 &&
+			*input.NextToken == "children-page-2" && input.TraversalDirection == iotsitewisetypes.TraversalDirectionChild
+	})).Return(&iotsitewise.ListAssociatedAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssociatedAssetsSummary{{
+			Id:           aws.String("blade-1"),
+			Name:         aws.String("Blade 1"),
+			AssetModelId: aws.String("model-2"),
+		}},
+		NextToken: aws.String("children-page-3"),
+	}, nil)
+	mockSw.On("DescribeAsset", mock.Anything, mock.Anything).Return(nil, &iotsitewisetypes.ResourceNotFoundException{Message: aws.String("missing")})
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+	s := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+	}
+	s.resourceHandler = getResourceHandler(s)
+
+	t.Run("models", func(t *testing.T) {
+		status, body := callResource(t, s, "models", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"items":[{"id":"model-1","name":"Turbine","status":"ACTIVE"}],"nextToken":"models-next"}`, string(body))
+	})
+
+	t.Run("model", func(t *testing.T) {
+		status, body := callResource(t, s, "models/model-1", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"id":"model-1","name":"Turbine","properties":[{"id":"prop-1","name":"Wind Speed","dataType":"DOUBLE","unit":"m/s"}]}`, string(body))
+	})
+
+	t.Run("assets by model", func(t *testing.T) {
+		status, body := callResource(t, s, "assets", "modelId=model-1&nextToken=assets-page-2")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"items":[{"id":"asset-1","name":"Turbine 1","modelId":"model-1","hierarchies":[{"id":"h-1","name":"Blades"}]}]}`, string(body))
+	})
+
+	t.Run("asset properties", func(t *testing.T) {
+		status, body := callResource(t, s, "assets/asset-1/properties", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"items":[{"id":"prop-1","name":"Wind Speed","alias":"/turbine/1/wind"}]}`, string(body))
+	})
+
+	t.Run("asset children", func(t *testing.T) {
+		status, body := callResource(t, s, "assets/asset-1/children", "hierarchyId=h-1&maxResults=10&nextToken=children-page-2")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"items":[{"id":"blade-1","name":"Blade 1","modelId":"model-2"}],"nextToken":"children-page-3"}`, string(body))
+	})
+
+	t.Run("asset children without a hierarchy", func(t *testing.T) {
+		status, body := callResource(t, s, "assets/asset-1/children", "")
+		require.Equal(t, http.StatusBadRequest, status)
+		assert.JSONEq(t, `{"error":"invalid resource request: hierarchyId is required"}`, string(body))
+	})
+
+	t.Run("asset children with an invalid max results", func(t *testing.T) {
+		status, _ := callResource(t, s, "assets/asset-1/children", "hierarchyId=h-1&maxResults=all")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("time series", func(t *testing.T) {
+		status, body := callResource(t, s, "timeseries", "aliasPrefix=/turbine")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"items":[{"id":"ts-1","alias":"/turbine/1/wind","dataType":"DOUBLE"}]}`, string(body))
+	})
+
+	t.Run("missing asset", func(t *testing.T) {
+		status, body := callResource(t, s, "assets/unknown", "")
+		require.Equal(t, http.StatusNotFound, status)
+		var resErr resourceError
+		require.NoError(t, json.Unmarshal(body, &resErr))
+		assert.Contains(t, resErr.Error, "missing")
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		status, _ := callResource(t, s, "unknown", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
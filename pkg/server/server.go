@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
 
@@ -16,10 +19,17 @@ import (
 )
 
 type Server struct {
-	Datasource    *sitewise.Datasource
-	channelPrefix string
-	closeCh       chan struct{}
-	queryMux      *datasource.QueryTypeMux
+	Datasource      *sitewise.Datasource
+	channelPrefix   string
+	closeCh         chan struct{}
+	queryMux        *datasource.QueryTypeMux
+	resourceHandler backend.CallResourceHandler
+
+	queryPoolOnce sync.Once
+	querySlots    chan struct{}
+
+	streamsMu sync.Mutex
+	streams   map[string]*propertyValueStream
 }
 
 // Make sure SampleDatasource implements required interfaces.
@@ -28,6 +38,8 @@ type Server struct {
 var (
 	_ backend.QueryDataHandler      = (*Server)(nil)
 	_ backend.CheckHealthHandler    = (*Server)(nil)
+	_ backend.StreamHandler         = (*Server)(nil)
+	_ backend.CallResourceHandler   = (*Server)(nil)
 	_ instancemgmt.InstanceDisposer = (*Server)(nil)
 )
 
@@ -55,10 +67,10 @@ type handler func(ctx context.Context, req *backend.QueryDataRequest) (*backend.
 func getQueryHandlers(s *Server) *datasource.QueryTypeMux {
 	mux := datasource.NewQueryTypeMux()
 
-	mux.HandleFunc(models.QueryTypePropertyValueHistory, s.lastObservation(s.HandlePropertyValueHistory))
-	mux.HandleFunc(models.QueryTypePropertyAggregate, s.lastObservation(s.HandlePropertyAggregate))
-	mux.HandleFunc(models.QueryTypePropertyInterpolated, s.lastObservation(s.HandleInterpolatedPropertyValue))
-	mux.HandleFunc(models.QueryTypePropertyValue, s.HandlePropertyValue)
+	mux.HandleFunc(models.QueryTypePropertyValueHistory, s.multiRegion(s.lastObservation(s.HandlePropertyValueHistory)))
+	mux.HandleFunc(models.QueryTypePropertyAggregate, s.multiRegion(s.lastObservation(s.HandlePropertyAggregate)))
+	mux.HandleFunc(models.QueryTypePropertyInterpolated, s.multiRegion(s.lastObservation(s.HandleInterpolatedPropertyValue)))
+	mux.HandleFunc(models.QueryTypePropertyValue, s.multiRegion(s.HandlePropertyValue))
 	mux.HandleFunc(models.QueryTypeListAssetModels, s.HandleListAssetModels)
 	mux.HandleFunc(models.QueryTypeListAssociatedAssets, s.HandleListAssociatedAssets)
 	mux.HandleFunc(models.QueryTypeListAssets, s.HandleListAssets)
@@ -66,6 +78,11 @@ func getQueryHandlers(s *Server) *datasource.QueryTypeMux {
 	mux.HandleFunc(models.QueryTypeListAssetProperties, s.HandleListAssetProperties)
 	mux.HandleFunc(models.QueryTypeListTimeSeries, s.HandleListTimeSeries)
 	mux.HandleFunc(models.QueryTypeExecuteQuery, s.HandleExecuteQuery)
+	mux.HandleFunc(models.QueryTypePropertyAnnotations, s.HandlePropertyAnnotations)
+	mux.HandleFunc(models.QueryTypeListAlarms, s.HandleListAlarms)
+	mux.HandleFunc(models.QueryTypeAlarmHistory, s.HandleAlarmHistory)
+	mux.HandleFunc(models.QueryTypeAssetHierarchy, s.HandleAssetHierarchy)
+	mux.HandleFunc(models.QueryTypeDescribeAssetProperty, s.HandleDescribeAssetProperty)
 
 	return mux
 }
@@ -81,6 +98,7 @@ func NewServerInstance(ctx context.Context, settings backend.DataSourceInstanceS
 		closeCh:       make(chan struct{}),
 	}
 	srvr.queryMux = getQueryHandlers(srvr) // init once
+	srvr.resourceHandler = getResourceHandler(srvr)
 	return srvr, nil
 }
 
@@ -97,18 +115,65 @@ func (s *Server) QueryData(ctx context.Context, req *backend.QueryDataRequest) (
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
 func (s *Server) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
-	if err := s.Datasource.HealthCheck(ctx, req); err != nil {
+	report, err := s.Datasource.HealthCheck(ctx, req)
+	if err != nil {
 		return &backend.CheckHealthResult{
 			Status:  backend.HealthStatusError,
 			Message: err.Error(),
 		}, nil
 	}
-	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: backend.HealthStatusOk.String(),
-	}, nil
+
+	details, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	// the SDK has no degraded status, missing capabilities are reported as an error listing the APIs
+	switch report.Status {
+	case models.HealthReportOk:
+		return &backend.CheckHealthResult{
+			Status:      backend.HealthStatusOk,
+			Message:     backend.HealthStatusOk.String(),
+			JSONDetails: details,
+		}, nil
+	case models.HealthReportDegraded:
+		reasons := []string{}
+		if missing := report.MissingAccess(); len(missing) > 0 {
+			reasons = append(reasons, fmt.Sprintf("missing access to %s", strings.Join(missing, ", ")))
+		}
+		if failing := report.Failing(); len(failing) > 0 {
+			reasons = append(reasons, fmt.Sprintf("unable to call %s", strings.Join(failing, ", ")))
+		}
+		if unknown := report.Unknown(); len(unknown) > 0 {
+			reasons = append(reasons, fmt.Sprintf("unable to verify access to %s", strings.Join(unknown, ", ")))
+		}
+		if len(report.InvalidSettings) > 0 {
+			reasons = append(reasons, fmt.Sprintf("invalid %s", strings.Join(report.InvalidSettings, ", ")))
+		}
+		return &backend.CheckHealthResult{
+			Status:      backend.HealthStatusError,
+			Message:     fmt.Sprintf("Degraded: %s", strings.Join(reasons, "; ")),
+			JSONDetails: details,
+		}, nil
+	default:
+		// the message of the first probe that failed, the others may only have been throttled
+		message := fmt.Sprintf("unable to verify access to %s", strings.Join(report.Unknown(), ", "))
+		for _, probe := range report.APIs {
+			if probe.Status == models.HealthProbeFail || probe.Status == models.HealthProbeDenied {
+				message = probe.Message
+				break
+			}
+		}
+		return &backend.CheckHealthResult{
+			Status:      backend.HealthStatusError,
+			Message:     fmt.Sprintf("Unable to access SiteWise: %s", message),
+			JSONDetails: details,
+		}, nil
+	}
 }
 
+// Dispose stops every running stream before the instance is replaced or removed
 func (s *Server) Dispose() {
 	close(s.closeCh)
+	s.stopStreams()
 }
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// streamPathPropertyValue is the channel path prefix for latest property value streams.
+// The full channel is ds/<id>/property-value/<anything>, the entries are sent as subscription data.
+const streamPathPropertyValue = "property-value"
+
+// streamSubscriberBuffer is the number of pending updates kept for a slow subscriber
+const streamSubscriberBuffer = 16
+
+type streamFetchFunc func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
+
+// propertyValueStream polls BatchGetAssetPropertyValue for a set of entries and
+// fans the changed rows out to every subscriber of the same entries. A subscriber
+// that missed an update receives the latest value of every entry instead.
+type propertyValueStream struct {
+	key      string
+	query    models.AssetPropertyValueQuery
+	interval time.Duration
+	fetch    streamFetchFunc
+
+	mu          sync.Mutex
+	subscribers map[chan data.Frames]bool // set when the subscriber missed an update
+	latest      map[string]*data.Frame
+	order       []string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPropertyValueStream(key string, query *models.PropertyValueStreamQuery, fetch streamFetchFunc) *propertyValueStream {
+	return &propertyValueStream{
+		key:         key,
+		query:       query.AssetPropertyValueQuery,
+		interval:    query.PollInterval(),
+		fetch:       fetch,
+		subscribers: map[chan data.Frames]bool{},
+		latest:      map[string]*data.Frame{},
+		done:        make(chan struct{}),
+	}
+}
+
+// start polls with the context of the first subscriber, which carries the plugin context and the
+// Grafana config, and is only stopped by stop since the other subscribers outlive the first one
+func (st *propertyValueStream) start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	st.cancel = cancel
+	go st.run(ctx)
+}
+
+// stop cancels the poller and waits until every subscriber channel is closed
+func (st *propertyValueStream) stop() {
+	st.cancel()
+	<-st.done
+}
+
+func (st *propertyValueStream) run(ctx context.Context) {
+	defer close(st.done)
+	defer st.closeSubscribers()
+
+	ticker := time.NewTicker(st.interval)
+	defer ticker.Stop()
+
+	for {
+		st.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (st *propertyValueStream) poll(ctx context.Context) {
+	query := st.query
+	frames, err := st.fetch(ctx, &query)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.DefaultLogger.Warn("failed to poll property values for stream", "stream", st.key, "error", err)
+		}
+		return
+	}
+
+	if changed := st.update(frames); len(changed) > 0 {
+		st.broadcast(changed)
+	}
+}
+
+// update stores the latest frame of every entry and returns only the entries whose row changed
+func (st *propertyValueStream) update(frames data.Frames) data.Frames {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	changed := data.Frames{}
+	for _, frame := range frames {
+		if frame == nil || frame.Rows() == 0 {
+			continue
+		}
+
+		entryId := frame.Name
+		if frame.Meta != nil {
+			if meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta); ok && meta.EntryId != "" {
+				entryId = meta.EntryId
+			}
+		}
+
+		previous, ok := st.latest[entryId]
+		if !ok {
+			st.order = append(st.order, entryId)
+		} else if rowsEqual(previous, frame) {
+			continue
+		}
+
+		st.latest[entryId] = frame
+		changed = append(changed, frame)
+	}
+
+	return changed
+}
+
+// broadcast sends the changed frames to every subscriber, and the latest value of every entry
+// to the subscribers that missed an update, since they may lack the entries changed in between
+func (st *propertyValueStream) broadcast(frames data.Frames) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for ch, missed := range st.subscribers {
+		update := frames
+		if missed {
+			update = st.snapshot()
+		}
+		select {
+		case ch <- update:
+			st.subscribers[ch] = false
+		default:
+			st.subscribers[ch] = true
+			log.DefaultLogger.Warn("stream subscriber is too slow, dropping update", "stream", st.key)
+		}
+	}
+}
+
+// snapshot returns the latest value of every entry, the caller holds the lock
+func (st *propertyValueStream) snapshot() data.Frames {
+	snapshot := make(data.Frames, 0, len(st.order))
+	for _, entryId := range st.order {
+		snapshot = append(snapshot, st.latest[entryId])
+	}
+	return snapshot
+}
+
+// subscribe registers a new subscriber and primes it with the latest known value of every entry
+func (st *propertyValueStream) subscribe() chan data.Frames {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ch := make(chan data.Frames, streamSubscriberBuffer)
+	if len(st.order) > 0 {
+		ch <- st.snapshot()
+	}
+	st.subscribers[ch] = false
+
+	return ch
+}
+
+// unsubscribe removes a subscriber and returns the number of remaining subscribers
+func (st *propertyValueStream) unsubscribe(ch chan data.Frames) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.subscribers, ch)
+	return len(st.subscribers)
+}
+
+func (st *propertyValueStream) closeSubscribers() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for ch := range st.subscribers {
+		close(ch)
+		delete(st.subscribers, ch)
+	}
+}
+
+// subscribePropertyValueStream attaches to the poller shared by every subscriber of the same entries,
+// starting it with the context of the subscriber when needed. The returned function must be called
+// once the subscriber is done.
+func (s *Server) subscribePropertyValueStream(ctx context.Context, query *models.PropertyValueStreamQuery) (<-chan data.Frames, func()) {
+	key := propertyValueStreamKey(query)
+
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	if s.streams == nil {
+		s.streams = map[string]*propertyValueStream{}
+	}
+
+	stream, ok := s.streams[key]
+	if !ok {
+		stream = newPropertyValueStream(key, query, s.Datasource.HandleGetAssetPropertyValueQuery)
+		s.streams[key] = stream
+		stream.start(ctx)
+	}
+	ch := stream.subscribe()
+
+	return ch, func() {
+		s.streamsMu.Lock()
+		defer s.streamsMu.Unlock()
+
+		if stream.unsubscribe(ch) > 0 || s.streams[key] != stream {
+			return
+		}
+		delete(s.streams, key)
+		stream.stop()
+	}
+}
+
+func (s *Server) stopStreams() {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	for key, stream := range s.streams {
+		stream.stop()
+		delete(s.streams, key)
+	}
+}
+
+// SubscribeStream is called when a client wants to connect to a stream. The entries to
+// watch are passed as the subscription data and validated before the stream is allowed.
+func (s *Server) SubscribeStream(_ context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	if !strings.HasPrefix(req.Path, streamPathPropertyValue+"/") {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+
+	query, err := models.GetPropertyValueStreamQuery(req.Data)
+	if err != nil || !hasStreamEntries(query) {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// RunStream is called once per channel by Grafana and pushes the changed rows of the
+// shared poller to the channel until the last subscriber leaves or the server is disposed.
+func (s *Server) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	query, err := models.GetPropertyValueStreamQuery(req.Data)
+	if err != nil {
+		return err
+	}
+
+	channel := s.channelPrefix + req.Path
+	log.DefaultLogger.Debug("starting stream", "channel", channel, "interval", query.PollInterval())
+
+	frames, unsubscribe := s.subscribePropertyValueStream(ctx, query)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.DefaultLogger.Debug("stream closed by client", "channel", channel)
+			return nil
+		case <-s.closeCh:
+			return nil
+		case fs, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			for _, frame := range fs {
+				if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// PublishStream is called when a client sends a message to the stream.
+// Property value streams are read only.
+func (s *Server) PublishStream(_ context.Context, _ *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+func hasStreamEntries(query *models.PropertyValueStreamQuery) bool {
+	return len(query.PropertyAliases) > 0 || (len(query.AssetIds) > 0 && len(query.PropertyIds) > 0)
+}
+
+// propertyValueStreamKey identifies the poller shared by every subscriber of the same entries
+func propertyValueStreamKey(query *models.PropertyValueStreamQuery) string {
+	entries := []string{}
+	for _, assetId := range query.AssetIds {
+		for _, propertyId := range query.PropertyIds {
+			entries = append(entries, assetId+"/"+propertyId)
+		}
+	}
+	for _, alias := range query.PropertyAliases {
+		entries = append(entries, "alias:"+alias)
+	}
+	sort.Strings(entries)
+
+	flattenJson := ""
+	if query.FlattenJson {
+		flattenJson = fmt.Sprintf("%d:%s", query.JsonDiscoveryRowCount(), strings.Join(query.JsonPaths, ","))
+	}
+
+	qualities := string(query.Quality)
+	for _, quality := range query.Qualities {
+		qualities += "," + string(quality)
+	}
+
+	return util.EncodeEntryId(fmt.Sprintf("%s|%s|%t|%s|%s|%s|%s", query.AwsRegion, query.PollInterval(), query.FlattenL4e, flattenJson,
+		qualities, query.ResponseFormat, strings.Join(entries, ",")))
+}
+
+func rowsEqual(a, b *data.Frame) bool {
+	if a.Rows() != b.Rows() || len(a.Fields) != len(b.Fields) {
+		return false
+	}
+
+	for i := range a.Fields {
+		if a.Fields[i].Name != b.Fields[i].Name {
+			return false
+		}
+		for row := 0; row < a.Rows(); row++ {
+			av, bv := a.Fields[i].At(row), b.Fields[i].At(row)
+			if at, ok := av.(time.Time); ok {
+				if bt, ok := bv.(time.Time); !ok || !at.Equal(bt) {
+					return false
+				}
+				continue
+			}
+			if !reflect.DeepEqual(av, bv) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePacketSender struct {
+	packets chan *backend.StreamPacket
+}
+
+func (f *fakePacketSender) Send(p *backend.StreamPacket) error {
+	f.packets <- p
+	return nil
+}
+
+func valueFrame(entryId string, ts int64, value float64) *data.Frame {
+	return data.NewFrame("asset",
+		data.NewField("time", nil, []time.Time{time.Unix(ts, 0)}),
+		data.NewField("value", nil, []float64{value}),
+	).SetMeta(&data.FrameMeta{Custom: models.SitewiseCustomMeta{EntryId: entryId}})
+}
+
+func TestSubscribeStream(t *testing.T) {
+	s := &Server{}
+
+	tests := []struct {
+		name   string
+		path   string
+		data   string
+		status backend.SubscribeStreamStatus
+	}{
+		{"asset and property ids", "property-value/A", `{"assetIds":["a1"],"propertyIds":["p1"]}`, backend.SubscribeStreamStatusOK},
+		{"property aliases", "property-value/A", `{"propertyAliases":["/plant/temp"]}`, backend.SubscribeStreamStatusOK},
+		{"unknown path", "other/A", `{"propertyAliases":["/plant/temp"]}`, backend.SubscribeStreamStatusNotFound},
+		{"missing entries", "property-value/A", `{"assetIds":["a1"]}`, backend.SubscribeStreamStatusNotFound},
+		{"invalid data", "property-value/A", `not json`, backend.SubscribeStreamStatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: tt.path, Data: []byte(tt.data)})
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.Status)
+		})
+	}
+}
+
+func TestPropertyValueStreamKey(t *testing.T) {
+	a, err := models.GetPropertyValueStreamQuery([]byte(`{"assetIds":["a1","a2"],"propertyIds":["p1"]}`))
+	require.NoError(t, err)
+	b, err := models.GetPropertyValueStreamQuery([]byte(`{"assetIds":["a2","a1"],"propertyIds":["p1"]}`))
+	require.NoError(t, err)
+	c, err := models.GetPropertyValueStreamQuery([]byte(`{"assetIds":["a1","a2"],"propertyIds":["p1"],"pollIntervalMs":10000}`))
+	require.NoError(t, err)
+
+	d, err := models.GetPropertyValueStreamQuery([]byte(`{"assetIds":["a1","a2"],"propertyIds":["p1"],"quality":"BAD"}`))
+	require.NoError(t, err)
+	e, err := models.GetPropertyValueStreamQuery([]byte(`{"assetIds":["a1","a2"],"propertyIds":["p1"],"responseFormat":"timeseries"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, propertyValueStreamKey(a), propertyValueStreamKey(b))
+	assert.NotEqual(t, propertyValueStreamKey(a), propertyValueStreamKey(c))
+	assert.NotEqual(t, propertyValueStreamKey(a), propertyValueStreamKey(d))
+	assert.NotEqual(t, propertyValueStreamKey(a), propertyValueStreamKey(e))
+}
+
+func TestPropertyValueStreamPushesChangedRows(t *testing.T) {
+	polls := []data.Frames{
+		{valueFrame("e1", 1, 1.0), valueFrame("e2", 1, 2.0)},
+		{valueFrame("e1", 1, 1.0), valueFrame("e2", 2, 2.5)},
+		{valueFrame("e1", 1, 1.0), valueFrame("e2", 2, 2.5)},
+	}
+	i := 0
+	query := &models.PropertyValueStreamQuery{}
+	stream := newPropertyValueStream("key", query, func(_ context.Context, _ *models.AssetPropertyValueQuery) (data.Frames, error) {
+		frames := polls[i]
+		i++
+		return frames, nil
+	})
+	ch := stream.subscribe()
+
+	stream.poll(context.Background())
+	require.Len(t, <-ch, 2)
+
+	stream.poll(context.Background())
+	changed := <-ch
+	require.Len(t, changed, 1)
+	assert.Equal(t, 2.5, changed[0].Fields[1].At(0))
+
+	stream.poll(context.Background())
+	assert.Len(t, ch, 0)
+
+	// late subscribers receive the latest value of every entry
+	late := stream.subscribe()
+	snapshot := <-late
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, 1.0, snapshot[0].Fields[1].At(0))
+	assert.Equal(t, 2.5, snapshot[1].Fields[1].At(0))
+}
+
+func TestPropertyValueStreamResendsLatestValuesAfterDrop(t *testing.T) {
+	// the first poll and the changes of e1 fill the buffer of the subscriber, the change of e2 is dropped
+	polls := []data.Frames{{valueFrame("e1", 1, 1.0), valueFrame("e2", 1, 2.0)}}
+	for i := 1; i < streamSubscriberBuffer; i++ {
+		polls = append(polls, data.Frames{valueFrame("e1", int64(i+1), 1.0), valueFrame("e2", 1, 2.0)})
+	}
+	polls = append(polls,
+		data.Frames{valueFrame("e1", 99, 1.0), valueFrame("e2", 99, 3.0)},
+		data.Frames{valueFrame("e1", 100, 1.0), valueFrame("e2", 99, 3.0)},
+	)
+	i := 0
+	stream := newPropertyValueStream("key", &models.PropertyValueStreamQuery{}, func(_ context.Context, _ *models.AssetPropertyValueQuery) (data.Frames, error) {
+		frames := polls[i]
+		i++
+		return frames, nil
+	})
+	ch := stream.subscribe()
+
+	for range polls[:len(polls)-1] {
+		stream.poll(context.Background())
+	}
+	require.Len(t, ch, streamSubscriberBuffer)
+	for len(ch) > 0 {
+		<-ch
+	}
+
+	// only e1 changed since, the subscriber receives the dropped change of e2 as well
+	stream.poll(context.Background())
+	update := <-ch
+	require.Len(t, update, 2)
+	assert.Equal(t, time.Unix(100, 0), update[0].Fields[0].At(0))
+	assert.Equal(t, 3.0, update[1].Fields[1].At(0))
+}
+
+func TestPropertyValueStreamKeepsTheContextOfTheFirstSubscriber(t *testing.T) {
+	type ctxKey struct{}
+	polled := make(chan context.Context, 1)
+	stream := newPropertyValueStream("key", &models.PropertyValueStreamQuery{}, func(ctx context.Context, _ *models.AssetPropertyValueQuery) (data.Frames, error) {
+		select {
+		case polled <- ctx:
+		default:
+		}
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "plugin context"))
+	cancel()
+	stream.start(ctx)
+	defer stream.stop()
+
+	select {
+	case ctx := <-polled:
+		assert.Equal(t, "plugin context", ctx.Value(ctxKey{}))
+		assert.NoError(t, ctx.Err())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stream to poll")
+	}
+}
+
+func TestRunStreamSharesPollerAndStopsOnDispose(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry{{
+			EntryId: util.GetEntryIdFromAssetProperty("a1", "p1"),
+			AssetPropertyValue: &iotsitewisetypes.AssetPropertyValue{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(1612207200)},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(23.8)},
+			},
+		}},
+	}, nil)
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetName: aws.String("Demo Turbine Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Name:     aws.String("Wind Speed"),
+		},
+	}, nil)
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	s := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+		channelPrefix: "ds/1/",
+		closeCh:       make(chan struct{}),
+	}
+
+	req := &backend.RunStreamRequest{
+		Path: "property-value/A",
+		Data: []byte(`{"assetIds":["a1"],"propertyIds":["p1"],"pollIntervalMs":60000}`),
+	}
+
+	var wg sync.WaitGroup
+	senders := []*fakePacketSender{
+		{packets: make(chan *backend.StreamPacket, 10)},
+		{packets: make(chan *backend.StreamPacket, 10)},
+	}
+	for _, sender := range senders {
+		wg.Add(1)
+		go func(sender *fakePacketSender) {
+			defer wg.Done()
+			err := s.RunStream(context.Background(), req, backend.NewStreamSender(sender))
+			assert.NoError(t, err)
+		}(sender)
+	}
+
+	for _, sender := range senders {
+		select {
+		case <-sender.packets:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for stream data")
+		}
+	}
+
+	s.streamsMu.Lock()
+	assert.Len(t, s.streams, 1)
+	s.streamsMu.Unlock()
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValue", 1)
+
+	s.Dispose()
+	wg.Wait()
+
+	s.streamsMu.Lock()
+	assert.Len(t, s.streams, 0)
+	s.streamsMu.Unlock()
+}
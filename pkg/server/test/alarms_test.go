@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/testdata"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var alarmsStart = time.Date(2021, 2, 1, 16, 0, 0, 0, time.UTC)
+
+func mockAlarmAsset(mockSw *mocks.SitewiseAPIClient, assetId string, assetName string, alarmName string, hierarchies []iotsitewisetypes.AssetHierarchy) {
+	mockSw.On("DescribeAsset", mock.Anything, &iotsitewise.DescribeAssetInput{AssetId: Pointer(assetId)}).Return(&iotsitewise.DescribeAssetOutput{
+		AssetId:          Pointer(assetId),
+		AssetName:        Pointer(assetName),
+		AssetHierarchies: hierarchies,
+		AssetCompositeModels: []iotsitewisetypes.AssetCompositeModel{
+			{
+				Name: Pointer("Anomaly"),
+				Type: Pointer("AWS/L4E_ANOMALY"),
+				Properties: []iotsitewisetypes.AssetProperty{
+					{Id: Pointer(assetId + "-anomaly"), Name: Pointer("AWS/L4E_ANOMALY_RESULT")},
+				},
+			},
+			{
+				Name: Pointer(alarmName),
+				Type: Pointer("AWS/ALARM"),
+				Properties: []iotsitewisetypes.AssetProperty{
+					{Id: Pointer(assetId + "-type"), Name: Pointer("AWS/ALARM_TYPE")},
+					{Id: Pointer(assetId + "-state"), Name: Pointer("AWS/ALARM_STATE")},
+				},
+			},
+		},
+	}, nil)
+}
+
+func mockAlarmHierarchy(mockSw *mocks.SitewiseAPIClient) {
+	mockAlarmAsset(mockSw, "root", "Wind Farm", "Power Alarm", []iotsitewisetypes.AssetHierarchy{{Id: Pointer("turbines"), Name: Pointer("Turbines")}})
+	mockAlarmAsset(mockSw, "turbine", "Turbine 1", "Speed Alarm", nil)
+	mockSw.On("ListAssociatedAssets", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssociatedAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssociatedAssetsSummary{{Id: Pointer("turbine"), Name: Pointer("Turbine 1")}},
+	}, nil)
+}
+
+func mockAlarmStateValue(offset time.Duration, state string) iotsitewisetypes.AssetPropertyValue {
+	return iotsitewisetypes.AssetPropertyValue{
+		Quality: iotsitewisetypes.QualityGood,
+		Timestamp: &iotsitewisetypes.TimeInNanos{
+			OffsetInNanos: Pointer(int32(0)),
+			TimeInSeconds: Pointer(alarmsStart.Add(offset).Unix()),
+		},
+		Value: &iotsitewisetypes.Variant{StringValue: Pointer(state)},
+	}
+}
+
+func queryAlarms(t *testing.T, mockSw *mocks.SitewiseAPIClient, queryType string) backend.DataResponse {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	handle := srvr.HandleListAlarms
+	if queryType == models.QueryTypeAlarmHistory {
+		handle = srvr.HandleAlarmHistory
+	}
+	qdr, err := handle(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType:     queryType,
+				RefID:         "A",
+				MaxDataPoints: 100,
+				TimeRange:     backend.TimeRange{From: alarmsStart, To: alarmsStart.Add(time.Hour)},
+				JSON: []byte(`{
+					"region":"us-west-2",
+					"assetIds":["root"],
+					"loadAllChildren":true
+				}`),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	return res
+}
+
+func Test_list_alarms_of_asset_hierarchy(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAlarmHierarchy(mockSw)
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry{
+			{
+				EntryId:            util.GetEntryIdFromAssetProperty("turbine", "turbine-state"),
+				AssetPropertyValue: Pointer(mockAlarmStateValue(5*time.Minute, `{"stateName":"Active","severity":3}`)),
+			},
+			{
+				EntryId:            util.GetEntryIdFromAssetProperty("root", "root-state"),
+				AssetPropertyValue: Pointer(mockAlarmStateValue(time.Minute, `{"stateName":"Acknowledged","customerAction":{"actionType":"ACKNOWLEDGE","acknowledge":{"note":"Checking the inverter"}}}`)),
+			},
+		},
+	}, nil)
+	// the state history of the triggered alarms, newest first, back to their activation
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+		{
+			EntryId: util.GetEntryIdFromAssetProperty("root", "root-state"),
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+				mockAlarmStateValue(time.Minute, `{"stateName":"Acknowledged","customerAction":{"actionType":"ACKNOWLEDGE","acknowledge":{"note":"Checking the inverter"}}}`),
+				mockAlarmStateValue(-2*time.Minute, `{"stateName":"Active"}`),
+				mockAlarmStateValue(-3*time.Minute, `{"stateName":"Normal"}`),
+				mockAlarmStateValue(-4*time.Minute, `{"stateName":"Active"}`),
+			},
+		},
+		{
+			EntryId: util.GetEntryIdFromAssetProperty("turbine", "turbine-state"),
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+				mockAlarmStateValue(5*time.Minute, `{"stateName":"Active","severity":3}`),
+				mockAlarmStateValue(-time.Minute, `{"stateName":"Normal"}`),
+			},
+		},
+	}, nil)
+
+	res := queryAlarms(t, mockSw, models.QueryTypeListAlarms)
+
+	expectedFrame := data.NewFrame("alarms",
+		data.NewField("time", nil, []time.Time{alarmsStart.Add(time.Minute), alarmsStart.Add(5 * time.Minute)}),
+		data.NewField("assetName", nil, []string{"Wind Farm", "Turbine 1"}),
+		data.NewField("name", nil, []string{"Power Alarm", "Speed Alarm"}),
+		data.NewField("state", nil, []string{"Acknowledged", "Active"}),
+		data.NewField("severity", nil, []*int64{nil, Pointer(int64(3))}),
+		data.NewField("triggeredTime", nil, []*time.Time{Pointer(alarmsStart.Add(-2 * time.Minute)), Pointer(alarmsStart.Add(5 * time.Minute))}),
+		data.NewField("acknowledgedTime", nil, []*time.Time{Pointer(alarmsStart.Add(time.Minute)), nil}),
+		data.NewField("asset_id", nil, []string{"root", "turbine"}),
+	)
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValue", 1)
+	// the history is only read in the time range of the query
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return len(input.Entries) == 2 && input.Entries[0].TimeOrdering == iotsitewisetypes.TimeOrderingDescending &&
+			input.Entries[0].StartDate.Equal(alarmsStart) && input.Entries[0].EndDate.Equal(alarmsStart.Add(time.Hour))
+	}), 1, mock.Anything)
+}
+
+func Test_list_alarms_of_alarm_state_payloads(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAlarmHierarchy(mockSw)
+	// AWS/ALARM_STATE values as written by AWS IoT Events, without a severity
+	states := testdata.GetIoTSitewisePropVal(t, testDataRelativePath("list-alarms-state.json"))
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&states, nil)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, nil, nil)
+
+	res := queryAlarms(t, mockSw, models.QueryTypeListAlarms)
+
+	expectedFrame := data.NewFrame("alarms",
+		data.NewField("time", nil, []time.Time{alarmsStart.Add(time.Minute), alarmsStart.Add(5 * time.Minute)}),
+		data.NewField("assetName", nil, []string{"Wind Farm", "Turbine 1"}),
+		data.NewField("name", nil, []string{"Power Alarm", "Speed Alarm"}),
+		data.NewField("state", nil, []string{"Acknowledged", "Active"}),
+		data.NewField("severity", nil, []*int64{nil, nil}),
+		data.NewField("triggeredTime", nil, []*time.Time{nil, Pointer(alarmsStart.Add(5 * time.Minute))}),
+		data.NewField("acknowledgedTime", nil, []*time.Time{Pointer(alarmsStart.Add(time.Minute)), nil}),
+		data.NewField("asset_id", nil, []string{"root", "turbine"}),
+	)
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+}
+
+func Test_alarm_history_tracks_activations(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAlarmHierarchy(mockSw)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+		{
+			EntryId: util.GetEntryIdFromAssetProperty("root", "root-state"),
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+				mockAlarmStateValue(0, `{"stateName":"Normal"}`),
+				mockAlarmStateValue(time.Minute, `{"stateName":"Active","severity":1}`),
+				mockAlarmStateValue(2*time.Minute, `{"stateName":"Acknowledged","severity":1}`),
+				mockAlarmStateValue(3*time.Minute, `{"stateName":"Normal","severity":1}`),
+			},
+		},
+		{
+			EntryId: util.GetEntryIdFromAssetProperty("turbine", "turbine-state"),
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+				mockAlarmStateValue(4*time.Minute, `not json`),
+			},
+		},
+	}, nil)
+
+	res := queryAlarms(t, mockSw, models.QueryTypeAlarmHistory)
+
+	triggered := Pointer(alarmsStart.Add(time.Minute))
+	expectedFrame := data.NewFrame("alarms",
+		data.NewField("time", nil, []time.Time{alarmsStart, alarmsStart.Add(time.Minute), alarmsStart.Add(2 * time.Minute), alarmsStart.Add(3 * time.Minute)}),
+		data.NewField("assetName", nil, []string{"Wind Farm", "Wind Farm", "Wind Farm", "Wind Farm"}),
+		data.NewField("name", nil, []string{"Power Alarm", "Power Alarm", "Power Alarm", "Power Alarm"}),
+		data.NewField("state", nil, []string{"Normal", "Active", "Acknowledged", "Normal"}),
+		data.NewField("severity", nil, []*int64{nil, Pointer(int64(1)), Pointer(int64(1)), Pointer(int64(1))}),
+		data.NewField("triggeredTime", nil, []*time.Time{nil, triggered, triggered, nil}),
+		data.NewField("acknowledgedTime", nil, []*time.Time{nil, nil, Pointer(alarmsStart.Add(2 * time.Minute)), nil}),
+		data.NewField("asset_id", nil, []string{"root", "root", "root", "root"}),
+	).SetMeta(&data.FrameMeta{
+		Notices: []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "Turbine 1 Speed Alarm: invalid alarm state: invalid character 'o' in literal null (expecting 'u')",
+		}},
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW"},
+	})
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+}
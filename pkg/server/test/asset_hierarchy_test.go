@@ -0,0 +1,200 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockHierarchySummary(id string, name string, modelId string, hierarchies ...iotsitewisetypes.AssetHierarchy) iotsitewisetypes.AssociatedAssetsSummary {
+	return iotsitewisetypes.AssociatedAssetsSummary{
+		Id:           Pointer(id),
+		Name:         Pointer(name),
+		AssetModelId: Pointer(modelId),
+		Status:       &iotsitewisetypes.AssetStatus{State: iotsitewisetypes.AssetStateActive},
+		Hierarchies:  hierarchies,
+	}
+}
+
+func mockListAssociatedAssets(mockSw *mocks.SitewiseAPIClient, assetId string, hierarchyId *string, direction iotsitewisetypes.TraversalDirection, summaries ...iotsitewisetypes.AssociatedAssetsSummary) {
+	mockSw.On("ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+		return *input.AssetId == assetId && input.TraversalDirection == direction &&
+			(hierarchyId == nil) == (input.HierarchyId == nil) && (hierarchyId == nil || *hierarchyId == *input.HierarchyId)
+	})).Return(&iotsitewise.ListAssociatedAssetsOutput{AssetSummaries: summaries}, nil)
+}
+
+// farm -(Turbines)-> turbine-1, turbine-2 -(Sensors)-> sensor
+func mockAssetHierarchy(mockSw *mocks.SitewiseAPIClient) {
+	turbines := iotsitewisetypes.AssetHierarchy{Id: Pointer("turbines"), Name: Pointer("Turbines")}
+	substations := iotsitewisetypes.AssetHierarchy{Id: Pointer("substations"), Name: Pointer("Substations")}
+	sensors := iotsitewisetypes.AssetHierarchy{Id: Pointer("sensors"), Name: Pointer("Sensors")}
+
+	farm := mockHierarchySummary("farm", "Wind Farm", "farm-model", turbines, substations)
+	turbine1 := mockHierarchySummary("turbine-1", "Turbine 1", "turbine-model", sensors)
+	turbine2 := mockHierarchySummary("turbine-2", "Turbine 2", "turbine-model", sensors)
+	sensor := mockHierarchySummary("sensor", "Sensor", "sensor-model")
+
+	mockListAssociatedAssets(mockSw, "farm", Pointer("turbines"), iotsitewisetypes.TraversalDirectionChild, turbine1, turbine2)
+	mockListAssociatedAssets(mockSw, "farm", Pointer("substations"), iotsitewisetypes.TraversalDirectionChild)
+	mockListAssociatedAssets(mockSw, "turbine-1", Pointer("sensors"), iotsitewisetypes.TraversalDirectionChild, sensor)
+	mockListAssociatedAssets(mockSw, "turbine-2", Pointer("sensors"), iotsitewisetypes.TraversalDirectionChild)
+	mockListAssociatedAssets(mockSw, "sensor", nil, iotsitewisetypes.TraversalDirectionParent, turbine1)
+	mockListAssociatedAssets(mockSw, "turbine-1", nil, iotsitewisetypes.TraversalDirectionParent, farm)
+	mockListAssociatedAssets(mockSw, "farm", nil, iotsitewisetypes.TraversalDirectionParent)
+
+	for _, asset := range []iotsitewisetypes.AssociatedAssetsSummary{farm, turbine1, sensor} {
+		mockSw.On("DescribeAsset", mock.Anything, &iotsitewise.DescribeAssetInput{AssetId: asset.Id}).Return(&iotsitewise.DescribeAssetOutput{
+			AssetId:          asset.Id,
+			AssetName:        asset.Name,
+			AssetModelId:     asset.AssetModelId,
+			AssetStatus:      asset.Status,
+			AssetHierarchies: asset.Hierarchies,
+		}, nil)
+	}
+	for id, name := range map[string]string{"farm-model": "Farm", "turbine-model": "Turbine", "sensor-model": "Sensor"} {
+		mockSw.On("DescribeAssetModel", mock.Anything, &iotsitewise.DescribeAssetModelInput{AssetModelId: Pointer(id)}).Return(&iotsitewise.DescribeAssetModelOutput{
+			AssetModelName: Pointer(name),
+		}, nil)
+	}
+}
+
+func queryAssetHierarchy(t *testing.T, mockSw *mocks.SitewiseAPIClient, query string) backend.DataResponse {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandleAssetHierarchy(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypeAssetHierarchy,
+				RefID:     "A",
+				JSON:      []byte(query),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+	require.Len(t, res.Frames, 2)
+
+	return res
+}
+
+func nodeGraphFrames(nodes []string, titles []string, subtitles []string, edges []string, hierarchies []string) data.Frames {
+	statuses := make([]string, len(nodes))
+	for i := range statuses {
+		statuses[i] = "ACTIVE"
+	}
+	// the edges are source, hierarchy id and target triples
+	ids, sources, targets := []string{}, []string{}, []string{}
+	for i := 0; i < len(edges); i += 3 {
+		ids = append(ids, edges[i]+"-"+edges[i+1]+"-"+edges[i+2])
+		sources = append(sources, edges[i])
+		targets = append(targets, edges[i+2])
+	}
+
+	return data.Frames{
+		data.NewFrame("nodes",
+			data.NewField("id", nil, nodes),
+			data.NewField("title", nil, titles),
+			data.NewField("subtitle", nil, subtitles),
+			data.NewField("mainstat", nil, statuses),
+		).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}),
+		data.NewFrame("edges",
+			data.NewField("id", nil, ids),
+			data.NewField("source", nil, sources),
+			data.NewField("target", nil, targets),
+			data.NewField("mainstat", nil, hierarchies),
+		).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}),
+	}
+}
+
+func Test_asset_hierarchy_children(t *testing.T) {
+	tests := []struct {
+		name     string
+		depth    string
+		expected data.Frames
+	}{
+		{
+			name:  "depth 1",
+			depth: "1",
+			expected: nodeGraphFrames(
+				[]string{"farm", "turbine-1", "turbine-2"},
+				[]string{"Wind Farm", "Turbine 1", "Turbine 2"},
+				[]string{"Farm", "Turbine", "Turbine"},
+				[]string{"farm", "turbines", "turbine-1", "farm", "turbines", "turbine-2"},
+				[]string{"Turbines", "Turbines"},
+			),
+		},
+		{
+			name:  "default depth",
+			depth: "0",
+			expected: nodeGraphFrames(
+				[]string{"farm", "turbine-1", "turbine-2", "sensor"},
+				[]string{"Wind Farm", "Turbine 1", "Turbine 2", "Sensor"},
+				[]string{"Farm", "Turbine", "Turbine", "Sensor"},
+				[]string{"farm", "turbines", "turbine-1", "farm", "turbines", "turbine-2", "turbine-1", "sensors", "sensor"},
+				[]string{"Turbines", "Turbines", "Sensors"},
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockAssetHierarchy(mockSw)
+
+			res := queryAssetHierarchy(t, mockSw, `{"region":"us-west-2","assetIds":["farm"],"depth":`+tc.depth+`}`)
+
+			if diff := cmp.Diff(tc.expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+			mockSw.AssertNotCalled(t, "ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+				return input.TraversalDirection == iotsitewisetypes.TraversalDirectionParent
+			}))
+		})
+	}
+}
+
+func Test_asset_hierarchy_parents(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAssetHierarchy(mockSw)
+
+	res := queryAssetHierarchy(t, mockSw, `{"region":"us-west-2","assetIds":["sensor"],"traversalDirection":"PARENT"}`)
+
+	expected := nodeGraphFrames(
+		[]string{"sensor", "turbine-1", "farm"},
+		[]string{"Sensor", "Turbine 1", "Wind Farm"},
+		[]string{"Sensor", "Turbine", "Farm"},
+		[]string{"turbine-1", "sensors", "sensor", "farm", "turbines", "turbine-1"},
+		[]string{"Sensors", "Turbines"},
+	)
+	if diff := cmp.Diff(expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	// the farm has two hierarchies, the one of the turbine is found by listing their children
+	mockSw.AssertCalled(t, "ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+		return *input.AssetId == "farm" && input.HierarchyId != nil && *input.HierarchyId == "turbines"
+	}))
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// the turbine has a wind speed measurement and its transform to km/h
+func mockTurbineProperties(mockSw *mocks.SitewiseAPIClient) {
+	mockSw.On("DescribeAssetProperty", mock.Anything, &iotsitewise.DescribeAssetPropertyInput{
+		AssetId:    Pointer("turbine"),
+		PropertyId: Pointer("wind-speed"),
+	}).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   Pointer("turbine"),
+		AssetName: Pointer("Turbine 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       Pointer("wind-speed"),
+			Name:     Pointer("Wind Speed"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Unit:     Pointer("m/s"),
+			Alias:    Pointer("/farm/turbine/wind"),
+			Notification: &iotsitewisetypes.PropertyNotification{
+				State: iotsitewisetypes.PropertyNotificationStateEnabled,
+				Topic: Pointer("$aws/sitewise/asset-models/turbine/assets/turbine/properties/wind-speed"),
+			},
+			Type: &iotsitewisetypes.PropertyType{Measurement: &iotsitewisetypes.Measurement{}},
+		},
+	}, nil)
+	mockSw.On("DescribeAssetProperty", mock.Anything, &iotsitewise.DescribeAssetPropertyInput{
+		AssetId:    Pointer("turbine"),
+		PropertyId: Pointer("wind-speed-kmh"),
+	}).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   Pointer("turbine"),
+		AssetName: Pointer("Turbine 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       Pointer("wind-speed-kmh"),
+			Name:     Pointer("Wind Speed km/h"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Type: &iotsitewisetypes.PropertyType{Transform: &iotsitewisetypes.Transform{
+				Expression: Pointer("speed * 3.6"),
+				Variables: []iotsitewisetypes.ExpressionVariable{{
+					Name:  Pointer("speed"),
+					Value: &iotsitewisetypes.VariableValue{PropertyId: Pointer("wind-speed")},
+				}},
+			}},
+		},
+	}, nil)
+}
+
+func Test_describe_asset_properties(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "property ids",
+			query: `{"region":"us-west-2","assetIds":["turbine"],"propertyIds":["wind-speed","wind-speed-kmh"]}`,
+		},
+		{
+			name:  "every property of the asset",
+			query: `{"region":"us-west-2","assetIds":["turbine"]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockTurbineProperties(mockSw)
+			mockSw.On("DescribeAsset", mock.Anything, &iotsitewise.DescribeAssetInput{AssetId: Pointer("turbine")}).Return(&iotsitewise.DescribeAssetOutput{
+				AssetId: Pointer("turbine"),
+				AssetProperties: []iotsitewisetypes.AssetProperty{
+					{Id: Pointer("wind-speed"), Name: Pointer("Wind Speed")},
+					{Id: Pointer("wind-speed-kmh"), Name: Pointer("Wind Speed km/h")},
+				},
+			}, nil).Maybe()
+
+			describeAssetProperties(t, mockSw, tc.query)
+		})
+	}
+}
+
+func describeAssetProperties(t *testing.T, mockSw *mocks.SitewiseAPIClient, query string) {
+	t.Helper()
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandleDescribeAssetProperty(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypeDescribeAssetProperty,
+				RefID:     "A",
+				JSON:      []byte(query),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	expectedFrame := data.NewFrame("",
+		data.NewField("asset_id", nil, []string{"turbine", "turbine"}),
+		data.NewField("assetName", nil, []string{"Turbine 1", "Turbine 1"}),
+		data.NewField("propertyId", nil, []string{"wind-speed", "wind-speed-kmh"}),
+		data.NewField("name", nil, []string{"Wind Speed", "Wind Speed km/h"}),
+		data.NewField("dataType", nil, []string{"DOUBLE", "DOUBLE"}),
+		data.NewField("unit", nil, []*string{Pointer("m/s"), nil}),
+		data.NewField("alias", nil, []string{"/farm/turbine/wind", ""}),
+		data.NewField("notificationState", nil, []*string{Pointer("ENABLED"), nil}),
+		data.NewField("notificationTopic", nil, []*string{Pointer("$aws/sitewise/asset-models/turbine/assets/turbine/properties/wind-speed"), nil}),
+		data.NewField("propertyType", nil, []string{"MEASUREMENT", "TRANSFORM"}),
+		data.NewField("expression", nil, []*string{nil, Pointer("speed * 3.6")}),
+		data.NewField("variables", nil, []*string{nil, Pointer(`[{"Name":"speed","Value":{"HierarchyId":null,"PropertyId":"wind-speed","PropertyPath":null}}]`)}),
+	)
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// healthCheckAPIs are the APIs probed by the health check
+var healthCheckAPIs = []string{
+	"ListAssetModels", "ListAssets", "ListAssetProperties", "ListAssociatedAssets", "ListTimeSeries",
+	"DescribeAsset", "DescribeAssetModel", "DescribeAssetProperty", "DescribeTimeSeries",
+	"GetAssetPropertyValue", "GetAssetPropertyValueHistory", "GetAssetPropertyAggregates", "GetInterpolatedAssetPropertyValues",
+	"BatchGetAssetPropertyValue", "BatchGetAssetPropertyValueHistory", "BatchGetAssetPropertyAggregates", "ExecuteQuery",
+}
+
+func healthCheckMock(denied ...string) *mocks.SitewiseAPIClient {
+	errs := map[string]error{}
+	for _, api := range denied {
+		errs[api] = &iotsitewisetypes.AccessDeniedException{Message: aws.String("not authorized to perform " + api)}
+	}
+	return healthCheckMockWithErrors(errs)
+}
+
+// healthCheckMockWithErrors fails the calls of the APIs with their error, the first matching expectation is used
+func healthCheckMockWithErrors(errs map[string]error) *mocks.SitewiseAPIClient {
+	mockSw := &mocks.SitewiseAPIClient{}
+	for api, err := range errs {
+		mockSw.On(api, mock.Anything, mock.Anything).Return(nil, err)
+	}
+
+	notFound := &iotsitewisetypes.ResourceNotFoundException{Message: aws.String("not found")}
+	mockSw.On("ListAssetModels", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetModelsOutput{}, nil)
+	mockSw.On("ListAssets", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetsOutput{}, nil)
+	mockSw.On("ListAssetProperties", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("ListAssociatedAssets", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("ListTimeSeries", mock.Anything, mock.Anything).Return(&iotsitewise.ListTimeSeriesOutput{}, nil)
+	mockSw.On("DescribeAsset", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("DescribeAssetModel", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("DescribeTimeSeries", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("GetAssetPropertyValue", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("GetAssetPropertyValueHistory", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("GetAssetPropertyAggregates", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("GetInterpolatedAssetPropertyValues", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{}, nil)
+	mockSw.On("BatchGetAssetPropertyValueHistory", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}, nil)
+	mockSw.On("BatchGetAssetPropertyAggregates", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyAggregatesOutput{}, nil)
+	mockSw.On("ExecuteQuery", mock.Anything, mock.Anything).Return(&iotsitewise.ExecuteQueryOutput{}, nil)
+	return mockSw
+}
+
+func checkHealth(t *testing.T, mockSw *mocks.SitewiseAPIClient) (*backend.CheckHealthResult, models.HealthReport) {
+	t.Helper()
+	return checkDatasourceHealth(t, mockedDatasource(mockSw).(*sitewise.Datasource))
+}
+
+func checkDatasourceHealth(t *testing.T, ds *sitewise.Datasource) (*backend.CheckHealthResult, models.HealthReport) {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: ds}
+	result, err := srvr.CheckHealth(context.Background(), &backend.CheckHealthRequest{})
+	require.NoError(t, err)
+
+	report := models.HealthReport{}
+	require.NoError(t, json.Unmarshal(result.JSONDetails, &report))
+	return result, report
+}
+
+func TestCheckHealth(t *testing.T) {
+	t.Run("every API is allowed", func(t *testing.T) {
+		result, report := checkHealth(t, healthCheckMock())
+
+		assert.Equal(t, backend.HealthStatusOk, result.Status)
+		assert.Equal(t, models.HealthReportOk, report.Status)
+		assert.Len(t, report.APIs, 17)
+		for _, probe := range report.APIs {
+			assert.Equal(t, models.HealthProbePass, probe.Status, probe.API)
+		}
+	})
+
+	t.Run("missing permissions degrade the datasource", func(t *testing.T) {
+		result, report := checkHealth(t, healthCheckMock("BatchGetAssetPropertyAggregates", "ExecuteQuery"))
+
+		assert.Equal(t, backend.HealthStatusError, result.Status)
+		assert.Equal(t, "Degraded: missing access to BatchGetAssetPropertyAggregates, ExecuteQuery", result.Message)
+		assert.Equal(t, models.HealthReportDegraded, report.Status)
+		for _, probe := range report.APIs {
+			switch probe.API {
+			case "BatchGetAssetPropertyAggregates", "ExecuteQuery":
+				assert.Equal(t, models.HealthProbeDenied, probe.Status)
+				assert.Equal(t, "not authorized to perform "+probe.API, probe.Message)
+			default:
+				assert.Equal(t, models.HealthProbePass, probe.Status, probe.API)
+			}
+		}
+	})
+
+	t.Run("throttled and server side errors do not tell about access", func(t *testing.T) {
+		mockSw := healthCheckMockWithErrors(map[string]error{
+			"ListAssets":    &iotsitewisetypes.ThrottlingException{Message: aws.String("rate exceeded")},
+			"DescribeAsset": &iotsitewisetypes.InternalFailureException{Message: aws.String("internal failure")},
+			"ExecuteQuery":  &iotsitewisetypes.AccessDeniedException{Message: aws.String("not authorized to perform ExecuteQuery")},
+		})
+
+		result, report := checkHealth(t, mockSw)
+
+		assert.Equal(t, backend.HealthStatusError, result.Status)
+		assert.Equal(t, "Degraded: missing access to ExecuteQuery; unable to verify access to ListAssets, DescribeAsset", result.Message)
+		assert.Equal(t, models.HealthReportDegraded, report.Status)
+		for _, probe := range report.APIs {
+			switch probe.API {
+			case "ListAssets", "DescribeAsset":
+				assert.Equal(t, models.HealthProbeUnknown, probe.Status, probe.API)
+			case "ExecuteQuery":
+				assert.Equal(t, models.HealthProbeDenied, probe.Status)
+			default:
+				assert.Equal(t, models.HealthProbePass, probe.Status, probe.API)
+			}
+		}
+	})
+
+	t.Run("only unknown probes do not tell that the datasource works", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		for _, api := range healthCheckAPIs {
+			mockSw.On(api, mock.Anything, mock.Anything).Return(nil, &iotsitewisetypes.ThrottlingException{Message: aws.String("rate exceeded")})
+		}
+
+		result, report := checkHealth(t, mockSw)
+
+		assert.Equal(t, backend.HealthStatusError, result.Status)
+		assert.Equal(t, models.HealthReportError, report.Status)
+		assert.Contains(t, result.Message, "Unable to access SiteWise: unable to verify access to ListAssetModels, ListAssets, ")
+		assert.Len(t, report.Unknown(), len(healthCheckAPIs))
+	})
+
+	t.Run("an invalid shift calendar degrades the datasource", func(t *testing.T) {
+		ds := mockedDatasource(healthCheckMock()).(*sitewise.Datasource)
+		ds.Cfg.ShiftCalendar = &models.ShiftCalendar{
+			Timezone: "UTC",
+			Shifts: []models.Shift{
+				{Name: "Day", Start: "06:00", End: "18:00"},
+				{Name: "Maintenance", Days: []string{"SUN"}, Start: "12:00", End: "14:00"},
+			},
+		}
+
+		result, report := checkDatasourceHealth(t, ds)
+
+		assert.Equal(t, backend.HealthStatusError, result.Status)
+		assert.Equal(t, "Degraded: invalid shift calendar: shifts Day and Maintenance overlap", result.Message)
+		assert.Equal(t, models.HealthReportDegraded, report.Status)
+	})
+
+	t.Run("unexpected errors fail the probe", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		for _, api := range healthCheckAPIs {
+			mockSw.On(api, mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+		}
+
+		result, report := checkHealth(t, mockSw)
+
+		assert.Equal(t, backend.HealthStatusError, result.Status)
+		assert.Equal(t, "Unable to access SiteWise: connection refused", result.Message)
+		assert.Equal(t, models.HealthReportError, report.Status)
+		for _, probe := range report.APIs {
+			assert.Equal(t, models.HealthProbeFail, probe.Status, probe.API)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRawValues returns the values 1 to 5 in the minute of the aggregates, and a value of the next minute
+func mockRawValues() iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry {
+	minute := time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC).Unix()
+	history := []iotsitewisetypes.AssetPropertyValue{}
+	for i, v := range []float64{5, 1, 4, 2, 3} {
+		history = append(history, iotsitewisetypes.AssetPropertyValue{
+			Quality:   iotsitewisetypes.QualityGood,
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: Pointer(minute + int64(i*10)), OffsetInNanos: Pointer(int32(0))},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: Pointer(v)},
+		})
+	}
+	history = append(history, iotsitewisetypes.AssetPropertyValue{
+		Quality:   iotsitewisetypes.QualityGood,
+		Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: Pointer(minute + 60), OffsetInNanos: Pointer(int32(0))},
+		Value:     &iotsitewisetypes.Variant{IntegerValue: Pointer(int32(100))},
+	})
+	return iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+		EntryId:                   mockAssetPropertyEntryId,
+		AssetPropertyValueHistory: history,
+	}
+}
+
+// syntheticTimeRange holds the three minute buckets starting at the minute of mockRawValues
+var syntheticTimeRange = backend.TimeRange{
+	From: time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC),
+	To:   time.Date(2021, 2, 1, 16, 30, 0, 0, time.UTC),
+}
+
+func queryPropertyAggregate(t *testing.T, mockSw *mocks.SitewiseAPIClient, query string) backend.DataResponse {
+	t.Helper()
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyAggregate,
+				TimeRange: syntheticTimeRange,
+				JSON:      []byte(query),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	return res
+}
+
+func TestPropertyValueAggregate_percentiles_with_native_aggregates(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AggregatedValues: []iotsitewisetypes.AggregatedValue{{
+			Timestamp: Pointer(time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC)),
+			Value:     &iotsitewisetypes.Aggregates{Average: Pointer(3.0)},
+		}},
+	}}, nil)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{mockRawValues()}, nil)
+
+	res := queryPropertyAggregate(t, mockSw, fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetId":"%s",
+		"propertyId":"%s",
+		"aggregates":["AVERAGE", "P90", "MEDIAN"],
+		"resolution":"1m"
+	}`, mockAssetId, mockPropertyId))
+	require.Nil(t, res.Error)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC)}),
+		data.NewField("avg", nil, []float64{3}),
+		data.NewField("p90", nil, []*float64{Pointer(4.6)}),
+		data.NewField("median", nil, []*float64{Pointer(3.0)}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId:    *mockAssetPropertyEntryId,
+			Resolution: "1m",
+			Aggregates: []string{models.AggregateAvg, models.AggregateP90, models.AggregateMedian},
+		},
+	})
+	if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+		return cmp.Equal(input.Entries[0].AggregateTypes, []iotsitewisetypes.AggregateType{iotsitewisetypes.AggregateTypeAverage})
+	}), mock.Anything, mock.Anything)
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return input.Entries[0].TimeOrdering == iotsitewisetypes.TimeOrderingAscending
+	}), 1, models.DefaultMaxRawDataPoints)
+}
+
+func TestPropertyValueAggregate_percentiles_raw_value_cap(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, Pointer("next-token"), []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{mockRawValues()}, nil)
+
+	res := queryPropertyAggregate(t, mockSw, fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetId":"%s",
+		"propertyId":"%s",
+		"aggregates":["MEDIAN"],
+		"resolution":"1m",
+		"maxRawDataPoints":5
+	}`, mockAssetId, mockPropertyId))
+	require.Nil(t, res.Error)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+		data.NewField("time", nil, []time.Time{
+			time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC),
+			time.Date(2021, 2, 1, 16, 28, 0, 0, time.UTC),
+			time.Date(2021, 2, 1, 16, 29, 0, 0, time.UTC),
+		}),
+		data.NewField("median", nil, []*float64{Pointer(3.0), Pointer(100.0), nil}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId:    *mockAssetPropertyEntryId,
+			Resolution: "1m (derived from RAW)",
+			Aggregates: []string{models.AggregateMedian},
+			Truncated:  true,
+		},
+		Notices: []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "the synthetic aggregates are computed from the first 5 raw values, the last buckets are incomplete",
+		}},
+	})
+	if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 1)
+}
+
+func TestPropertyValueAggregate_percentiles_raw_value_cap_skips_later_batches(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, Pointer("next-token"), []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{mockRawValues()}, nil)
+
+	// the assets of a second history batch
+	assetIds := []string{mockAssetId}
+	for i := 1; i <= 16; i++ {
+		assetIds = append(assetIds, fmt.Sprintf("00000000-0000-0000-0000-%012d", i))
+	}
+	assetIdsJSON, err := json.Marshal(assetIds)
+	require.NoError(t, err)
+
+	res := queryPropertyAggregate(t, mockSw, fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetIds":%s,
+		"propertyId":"%s",
+		"aggregates":["MEDIAN"],
+		"resolution":"1m",
+		"maxRawDataPoints":5
+	}`, assetIdsJSON, mockPropertyId))
+	require.Nil(t, res.Error)
+
+	require.Len(t, res.Frames, 2)
+	skipped := res.Frames[1]
+	require.Len(t, skipped.Fields, 2)
+	assert.Equal(t, 3, skipped.Fields[1].Len())
+	for i := 0; i < skipped.Fields[1].Len(); i++ {
+		assert.Nil(t, skipped.Fields[1].At(i))
+	}
+	assert.True(t, skipped.Meta.Custom.(models.SitewiseCustomMeta).Truncated)
+	assert.Equal(t, []data.Notice{{
+		Severity: data.NoticeSeverityWarning,
+		Text:     "the synthetic aggregates are computed from the first 5 raw values, the last buckets are incomplete",
+	}}, skipped.Meta.Notices)
+
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 1)
+}
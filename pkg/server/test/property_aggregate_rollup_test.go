@@ -0,0 +1,206 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockNativeAggregate(minute int, count, average, minimum, maximum, stddev float64) iotsitewisetypes.AggregatedValue {
+	return iotsitewisetypes.AggregatedValue{
+		Timestamp: Pointer(time.Date(2021, 2, 1, 16, minute, 0, 0, time.UTC)),
+		Value: &iotsitewisetypes.Aggregates{
+			Average:           Pointer(average),
+			Count:             Pointer(count),
+			Maximum:           Pointer(maximum),
+			Minimum:           Pointer(minimum),
+			StandardDeviation: Pointer(stddev),
+			Sum:               Pointer(count * average),
+		},
+	}
+}
+
+func TestPropertyValueAggregate_derived_resolution(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AggregatedValues: []iotsitewisetypes.AggregatedValue{
+			// values 0, 2 and 2, 4 in the 16:00 bucket
+			mockNativeAggregate(0, 2, 1, 0, 2, math.Sqrt2),
+			mockNativeAggregate(1, 2, 3, 2, 4, math.Sqrt2),
+			// values 10, 10 in the 16:05 bucket
+			mockNativeAggregate(5, 2, 10, 10, 10, 0),
+		},
+	}}, nil)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyAggregate,
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetId":"%s",
+					"propertyId":"%s",
+					"aggregates":["AVERAGE", "MINIMUM", "MAXIMUM", "SUM", "STANDARD_DEVIATION"],
+					"resolution":"5m"
+				}`, mockAssetId, mockPropertyId)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+		data.NewField("time", nil, []time.Time{
+			time.Date(2021, 2, 1, 16, 0, 0, 0, time.UTC),
+			time.Date(2021, 2, 1, 16, 5, 0, 0, time.UTC),
+		}),
+		data.NewField("avg", nil, []float64{2, 10}),
+		data.NewField("min", nil, []float64{0, 10}),
+		data.NewField("max", nil, []float64{4, 10}),
+		data.NewField("sum", nil, []float64{8, 20}),
+		data.NewField("stddev", nil, []float64{math.Sqrt(8.0 / 3), 0}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId:    *mockAssetPropertyEntryId,
+			Resolution: "5m (derived from 1m)",
+			Aggregates: []string{models.AggregateAvg, models.AggregateMin, models.AggregateMax, models.AggregateSum, models.AggregateStdDev},
+		},
+	})
+	if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+		entry := input.Entries[0]
+		return *entry.Resolution == "1m" && slices.Contains(entry.AggregateTypes, iotsitewisetypes.AggregateTypeCount)
+	}), mock.Anything, mock.Anything)
+}
+
+func TestPropertyValueAggregate_invalid_derived_resolution(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyAggregate,
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetId":"%s",
+					"propertyId":"%s",
+					"aggregates":["AVERAGE"],
+					"resolution":"90s"
+				}`, mockAssetId, mockPropertyId)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.EqualError(t, qdr.Responses["A"].Error, "failed to fetch query data: invalid resolution 90s, expected a multiple of 1m")
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPropertyValueAggregate_timezone_days(t *testing.T) {
+	hour := func(day, hour int) iotsitewisetypes.AggregatedValue {
+		return iotsitewisetypes.AggregatedValue{
+			Timestamp: Pointer(time.Date(2021, 3, day, hour, 0, 0, 0, time.UTC)),
+			Value:     &iotsitewisetypes.Aggregates{Sum: Pointer(float64(hour))},
+		}
+	}
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AggregatedValues: []iotsitewisetypes.AggregatedValue{
+			hour(14, 4), // Mar 13 23:00 EST
+			hour(14, 5), // Mar 14 00:00 EST
+			hour(15, 3), // Mar 14 23:00 EDT
+			hour(15, 4), // Mar 15 00:00 EDT
+		},
+	}}, nil)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyAggregate,
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetId":"%s",
+					"propertyId":"%s",
+					"aggregates":["SUM"],
+					"resolution":"1d",
+					"timezone":"America/New_York"
+				}`, mockAssetId, mockPropertyId)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+		data.NewField("time", nil, []time.Time{
+			time.Date(2021, 3, 13, 5, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 14, 5, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 15, 4, 0, 0, 0, time.UTC),
+		}),
+		data.NewField("sum", nil, []float64{4, 8, 4}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId:    *mockAssetPropertyEntryId,
+			Resolution: "1d (derived from 1h)",
+			Aggregates: []string{models.AggregateSum},
+		},
+	})
+	if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+		return *input.Entries[0].Resolution == "1h"
+	}), mock.Anything, mock.Anything)
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var shiftCalendar = &models.ShiftCalendar{
+	Timezone: "UTC",
+	Shifts: []models.Shift{
+		{Name: "Morning", Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "06:00", End: "14:00"},
+		{Name: "Afternoon", Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "14:00", End: "22:00"},
+		{Name: "Night", Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "22:00", End: "06:00"},
+		{Name: "Weekend Day", Days: []string{"SAT", "SUN"}, Start: "07:00", End: "19:00"},
+		{Name: "Weekend Night", Days: []string{"SAT", "SUN"}, Start: "19:00", End: "06:00"},
+	},
+}
+
+func TestPropertyValueAggregate_shifts(t *testing.T) {
+	tests := []struct {
+		name             string
+		queryCalendar    *models.ShiftCalendar
+		settingsCalendar *models.ShiftCalendar
+		expectedError    string
+	}{
+		{
+			name:          "shift calendar of the query",
+			queryCalendar: shiftCalendar,
+		},
+		{
+			name:             "shift calendar of the datasource settings",
+			settingsCalendar: shiftCalendar,
+		},
+		{
+			name: "invalid shift calendar of the datasource settings",
+			settingsCalendar: &models.ShiftCalendar{
+				Timezone: "UTC",
+				Shifts: []models.Shift{
+					{Name: "Day", Start: "06:00", End: "18:00"},
+					{Name: "Late", Start: "17:00", End: "22:00"},
+				},
+			},
+			expectedError: "failed to fetch query data: invalid shift calendar: shifts Day and Late overlap",
+		},
+		{
+			name:          "no shift calendar",
+			expectedError: "failed to fetch query data: the SHIFT resolution requires a shift calendar in the query or the datasource settings",
+		},
+	}
+
+	hour := func(day, hour int) iotsitewisetypes.AggregatedValue {
+		return iotsitewisetypes.AggregatedValue{
+			Timestamp: Pointer(time.Date(2021, 1, day, hour, 0, 0, 0, time.UTC)),
+			Value:     &iotsitewisetypes.Aggregates{Sum: Pointer(float64(hour))},
+		}
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{{
+				EntryId: mockAssetPropertyEntryId,
+				AggregatedValues: []iotsitewisetypes.AggregatedValue{
+					hour(31, 18), // Sunday
+					hour(31, 19),
+					hour(32, 5), // Monday
+					hour(32, 6),
+					hour(32, 13),
+					hour(32, 14),
+				},
+			}}, nil)
+
+			ds := mockedDatasource(mockSw).(*sitewise.Datasource)
+			ds.Cfg.ShiftCalendar = tc.settingsCalendar
+			srvr := &server.Server{Datasource: ds}
+
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			calendar, err := json.Marshal(tc.queryCalendar)
+			require.Nil(t, err)
+
+			qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						RefID:     "A",
+						QueryType: models.QueryTypePropertyAggregate,
+						TimeRange: backend.TimeRange{
+							From: time.Date(2021, 1, 31, 18, 0, 0, 0, time.UTC),
+							To:   time.Date(2021, 2, 1, 15, 0, 0, 0, time.UTC),
+						},
+						JSON: []byte(fmt.Sprintf(`{
+							"region":"us-west-2",
+							"assetId":"%s",
+							"propertyId":"%s",
+							"aggregates":["SUM"],
+							"resolution":"SHIFT",
+							"shiftCalendar":%s
+						}`, mockAssetId, mockPropertyId, string(calendar))),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, res.Error, tc.expectedError)
+				mockSw.AssertNotCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.Nil(t, res.Error)
+
+			expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+				data.NewField("time", nil, []time.Time{
+					time.Date(2021, 1, 31, 7, 0, 0, 0, time.UTC),
+					time.Date(2021, 1, 31, 19, 0, 0, 0, time.UTC),
+					time.Date(2021, 2, 1, 6, 0, 0, 0, time.UTC),
+					time.Date(2021, 2, 1, 14, 0, 0, 0, time.UTC),
+				}),
+				data.NewField("shift", nil, []string{"Weekend Day", "Weekend Night", "Morning", "Afternoon"}),
+				data.NewField("sum", nil, []float64{18, 24, 19, 14}),
+			).SetMeta(&data.FrameMeta{
+				Custom: models.SitewiseCustomMeta{
+					EntryId:    *mockAssetPropertyEntryId,
+					Resolution: "SHIFT (derived from 1h)",
+					Aggregates: []string{models.AggregateSum},
+				},
+			})
+			if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+
+			mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+				return *input.Entries[0].Resolution == "1h"
+			}), mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockTimeWeightedValue(minute, second int, value float64) iotsitewisetypes.AssetPropertyValue {
+	return iotsitewisetypes.AssetPropertyValue{
+		Quality:   iotsitewisetypes.QualityGood,
+		Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: Pointer(time.Date(2021, 2, 1, 16, minute, second, 0, time.UTC).Unix()), OffsetInNanos: Pointer(int32(0))},
+		Value:     &iotsitewisetypes.Variant{DoubleValue: Pointer(value)},
+	}
+}
+
+func TestPropertyValueAggregate_time_weighted(t *testing.T) {
+	tests := []struct {
+		name            string
+		interpolation   string
+		lastObservation bool
+		history         []iotsitewisetypes.AssetPropertyValue
+		integral        []*float64
+		average         []*float64
+	}{
+		{
+			name:            "step from the last observation",
+			lastObservation: true,
+			integral:        []*float64{Pointer(150.0), Pointer(120.0), Pointer(120.0)},
+			average:         []*float64{Pointer(2.5), Pointer(2.0), Pointer(2.0)},
+		},
+		{
+			name:            "linear from the last observation",
+			interpolation:   models.TimeWeightedLinear,
+			lastObservation: true,
+			integral:        []*float64{Pointer(174.0), Pointer(120.0), Pointer(120.0)},
+			average:         []*float64{Pointer(2.9), Pointer(2.0), Pointer(2.0)},
+		},
+		{
+			name:            "linear with values at the same time",
+			interpolation:   models.TimeWeightedLinear,
+			lastObservation: true,
+			history: []iotsitewisetypes.AssetPropertyValue{
+				mockTimeWeightedValue(27, 15, 4),
+				mockTimeWeightedValue(27, 15, 6),
+				mockTimeWeightedValue(27, 45, 2),
+			},
+			integral: []*float64{Pointer(204.0), Pointer(120.0), Pointer(120.0)},
+			average:  []*float64{Pointer(3.4), Pointer(2.0), Pointer(2.0)},
+		},
+		{
+			name:     "step from the first value without a last observation",
+			integral: []*float64{Pointer(150.0), Pointer(120.0), Pointer(120.0)},
+			average:  []*float64{Pointer(150.0 / 45), Pointer(2.0), Pointer(2.0)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			history := tc.history
+			if history == nil {
+				history = []iotsitewisetypes.AssetPropertyValue{
+					mockTimeWeightedValue(27, 15, 4),
+					mockTimeWeightedValue(27, 45, 2),
+				}
+			}
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId:                   mockAssetPropertyEntryId,
+				AssetPropertyValueHistory: history,
+			}}, nil)
+			lastObservation := []iotsitewisetypes.AssetPropertyValue{}
+			if tc.lastObservation {
+				lastObservation = append(lastObservation, mockTimeWeightedValue(26, 0, 0))
+			}
+			mockSw.On("GetAssetPropertyValueHistory", mock.Anything, mock.Anything).Return(&iotsitewise.GetAssetPropertyValueHistoryOutput{
+				AssetPropertyValueHistory: lastObservation,
+			}, nil)
+
+			res := queryPropertyAggregate(t, mockSw, fmt.Sprintf(`{
+				"region":"us-west-2",
+				"assetId":"%s",
+				"propertyId":"%s",
+				"aggregates":["TIME_WEIGHTED_AVERAGE", "TIME_WEIGHTED_INTEGRAL"],
+				"resolution":"1m",
+				"timeWeightedInterpolation":"%s"
+			}`, mockAssetId, mockPropertyId, tc.interpolation))
+			require.Nil(t, res.Error)
+
+			expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed",
+				// the last value is held over the buckets without raw values
+				data.NewField("time", nil, []time.Time{
+					time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC),
+					time.Date(2021, 2, 1, 16, 28, 0, 0, time.UTC),
+					time.Date(2021, 2, 1, 16, 29, 0, 0, time.UTC),
+				}),
+				data.NewField("time_weighted_average", nil, tc.average),
+				data.NewField("time_weighted_integral", nil, tc.integral),
+			).SetMeta(&data.FrameMeta{
+				Custom: models.SitewiseCustomMeta{
+					EntryId:    *mockAssetPropertyEntryId,
+					Resolution: "1m (derived from RAW)",
+					Aggregates: []string{models.AggregateTimeWeightedAverage, models.AggregateTimeWeightedIntegral},
+				},
+			})
+			if diff := cmp.Diff(data.Frames{expectedFrame}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+
+			mockSw.AssertCalled(t, "GetAssetPropertyValueHistory", mock.Anything, mock.MatchedBy(func(input *iotsitewise.GetAssetPropertyValueHistoryInput) bool {
+				return input.TimeOrdering == iotsitewisetypes.TimeOrderingDescending && *input.MaxResults == 1 && input.EndDate.Before(syntheticTimeRange.From)
+			}))
+		})
+	}
+}
+
+func TestPropertyValueAggregate_invalid_time_weighted_interpolation(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	res := queryPropertyAggregate(t, mockSw, fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetId":"%s",
+		"propertyId":"%s",
+		"aggregates":["TIME_WEIGHTED_AVERAGE"],
+		"resolution":"1m",
+		"timeWeightedInterpolation":"CUBIC"
+	}`, mockAssetId, mockPropertyId))
+	require.EqualError(t, res.Error, "failed to unmarshal JSON request into query: invalid time weighted interpolation CUBIC, expected STEP or LINEAR")
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
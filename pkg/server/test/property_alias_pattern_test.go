@@ -0,0 +1,172 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// the data streams below /plant1/line are listed on two pages, /plant1/line2/temp is disassociated
+func mockListTimeSeriesPages(mockSw *mocks.SitewiseAPIClient, prefix *string) {
+	mockSw.On("ListTimeSeries", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListTimeSeriesInput) bool {
+		return (prefix == nil) == (input.AliasPrefix == nil) && (prefix == nil || *prefix == *input.AliasPrefix) && input.NextToken == nil
+	})).Return(&iotsitewise.ListTimeSeriesOutput{
+		TimeSeriesSummaries: []iotsitewisetypes.TimeSeriesSummary{
+			{Alias: Pointer("/plant1/line1/pressure"), AssetId: Pointer("line-1"), PropertyId: Pointer("pressure")},
+			{Alias: Pointer("/plant1/line1/temp"), AssetId: Pointer("line-1"), PropertyId: Pointer("temp")},
+		},
+		NextToken: Pointer("page-2"),
+	}, nil)
+	mockSw.On("ListTimeSeries", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListTimeSeriesInput) bool {
+		return input.NextToken != nil && *input.NextToken == "page-2"
+	})).Return(&iotsitewise.ListTimeSeriesOutput{
+		TimeSeriesSummaries: []iotsitewisetypes.TimeSeriesSummary{
+			{Alias: Pointer("/plant1/line2/temp")},
+			{Alias: Pointer("/plant1/line2/temperature")},
+		},
+	}, nil)
+
+	mockDescribeAssetProperty(mockSw)
+	mockSw.On("DescribeTimeSeries", mock.Anything, &iotsitewise.DescribeTimeSeriesInput{Alias: Pointer("/plant1/line2/temp")}).Return(&iotsitewise.DescribeTimeSeriesOutput{
+		Alias: Pointer("/plant1/line2/temp"),
+	}, nil)
+}
+
+func queryPropertyAliasPattern(t *testing.T, mockSw *mocks.SitewiseAPIClient, query string) backend.DataResponse {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON:      []byte(query),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+	return res
+}
+
+func matchHistoryEntries(entryIds ...*string) interface{} {
+	return mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		if len(input.Entries) != len(entryIds) {
+			return false
+		}
+		for i, e := range input.Entries {
+			if *e.EntryId != *entryIds[i] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func Test_property_alias_pattern(t *testing.T) {
+	line1 := util.GetEntryIdFromAssetProperty("line-1", "temp")
+	line2 := util.GetEntryIdFromPropertyAlias("/plant1/line2/temp")
+
+	tests := []struct {
+		name   string
+		query  string
+		prefix *string
+	}{
+		{
+			name:   "glob",
+			query:  `{"region":"us-west-2","propertyAliasPattern":"/plant1/line*/temp"}`,
+			prefix: Pointer("/plant1/line"),
+		},
+		{
+			name:   "anchored regex",
+			query:  `{"region":"us-west-2","propertyAliasPattern":"^/plant1/line\\d+/temp$","propertyAliasRegex":true}`,
+			prefix: Pointer("/plant1/line"),
+		},
+		{
+			name:  "unanchored regex",
+			query: `{"region":"us-west-2","propertyAliasPattern":"line\\d+/temp$","propertyAliasRegex":true}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockListTimeSeriesPages(mockSw, tc.prefix)
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+				mockBatchGetAssetPropertyValueHistorySuccessEntry(line1, 0),
+				mockBatchGetAssetPropertyValueHistorySuccessEntry(line2, 1),
+			}, nil)
+
+			res := queryPropertyAliasPattern(t, mockSw, tc.query)
+
+			require.Len(t, res.Frames, 2)
+			for _, frame := range res.Frames {
+				require.Empty(t, frame.Meta.Notices)
+			}
+			mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, matchHistoryEntries(line1, line2), mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func Test_property_alias_pattern_truncated(t *testing.T) {
+	line1 := util.GetEntryIdFromAssetProperty("line-1", "temp")
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockListTimeSeriesPages(mockSw, Pointer("/plant1/line"))
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+		mockBatchGetAssetPropertyValueHistorySuccessEntry(line1, 0),
+	}, nil)
+
+	res := queryPropertyAliasPattern(t, mockSw, `{"region":"us-west-2","propertyAliasPattern":"/plant1/line*/temp","maxPropertyAliasMatches":1}`)
+
+	require.Len(t, res.Frames, 1)
+	require.Equal(t, []data.Notice{{
+		Severity: data.NoticeSeverityWarning,
+		Text:     "property alias pattern /plant1/line*/temp matched more than 1 data streams, only the first 1 are queried",
+	}}, res.Frames[0].Meta.Notices)
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, matchHistoryEntries(line1), mock.Anything, mock.Anything)
+}
+
+func Test_property_alias_pattern_invalid_regex(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON:      []byte(`{"region":"us-west-2","propertyAliasPattern":"/plant1/(line","propertyAliasRegex":true}`),
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.ErrorContains(t, qdr.Responses["A"].Error, "invalid property alias pattern")
+	mockSw.AssertNotCalled(t, "ListTimeSeries", mock.Anything, mock.Anything)
+}
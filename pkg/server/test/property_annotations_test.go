@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockStringAssetPropertyValue(ts int64, value string) iotsitewisetypes.AssetPropertyValue {
+	return iotsitewisetypes.AssetPropertyValue{
+		Quality: iotsitewisetypes.QualityGood,
+		Timestamp: &iotsitewisetypes.TimeInNanos{
+			OffsetInNanos: Pointer(int32(0)),
+			TimeInSeconds: Pointer(ts),
+		},
+		Value: &iotsitewisetypes.Variant{StringValue: Pointer(value)},
+	}
+}
+
+// mockAnnotationsLastObservation returns the value before the time range, if any
+func mockAnnotationsLastObservation(mockSw *mocks.SitewiseAPIClient, values ...iotsitewisetypes.AssetPropertyValue) {
+	mockSw.On("GetAssetPropertyValueHistory", mock.Anything, mock.MatchedBy(func(input *iotsitewise.GetAssetPropertyValueHistoryInput) bool {
+		return input.TimeOrdering == iotsitewisetypes.TimeOrderingDescending && *input.MaxResults == 1
+	})).Return(&iotsitewise.GetAssetPropertyValueHistoryOutput{AssetPropertyValueHistory: values}, nil)
+}
+
+func queryPropertyAnnotations(t *testing.T, mockSw *mocks.SitewiseAPIClient, tr backend.TimeRange) backend.DataResponse {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyAnnotations(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType:     models.QueryTypePropertyAnnotations,
+				RefID:         "A",
+				MaxDataPoints: 100,
+				Interval:      1000,
+				TimeRange:     tr,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetId":"%s",
+					"propertyId":"%s"
+				}`, mockAssetId, mockPropertyId)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	return res
+}
+
+func Test_property_annotations_merge_consecutive_values(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	start := time.Date(2021, 2, 1, 16, 0, 0, 0, time.UTC)
+	tr := backend.TimeRange{From: start, To: start.Add(time.Hour)}
+
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+			mockStringAssetPropertyValue(start.Unix(), "RUNNING"),
+			mockStringAssetPropertyValue(start.Unix()+60, "RUNNING"),
+			mockStringAssetPropertyValue(start.Unix()+120, "STOPPED"),
+			mockStringAssetPropertyValue(start.Unix()+600, "RUNNING"),
+		},
+	}}, nil)
+	// the value at the start of the time range replaces the value before it
+	mockAnnotationsLastObservation(mockSw, mockStringAssetPropertyValue(start.Unix()-1800, "STOPPED"))
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetName: Pointer("Demo Turbine Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			DataType: iotsitewisetypes.PropertyDataTypeString,
+			Name:     Pointer("State"),
+		},
+	}, nil)
+
+	res := queryPropertyAnnotations(t, mockSw, tr)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 State",
+		data.NewField("time", nil, []time.Time{start, start.Add(2 * time.Minute), start.Add(10 * time.Minute)}),
+		data.NewField("timeEnd", nil, []time.Time{start.Add(2 * time.Minute), start.Add(10 * time.Minute), tr.To}),
+		data.NewField("text", nil, []string{"State: RUNNING", "State: STOPPED", "State: RUNNING"}),
+		data.NewField("tags", nil, []json.RawMessage{
+			json.RawMessage(`["Demo Turbine Asset 1","State","RUNNING"]`),
+			json.RawMessage(`["Demo Turbine Asset 1","State","STOPPED"]`),
+			json.RawMessage(`["Demo Turbine Asset 1","State","RUNNING"]`),
+		}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+	})
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+}
+
+func Test_property_annotations_numeric_property(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	successEntry := mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 0)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{successEntry}, nil)
+	mockAnnotationsLastObservation(mockSw)
+	mockDescribeAssetProperty(mockSw)
+
+	res := queryPropertyAnnotations(t, mockSw, timeRange)
+
+	require.Len(t, res.Frames[0].Fields, 0)
+	require.Len(t, res.Frames[0].Meta.Notices, 1)
+	require.Equal(t, data.NoticeSeverityWarning, res.Frames[0].Meta.Notices[0].Severity)
+	require.Equal(t, "annotations require a string or boolean property, Demo Turbine Asset 1 Wind Speed is DOUBLE", res.Frames[0].Meta.Notices[0].Text)
+
+	mockSw.AssertExpectations(t)
+}
+
+func Test_property_annotations_start_with_the_value_before_the_time_range(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	start := time.Date(2021, 2, 1, 16, 0, 0, 0, time.UTC)
+	tr := backend.TimeRange{From: start, To: start.Add(time.Hour)}
+
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+			mockStringAssetPropertyValue(start.Unix()+300, "RUNNING"),
+			mockStringAssetPropertyValue(start.Unix()+600, "STOPPED"),
+		},
+	}}, nil)
+	mockAnnotationsLastObservation(mockSw, mockStringAssetPropertyValue(start.Unix()-1800, "STOPPED"))
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetName: Pointer("Demo Turbine Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			DataType: iotsitewisetypes.PropertyDataTypeString,
+			Name:     Pointer("State"),
+		},
+	}, nil)
+
+	res := queryPropertyAnnotations(t, mockSw, tr)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1 State",
+		data.NewField("time", nil, []time.Time{start, start.Add(5 * time.Minute), start.Add(10 * time.Minute)}),
+		data.NewField("timeEnd", nil, []time.Time{start.Add(5 * time.Minute), start.Add(10 * time.Minute), tr.To}),
+		data.NewField("text", nil, []string{"State: STOPPED", "State: RUNNING", "State: STOPPED"}),
+		data.NewField("tags", nil, []json.RawMessage{
+			json.RawMessage(`["Demo Turbine Asset 1","State","STOPPED"]`),
+			json.RawMessage(`["Demo Turbine Asset 1","State","RUNNING"]`),
+			json.RawMessage(`["Demo Turbine Asset 1","State","STOPPED"]`),
+		}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+	})
+	if diff := cmp.Diff(expectedFrame, res.Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+
+	mockSw.AssertExpectations(t)
+}
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// the turbine model has two assets, each listed on a separate page
+func mockAssetModelAssets(mockSw *mocks.SitewiseAPIClient) {
+	mockSw.On("DescribeAssetModel", mock.Anything, &iotsitewise.DescribeAssetModelInput{AssetModelId: Pointer("turbine-model")}).Return(&iotsitewise.DescribeAssetModelOutput{
+		AssetModelProperties: []iotsitewisetypes.AssetModelProperty{
+			{Id: Pointer("rotor-speed"), Name: Pointer("Rotor Speed")},
+			{Id: Pointer("wind-speed"), Name: Pointer("Wind Speed")},
+		},
+	}, nil)
+	mockSw.On("ListAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssetsInput) bool {
+		return *input.AssetModelId == "turbine-model" && input.NextToken == nil
+	})).Return(&iotsitewise.ListAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssetSummary{{Id: Pointer("turbine-1"), Name: Pointer("Turbine 1")}},
+		NextToken:      Pointer("page-2"),
+	}, nil)
+	mockSw.On("ListAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssetsInput) bool {
+		return *input.AssetModelId == "turbine-model" && input.NextToken != nil && *input.NextToken == "page-2"
+	})).Return(&iotsitewise.ListAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssetSummary{{Id: Pointer("turbine-2"), Name: Pointer("Turbine 2")}},
+	}, nil)
+
+	for id, name := range map[string]string{"turbine-1": "Turbine 1", "turbine-2": "Turbine 2"} {
+		mockSw.On("DescribeAssetProperty", mock.Anything, &iotsitewise.DescribeAssetPropertyInput{
+			AssetId:    Pointer(id),
+			PropertyId: Pointer("wind-speed"),
+		}).Return(&iotsitewise.DescribeAssetPropertyOutput{
+			AssetId:   Pointer(id),
+			AssetName: Pointer(name),
+			AssetProperty: &iotsitewisetypes.Property{
+				Id:       Pointer("wind-speed"),
+				DataType: iotsitewisetypes.PropertyDataTypeDouble,
+				Name:     Pointer("Wind Speed"),
+				Unit:     Pointer("m/s"),
+			},
+		}, nil)
+	}
+}
+
+func Test_property_value_history_of_asset_model(t *testing.T) {
+	tests := []struct {
+		name     string
+		property string
+	}{
+		{name: "property name", property: `"propertyName":"Wind Speed"`},
+		{name: "property id", property: `"propertyIds":["wind-speed"]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockAssetModelAssets(mockSw)
+
+			turbine1 := util.GetEntryIdFromAssetProperty("turbine-1", "wind-speed")
+			turbine2 := util.GetEntryIdFromAssetProperty("turbine-2", "wind-speed")
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+				mockBatchGetAssetPropertyValueHistorySuccessEntry(turbine1, 0),
+				mockBatchGetAssetPropertyValueHistorySuccessEntry(turbine2, 1),
+			}, nil)
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						QueryType:     models.QueryTypePropertyValueHistory,
+						RefID:         "A",
+						MaxDataPoints: 100,
+						TimeRange:     timeRange,
+						JSON:          []byte(`{"region":"us-west-2","assetModelId":"turbine-model",` + tc.property + `}`),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+			require.Nil(t, res.Error)
+
+			expected := data.Frames{
+				data.NewFrame("Turbine 1",
+					data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
+					data.NewField("Wind Speed", data.Labels{"asset": "Turbine 1"}, []float64{23.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+					data.NewField("quality", nil, []string{"GOOD"}),
+				).SetMeta(&data.FrameMeta{
+					Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *turbine1},
+				}),
+				data.NewFrame("Turbine 2",
+					data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 1, 0, time.UTC)}),
+					data.NewField("Wind Speed", data.Labels{"asset": "Turbine 2"}, []float64{24.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+					data.NewField("quality", nil, []string{"GOOD"}),
+				).SetMeta(&data.FrameMeta{
+					Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *turbine2},
+				}),
+			}
+			if diff := cmp.Diff(expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+
+			mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+				return len(input.Entries) == 2 && *input.Entries[0].AssetId == "turbine-1" && *input.Entries[1].AssetId == "turbine-2"
+			}), mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func Test_property_value_history_of_asset_model_unknown_property(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAssetModelAssets(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON:      []byte(`{"region":"us-west-2","assetModelId":"turbine-model","propertyName":"Temperature"}`),
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.ErrorContains(t, qdr.Responses["A"].Error, "asset model turbine-model has no property Temperature")
+	mockSw.AssertNotCalled(t, "ListAssets", mock.Anything, mock.Anything)
+}
+
+func Test_property_value_history_of_asset_model_with_asset_ids(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON:      []byte(`{"region":"us-west-2","assetModelId":"turbine-model","assetIds":["turbine-1"],"propertyIds":["wind-speed"]}`),
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.ErrorContains(t, qdr.Responses["A"].Error, "an asset model query cannot be combined with asset ids or property aliases")
+	mockSw.AssertNotCalled(t, "ListAssets", mock.Anything, mock.Anything)
+}
+
+func Test_property_value_of_asset_model_timeseries(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAssetModelAssets(mockSw)
+
+	turbine1 := util.GetEntryIdFromAssetProperty("turbine-1", "wind-speed")
+	turbine2 := util.GetEntryIdFromAssetProperty("turbine-2", "wind-speed")
+	mockBatchGetAssetPropertyValue(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry{
+		mockBatchGetAssetPropertyValueSuccessEntry(turbine1, iotsitewisetypes.Variant{DoubleValue: Pointer(23.8)}, 0),
+		mockBatchGetAssetPropertyValueSuccessEntry(turbine2, iotsitewisetypes.Variant{DoubleValue: Pointer(24.8)}, 1),
+	}, nil)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValue,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON:      []byte(`{"region":"us-west-2","assetModelId":"turbine-model","propertyIds":["wind-speed"],"responseFormat":"timeseries"}`),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	require.Nil(t, res.Error)
+
+	// every asset keeps its own wide frame
+	require.Len(t, res.Frames, 2)
+	for i, asset := range []string{"Turbine 1", "Turbine 2"} {
+		field, _ := res.Frames[i].FieldByName("Wind Speed")
+		require.NotNil(t, field)
+		require.Equal(t, asset, field.Labels["asset"])
+	}
+}
+
+func Test_property_value_history_of_asset_model_caches_the_assets(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockAssetModelAssets(mockSw)
+	for range 2 {
+		mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(util.GetEntryIdFromAssetProperty("turbine-1", "wind-speed"), 0),
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(util.GetEntryIdFromAssetProperty("turbine-2", "wind-speed"), 1),
+		}, nil)
+	}
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	c := cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	sitewise.GetCache = func() *cache.Cache {
+		return c
+	}
+
+	// the next page of the query lists the assets of the model from the cache
+	for _, nextToken := range []string{"", "next"} {
+		qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+			PluginContext: backend.PluginContext{},
+			Queries: []backend.DataQuery{
+				{
+					QueryType:     models.QueryTypePropertyValueHistory,
+					RefID:         "A",
+					MaxDataPoints: 100,
+					TimeRange:     timeRange,
+					JSON:          []byte(`{"region":"us-west-2","assetModelId":"turbine-model","propertyIds":["wind-speed"],"nextToken":"` + nextToken + `"}`),
+				},
+			},
+		})
+		require.Nil(t, err)
+		require.Nil(t, qdr.Responses["A"].Error)
+	}
+
+	mockSw.AssertNumberOfCalls(t, "ListAssets", 2)
+}
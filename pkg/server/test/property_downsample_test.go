@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_property_value_history_downsample(t *testing.T) {
+	tests := []struct {
+		name          string
+		downsample    string
+		maxDataPoints int64
+		expected      *data.Frame
+	}{
+		{
+			name:          "largest triangle three buckets",
+			downsample:    models.DownsampleLTTB,
+			maxDataPoints: 4,
+			expected:      historyFrame([]int64{0, 2, 5, 9}, []float64{0, 5, -3, 0}, []string{"GOOD", "GOOD", "GOOD", "GOOD"}, nil),
+		},
+		{
+			name:          "min and max of each pixel",
+			downsample:    models.DownsampleMinMax,
+			maxDataPoints: 4,
+			expected:      historyFrame([]int64{0, 2, 5, 8}, []float64{0, 5, -3, 2}, []string{"GOOD", "GOOD", "GOOD", "GOOD"}, nil),
+		},
+		{
+			name:          "fewer values than the max data points",
+			downsample:    models.DownsampleLTTB,
+			maxDataPoints: 10,
+			expected: historyFrame(
+				[]int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+				[]float64{0, 1, 5, 2, 0, -3, 1, 0, 2, 0},
+				[]string{"GOOD", "GOOD", "GOOD", "GOOD", "GOOD", "GOOD", "GOOD", "GOOD", "GOOD", "GOOD"},
+				nil,
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			history := []iotsitewisetypes.AssetPropertyValue{}
+			for i, v := range []float64{0, 1, 5, 2, 0, -3, 1, 0, 2, 0} {
+				history = append(history, mockHistoryValue(int64(i), v, iotsitewisetypes.QualityGood))
+			}
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId:                   mockAssetPropertyEntryId,
+				AssetPropertyValueHistory: history,
+			}}, nil)
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						QueryType:     models.QueryTypePropertyValueHistory,
+						RefID:         "A",
+						TimeRange:     timeRange,
+						MaxDataPoints: tc.maxDataPoints,
+						JSON: []byte(fmt.Sprintf(`{
+							"region":"us-west-2",
+							"assetIds":["%s"],
+							"propertyIds":["%s"],
+							"downsample":"%s"
+						}`, mockAssetId, mockPropertyId, tc.downsample)),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+			require.Nil(t, res.Error)
+
+			if tc.expected.Rows() < len(history) {
+				tc.expected.Meta.Custom = models.SitewiseCustomMeta{
+					Resolution:         "RAW",
+					EntryId:            *mockAssetPropertyEntryId,
+					OriginalPointCount: len(history),
+				}
+			}
+			if diff := cmp.Diff(data.Frames{tc.expected}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
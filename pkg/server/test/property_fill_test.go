@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func queryFill(t *testing.T, mockSw *mocks.SitewiseAPIClient, fill string) backend.DataResponse {
+	t.Helper()
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetIds":["%s"],
+					"propertyIds":["%s"],
+					%s
+				}`, mockAssetId, mockPropertyId, fill)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	return res
+}
+
+func Test_property_value_history_fill(t *testing.T) {
+	at := func(second int64, offset time.Duration) time.Time {
+		return time.Unix(1612207200+second, 0).Add(offset).UTC()
+	}
+	fillFrame := func(times []time.Time, values interface{}, stale []bool) *data.Frame {
+		qualities := make([]string, len(times))
+		for i := range qualities {
+			qualities[i] = "GOOD"
+		}
+		frame := data.NewFrame("Demo Turbine Asset 1",
+			data.NewField("time", nil, times),
+			data.NewField("Wind Speed", nil, values).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+			data.NewField("quality", nil, qualities),
+		).SetMeta(&data.FrameMeta{
+			Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+		})
+		if stale != nil {
+			frame.Fields = append(frame.Fields, data.NewField("stale", nil, stale))
+		}
+		return frame
+	}
+
+	tests := []struct {
+		name     string
+		fill     string
+		expected *data.Frame
+	}{
+		{
+			name: "stale values without a fill",
+			fill: `"stalenessThreshold":"5m"`,
+			expected: fillFrame(
+				[]time.Time{at(0, 0), at(1, 0), at(600, 0), at(601, 0)},
+				[]float64{1, 2, 3, 4},
+				[]bool{false, false, true, false},
+			),
+		},
+		{
+			name: "previous value held through every gap",
+			fill: `"fill":"previous"`,
+			expected: fillFrame(
+				[]time.Time{at(0, 0), at(1, -time.Millisecond), at(1, 0), at(600, -time.Millisecond), at(600, 0), at(601, -time.Millisecond), at(601, 0)},
+				[]float64{1, 1, 2, 2, 3, 3, 4},
+				nil,
+			),
+		},
+		{
+			name: "previous value held through the stale gaps",
+			fill: `"fill":"previous","stalenessThreshold":"5m"`,
+			expected: fillFrame(
+				[]time.Time{at(0, 0), at(1, 0), at(600, -time.Millisecond), at(600, 0), at(601, 0)},
+				[]float64{1, 2, 2, 3, 4},
+				[]bool{false, false, true, true, false},
+			),
+		},
+		{
+			name: "null after the staleness threshold",
+			fill: `"fill":"null","stalenessThreshold":"5m"`,
+			expected: fillFrame(
+				[]time.Time{at(0, 0), at(1, 0), at(301, 0), at(600, 0), at(601, 0)},
+				[]*float64{Pointer(1.0), Pointer(2.0), nil, Pointer(3.0), Pointer(4.0)},
+				[]bool{false, false, true, true, false},
+			),
+		},
+		{
+			name: "zero after the staleness threshold",
+			fill: `"fill":"zero","stalenessThreshold":"5m"`,
+			expected: fillFrame(
+				[]time.Time{at(0, 0), at(1, 0), at(301, 0), at(600, -time.Millisecond), at(600, 0), at(601, 0)},
+				[]float64{1, 2, 0, 0, 3, 4},
+				[]bool{false, false, true, true, true, false},
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId: mockAssetPropertyEntryId,
+				AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+					mockHistoryValue(0, 1, iotsitewisetypes.QualityGood),
+					mockHistoryValue(1, 2, iotsitewisetypes.QualityGood),
+					mockHistoryValue(600, 3, iotsitewisetypes.QualityGood),
+					mockHistoryValue(601, 4, iotsitewisetypes.QualityGood),
+				},
+			}}, nil)
+
+			res := queryFill(t, mockSw, tc.fill)
+			require.Nil(t, res.Error)
+
+			if diff := cmp.Diff(data.Frames{tc.expected}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_property_value_history_invalid_fill(t *testing.T) {
+	tests := []struct {
+		name          string
+		fill          string
+		expectedError string
+	}{
+		{
+			name:          "unknown fill",
+			fill:          `"fill":"linear"`,
+			expectedError: "failed to unmarshal JSON request into query: invalid fill linear, expected one of none, previous, null or zero",
+		},
+		{
+			name:          "null fill without a staleness threshold",
+			fill:          `"fill":"null"`,
+			expectedError: "failed to unmarshal JSON request into query: the null fill requires a staleness threshold",
+		},
+		{
+			name:          "invalid staleness threshold",
+			fill:          `"stalenessThreshold":"-5m"`,
+			expectedError: "failed to unmarshal JSON request into query: invalid staleness threshold -5m, expected a positive duration",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			res := queryFill(t, mockSw, tc.fill)
+			require.EqualError(t, res.Error, tc.expectedError)
+			mockSw.AssertNotCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type plantAsset struct {
+	id         string
+	name       string
+	hierarchy  *iotsitewisetypes.AssetHierarchy
+	properties []string
+}
+
+// site -(Lines)-> line -(Machines)-> machine-1, machine-2
+func mockPlantHierarchy(mockSw *mocks.SitewiseAPIClient) {
+	lines := iotsitewisetypes.AssetHierarchy{Id: Pointer("lines"), Name: Pointer("Lines")}
+	machines := iotsitewisetypes.AssetHierarchy{Id: Pointer("machines"), Name: Pointer("Machines")}
+
+	assets := []plantAsset{
+		{id: "site", name: "Site", hierarchy: &lines, properties: []string{"Location"}},
+		{id: "line", name: "Line", hierarchy: &machines, properties: []string{"Line OEE"}},
+		{id: "machine-1", name: "Machine 1", properties: []string{"OEE", "Temperature"}},
+		{id: "machine-2", name: "Machine 2", properties: []string{"OEE", "Temperature"}},
+	}
+
+	for _, asset := range assets {
+		output := &iotsitewise.DescribeAssetOutput{
+			AssetId:   Pointer(asset.id),
+			AssetName: Pointer(asset.name),
+		}
+		if asset.hierarchy != nil {
+			output.AssetHierarchies = []iotsitewisetypes.AssetHierarchy{*asset.hierarchy}
+		}
+		for _, name := range asset.properties {
+			output.AssetProperties = append(output.AssetProperties, iotsitewisetypes.AssetProperty{Id: Pointer(asset.id + "/" + name), Name: Pointer(name)})
+			mockSw.On("DescribeAssetProperty", mock.Anything, &iotsitewise.DescribeAssetPropertyInput{
+				AssetId:    Pointer(asset.id),
+				PropertyId: Pointer(asset.id + "/" + name),
+			}).Return(&iotsitewise.DescribeAssetPropertyOutput{
+				AssetId:   Pointer(asset.id),
+				AssetName: Pointer(asset.name),
+				AssetProperty: &iotsitewisetypes.Property{
+					Id:       Pointer(asset.id + "/" + name),
+					Name:     Pointer(name),
+					DataType: iotsitewisetypes.PropertyDataTypeDouble,
+				},
+			}, nil)
+		}
+		mockSw.On("DescribeAsset", mock.Anything, &iotsitewise.DescribeAssetInput{AssetId: Pointer(asset.id)}).Return(output, nil)
+	}
+
+	mockListAssociatedAssets(mockSw, "site", Pointer("lines"), iotsitewisetypes.TraversalDirectionChild,
+		mockHierarchySummary("line", "Line", "line-model", machines))
+	mockListAssociatedAssets(mockSw, "line", Pointer("machines"), iotsitewisetypes.TraversalDirectionChild,
+		mockHierarchySummary("machine-1", "Machine 1", "machine-model"),
+		mockHierarchySummary("machine-2", "Machine 2", "machine-model"))
+}
+
+func Test_property_aggregates_of_hierarchy(t *testing.T) {
+	tests := []struct {
+		name         string
+		propertyName string
+		depth        int
+		expected     [][2]string // asset name, property name
+	}{
+		{
+			name:         "property name below the site",
+			propertyName: "OEE",
+			depth:        2,
+			expected:     [][2]string{{"Machine 1", "OEE"}, {"Machine 2", "OEE"}},
+		},
+		{
+			name:         "pattern below the site",
+			propertyName: "*OEE",
+			depth:        2,
+			expected:     [][2]string{{"Line", "Line OEE"}, {"Machine 1", "OEE"}, {"Machine 2", "OEE"}},
+		},
+		{
+			name:         "pattern with the default depth",
+			propertyName: "*OEE",
+			expected:     [][2]string{{"Line", "Line OEE"}, {"Machine 1", "OEE"}, {"Machine 2", "OEE"}},
+		},
+		{
+			name:         "pattern down to the lines",
+			propertyName: "*OEE",
+			depth:        1,
+			expected:     [][2]string{{"Line", "Line OEE"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockPlantHierarchy(mockSw)
+
+			assetIds := map[string]string{"Line": "line", "Machine 1": "machine-1", "Machine 2": "machine-2"}
+			successEntries := []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{}
+			expected := data.Frames{}
+			for i, e := range tc.expected {
+				entryId := util.GetEntryIdFromAssetProperty(assetIds[e[0]], assetIds[e[0]]+"/"+e[1])
+				successEntries = append(successEntries, mockBatchGetAssetPropertyAggregatesSuccessEntry(entryId, i))
+				expected = append(expected, data.NewFrame(e[0]+" "+e[1],
+					data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 16, 27, 0, 0, time.UTC)}),
+					data.NewField("sum", data.Labels{"asset": e[0]}, []float64{1688.6 + float64(i)}),
+				).SetMeta(&data.FrameMeta{
+					Custom: models.SitewiseCustomMeta{
+						EntryId:    *entryId,
+						Resolution: "1m",
+						Aggregates: []string{models.AggregateSum},
+					},
+				}))
+			}
+			mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, successEntries, nil)
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						RefID:     "A",
+						QueryType: models.QueryTypePropertyAggregate,
+						TimeRange: timeRange,
+						JSON: []byte(fmt.Sprintf(`{
+							"region":"us-west-2",
+							"assetIds":["site"],
+							"propertyName":"%s",
+							"hierarchyDepth":%d,
+							"aggregates":["SUM"],
+							"resolution":"1m"
+						}`, tc.propertyName, tc.depth)),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+			require.Nil(t, res.Error)
+
+			if diff := cmp.Diff(expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+
+			mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+				if len(input.Entries) != len(tc.expected) {
+					return false
+				}
+				for i, e := range tc.expected {
+					if *input.Entries[i].AssetId != assetIds[e[0]] || *input.Entries[i].PropertyId != assetIds[e[0]]+"/"+e[1] {
+						return false
+					}
+				}
+				return true
+			}), mock.Anything, mock.Anything)
+			if tc.depth == 1 {
+				mockSw.AssertNotCalled(t, "ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+					return *input.AssetId == "line"
+				}))
+			}
+		})
+	}
+}
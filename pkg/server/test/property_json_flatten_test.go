@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/require"
+)
+
+func mockJsonHistoryValue(second int64, value string) iotsitewisetypes.AssetPropertyValue {
+	return iotsitewisetypes.AssetPropertyValue{
+		Quality:   iotsitewisetypes.QualityGood,
+		Timestamp: &iotsitewisetypes.TimeInNanos{OffsetInNanos: Pointer(int32(0)), TimeInSeconds: Pointer(1612207200 + second)},
+		Value:     &iotsitewisetypes.Variant{StringValue: Pointer(value)},
+	}
+}
+
+func Test_property_value_history_flatten_json(t *testing.T) {
+	times := []time.Time{time.Unix(1612207200, 0).UTC(), time.Unix(1612207201, 0).UTC(), time.Unix(1612207202, 0).UTC()}
+	jsonFrame := func(jsonFields ...*data.Field) *data.Frame {
+		frameFields := append([]*data.Field{data.NewField("time", nil, times)}, jsonFields...)
+		return data.NewFrame("Demo Turbine Asset 1",
+			append(frameFields, data.NewField("quality", nil, []string{"GOOD", "GOOD", "GOOD"}))...,
+		).SetMeta(&data.FrameMeta{
+			Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+		})
+	}
+	at := time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		options  string
+		expected *data.Frame
+	}{
+		{
+			name:    "paths discovered from every document",
+			options: `"flattenJson":true`,
+			expected: jsonFrame(
+				data.NewField("at", nil, []*time.Time{&at, nil, nil}),
+				data.NewField("error", nil, []*string{nil, Pointer("timeout"), nil}),
+				data.NewField("readings.0.value", nil, []*float64{Pointer(1.5), Pointer(3.0), nil}),
+				data.NewField("readings.1.value", nil, []*float64{Pointer(2.0), nil, nil}),
+				data.NewField("readings.10.value", nil, []*float64{Pointer(4.0), nil, nil}),
+				data.NewField("status.code", nil, []*float64{Pointer(200.0), Pointer(500.0), nil}),
+				data.NewField("status.ok", nil, []*bool{Pointer(true), Pointer(false), nil}),
+			),
+		},
+		{
+			name:    "paths discovered from the first document",
+			options: `"flattenJson":true,"jsonDiscoveryRows":1`,
+			expected: jsonFrame(
+				data.NewField("at", nil, []*time.Time{&at, nil, nil}),
+				data.NewField("readings.0.value", nil, []*float64{Pointer(1.5), Pointer(3.0), nil}),
+				data.NewField("readings.1.value", nil, []*float64{Pointer(2.0), nil, nil}),
+				data.NewField("readings.10.value", nil, []*float64{Pointer(4.0), nil, nil}),
+				data.NewField("status.code", nil, []*float64{Pointer(200.0), Pointer(500.0), nil}),
+				data.NewField("status.ok", nil, []*bool{Pointer(true), Pointer(false), nil}),
+			),
+		},
+		{
+			name:    "paths of the query",
+			options: `"flattenJson":true,"jsonPaths":["$.status.code","status","missing"]`,
+			expected: jsonFrame(
+				data.NewField("$.status.code", nil, []*float64{Pointer(200.0), Pointer(500.0), nil}),
+				data.NewField("status", nil, []*string{Pointer(`{"code":200,"ok":true}`), Pointer(`{"code":500,"ok":false}`), nil}),
+				data.NewField("missing", nil, []*string{nil, nil, nil}),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId: mockAssetPropertyEntryId,
+				AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+					mockJsonHistoryValue(0, `{"status":{"code":200,"ok":true},"readings":[{"value":1.5},{"value":2},{},{},{},{},{},{},{},{},{"value":4}],"at":"2021-02-01T19:20:00Z"}`),
+					mockJsonHistoryValue(1, `{"status":{"code":500,"ok":false},"readings":[{"value":3}],"error":"timeout"}`),
+					mockJsonHistoryValue(2, `not a JSON document`),
+				},
+			}}, nil)
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						QueryType: models.QueryTypePropertyValueHistory,
+						RefID:     "A",
+						TimeRange: timeRange,
+						JSON: []byte(fmt.Sprintf(`{
+							"region":"us-west-2",
+							"assetIds":["%s"],
+							"propertyIds":["%s"],
+							%s
+						}`, mockAssetId, mockPropertyId, tc.options)),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+			require.Nil(t, res.Error)
+
+			if diff := cmp.Diff(data.Frames{tc.expected}, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockHistoryValue(second int64, value float64, quality iotsitewisetypes.Quality) iotsitewisetypes.AssetPropertyValue {
+	return iotsitewisetypes.AssetPropertyValue{
+		Quality:   quality,
+		Timestamp: &iotsitewisetypes.TimeInNanos{OffsetInNanos: Pointer(int32(0)), TimeInSeconds: Pointer(1612207200 + second)},
+		Value:     &iotsitewisetypes.Variant{DoubleValue: Pointer(value)},
+	}
+}
+
+// every quality is returned when the history is requested without a quality filter
+func mockHistoryOfEveryQuality(mockSw *mocks.SitewiseAPIClient) {
+	mockDescribeAssetProperty(mockSw)
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+		EntryId: mockAssetPropertyEntryId,
+		AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{
+			mockHistoryValue(0, 1, iotsitewisetypes.QualityGood),
+			mockHistoryValue(1, 2, iotsitewisetypes.QualityUncertain),
+			mockHistoryValue(2, 3, iotsitewisetypes.QualityBad),
+			mockHistoryValue(3, 4, iotsitewisetypes.QualityGood),
+		},
+	}}, nil)
+}
+
+func queryQualities(t *testing.T, mockSw *mocks.SitewiseAPIClient, queryType string, qualities string) backend.DataResponse {
+	t.Helper()
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	sitewise.GetCache = func() *cache.Cache {
+		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	}
+
+	req := &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: queryType,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetIds":["%s"],
+					"propertyIds":["%s"],
+					"aggregates":["AVERAGE"],
+					"resolution":"1m",
+					%s
+				}`, mockAssetId, mockPropertyId, qualities)),
+			},
+		},
+	}
+
+	var (
+		qdr *backend.QueryDataResponse
+		err error
+	)
+	switch queryType {
+	case models.QueryTypePropertyAggregate:
+		qdr, err = srvr.HandlePropertyAggregate(context.Background(), req)
+	case models.QueryTypePropertyInterpolated:
+		qdr, err = srvr.HandleInterpolatedPropertyValue(context.Background(), req)
+	default:
+		qdr, err = srvr.HandlePropertyValueHistory(context.Background(), req)
+	}
+	require.Nil(t, err)
+	res, ok := qdr.Responses["A"]
+	require.True(t, ok)
+	return res
+}
+
+func historyQualitiesMatcher(qualities []iotsitewisetypes.Quality) interface{} {
+	return mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return cmp.Equal(qualities, input.Entries[0].Qualities)
+	})
+}
+
+func historyFrame(times []int64, values interface{}, qualities []string, labels data.Labels) *data.Frame {
+	timestamps := make([]time.Time, len(times))
+	for i, s := range times {
+		timestamps[i] = time.Unix(1612207200+s, 0).UTC()
+	}
+	return data.NewFrame("Demo Turbine Asset 1",
+		data.NewField("time", nil, timestamps),
+		data.NewField("Wind Speed", labels, values).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		data.NewField("quality", nil, qualities),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+	})
+}
+
+func Test_property_value_history_qualities(t *testing.T) {
+	tests := []struct {
+		name              string
+		qualities         string
+		expectedQualities []iotsitewisetypes.Quality
+		expected          data.Frames
+	}{
+		{
+			name:              "any quality",
+			qualities:         `"quality":"ANY"`,
+			expectedQualities: nil,
+			expected: data.Frames{historyFrame(
+				[]int64{0, 1, 2, 3}, []float64{1, 2, 3, 4}, []string{"GOOD", "UNCERTAIN", "BAD", "GOOD"}, nil,
+			)},
+		},
+		{
+			name:              "single quality of the list",
+			qualities:         `"qualities":["UNCERTAIN"]`,
+			expectedQualities: []iotsitewisetypes.Quality{iotsitewisetypes.QualityUncertain},
+			expected: data.Frames{historyFrame(
+				[]int64{0, 1, 2, 3}, []float64{1, 2, 3, 4}, []string{"GOOD", "UNCERTAIN", "BAD", "GOOD"}, nil,
+			)},
+		},
+		{
+			name:              "two qualities are filtered in the backend",
+			qualities:         `"qualities":["GOOD","UNCERTAIN"]`,
+			expectedQualities: nil,
+			expected: data.Frames{historyFrame(
+				[]int64{0, 1, 3}, []float64{1, 2, 4}, []string{"GOOD", "UNCERTAIN", "GOOD"}, nil,
+			)},
+		},
+		{
+			name:              "one series per quality",
+			qualities:         `"qualities":["ANY"],"qualityMode":"split"`,
+			expectedQualities: nil,
+			expected: data.Frames{
+				historyFrame([]int64{0, 3}, []float64{1, 4}, []string{"GOOD", "GOOD"}, data.Labels{"quality": "GOOD"}),
+				historyFrame([]int64{2}, []float64{3}, []string{"BAD"}, data.Labels{"quality": "BAD"}),
+				historyFrame([]int64{1}, []float64{2}, []string{"UNCERTAIN"}, data.Labels{"quality": "UNCERTAIN"}),
+			},
+		},
+		{
+			name:              "values that are not good are nulled out",
+			qualities:         `"qualities":["GOOD","BAD"],"qualityMode":"nullNotGood"`,
+			expectedQualities: nil,
+			expected: data.Frames{historyFrame(
+				[]int64{0, 2, 3}, []*float64{Pointer(1.0), nil, Pointer(4.0)}, []string{"GOOD", "BAD", "GOOD"}, nil,
+			)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockHistoryOfEveryQuality(mockSw)
+
+			res := queryQualities(t, mockSw, models.QueryTypePropertyValueHistory, tc.qualities)
+			require.Nil(t, res.Error)
+
+			if diff := cmp.Diff(tc.expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+			mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, historyQualitiesMatcher(tc.expectedQualities), mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func Test_property_aggregate_qualities(t *testing.T) {
+	t.Run("any quality", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		mockDescribeAssetProperty(mockSw)
+		mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{
+			mockBatchGetAssetPropertyAggregatesSuccessEntry(mockAssetPropertyEntryId, 0),
+		}, nil)
+
+		res := queryQualities(t, mockSw, models.QueryTypePropertyAggregate, `"quality":"ANY"`)
+		require.Nil(t, res.Error)
+		mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+			return input.Entries[0].Qualities == nil
+		}), mock.Anything, mock.Anything)
+	})
+
+	t.Run("two qualities", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+
+		res := queryQualities(t, mockSw, models.QueryTypePropertyAggregate, `"qualities":["GOOD","BAD"]`)
+		require.ErrorContains(t, res.Error, "aggregates support a single quality or ANY")
+		mockSw.AssertNotCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_property_interpolated_qualities(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   Pointer(mockAssetId),
+		AssetName: Pointer("Demo Turbine Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       Pointer(mockPropertyId),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Name:     Pointer("Wind Speed"),
+		},
+	}, nil)
+	for quality, value := range map[iotsitewisetypes.Quality]float64{iotsitewisetypes.QualityGood: 1, iotsitewisetypes.QualityBad: 2} {
+		mockGetInterpolatedAssetPropertyValuesPageAggregation(mockSw, nil, Pointer(value), func(input *iotsitewise.GetInterpolatedAssetPropertyValuesInput) bool {
+			return input.Quality == quality
+		})
+	}
+
+	res := queryQualities(t, mockSw, models.QueryTypePropertyInterpolated, `"qualities":["BAD","GOOD"]`)
+	require.Nil(t, res.Error)
+	require.Len(t, res.Frames, 2)
+
+	for i, quality := range []string{"GOOD", "BAD"} {
+		valueField := res.Frames[i].Fields[1]
+		require.Equal(t, data.Labels{"quality": quality}, valueField.Labels)
+		require.Equal(t, float64(i+1), valueField.At(0))
+	}
+	mockSw.AssertNumberOfCalls(t, "GetInterpolatedAssetPropertyValuesPageAggregation", 2)
+}
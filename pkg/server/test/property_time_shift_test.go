@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockShiftedHistory returns a value at the start of the time range of the request, a week earlier for the shifted range
+func mockShiftedHistory(mockSw *mocks.SitewiseAPIClient, shifted bool, value float64, nextToken *string) {
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return input.Entries[0].StartDate.Before(timeRange.From.Add(-24*time.Hour)) == shifted
+	}), mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		NextToken: nextToken,
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+			EntryId: mockAssetPropertyEntryId,
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: Pointer(timeRange.From.Unix()), OffsetInNanos: Pointer(int32(0))},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: Pointer(value)},
+			}},
+		}},
+	}, nil).Once()
+}
+
+func Test_property_value_history_time_shift(t *testing.T) {
+	from := time.Unix(timeRange.From.Unix(), 0).UTC()
+	shiftedFrame := data.NewFrame("Demo Turbine Asset 1",
+		data.NewField("time", nil, []time.Time{from.AddDate(0, 0, 7)}),
+		data.NewField("Wind Speed", data.Labels{"period": "previous period"}, []float64{10}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		data.NewField("quality", nil, []string{"GOOD"}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId, Truncated: true},
+	})
+	currentFrame := data.NewFrame("Demo Turbine Asset 1",
+		data.NewField("time", nil, []time.Time{from}),
+		data.NewField("Wind Speed", nil, []float64{20}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		data.NewField("quality", nil, []string{"GOOD"}),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+	})
+
+	tests := []struct {
+		name     string
+		compare  bool
+		expected data.Frames
+	}{
+		{
+			name:     "shifted time range",
+			expected: data.Frames{shiftedFrame},
+		},
+		{
+			name:     "current and shifted time ranges",
+			compare:  true,
+			expected: data.Frames{currentFrame, shiftedFrame},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockDescribeAssetProperty(mockSw)
+			// the shifted frames are not paginated by the frontend
+			mockShiftedHistory(mockSw, true, 10, Pointer("next-token"))
+			if tc.compare {
+				mockShiftedHistory(mockSw, false, 20, nil)
+			}
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						QueryType: models.QueryTypePropertyValueHistory,
+						RefID:     "A",
+						TimeRange: timeRange,
+						JSON: []byte(fmt.Sprintf(`{
+							"region":"us-west-2",
+							"assetIds":["%s"],
+							"propertyIds":["%s"],
+							"timeShift":"-7d",
+							"timeShiftCompare":%t
+						}`, mockAssetId, mockPropertyId, tc.compare)),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+			require.Nil(t, res.Error)
+
+			if diff := cmp.Diff(tc.expected, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+			mockSw.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_property_value_history_invalid_time_shift(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{},
+		Queries: []backend.DataQuery{
+			{
+				QueryType: models.QueryTypePropertyValueHistory,
+				RefID:     "A",
+				TimeRange: timeRange,
+				JSON: []byte(fmt.Sprintf(`{
+					"region":"us-west-2",
+					"assetIds":["%s"],
+					"propertyIds":["%s"],
+					"timeShift":"last week"
+				}`, mockAssetId, mockPropertyId)),
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.EqualError(t, qdr.Responses["A"].Error, "failed to unmarshal JSON request into query: invalid time shift last week, expected for example -7d or -1y")
+}
@@ -333,3 +333,114 @@ func TestPropertyValueInterpolatedQueryMultipleIds(t *testing.T) {
 		})
 	}
 }
+
+func TestPropertyValueInterpolatedQueryInterpolationType(t *testing.T) {
+	tests := []struct {
+		name                   string
+		options                string
+		expectedType           string
+		expectedIntervalWindow *int64
+		expectedError          string
+	}{
+		{
+			name:         "linear interpolation by default",
+			options:      `"resolution": "1m"`,
+			expectedType: "LINEAR_INTERPOLATION",
+		},
+		{
+			name:         "last observation carried forward",
+			options:      `"resolution": "1m", "interpolationType": "LOCF_INTERPOLATION"`,
+			expectedType: "LOCF_INTERPOLATION",
+		},
+		{
+			name:                   "linear interpolation with an interval window",
+			options:                `"resolution": "1m", "interpolationType": "LINEAR_INTERPOLATION", "intervalWindowInSeconds": 300`,
+			expectedType:           "LINEAR_INTERPOLATION",
+			expectedIntervalWindow: Pointer(int64(300)),
+		},
+		{
+			name:          "unknown interpolation type",
+			options:       `"interpolationType": "CUBIC_INTERPOLATION"`,
+			expectedError: "invalid interpolation type CUBIC_INTERPOLATION",
+		},
+		{
+			name:          "interval window with last observation carried forward",
+			options:       `"interpolationType": "LOCF_INTERPOLATION", "intervalWindowInSeconds": 300`,
+			expectedError: "the interval window is only supported by LINEAR_INTERPOLATION",
+		},
+		{
+			name:          "interval window out of range",
+			options:       `"intervalWindowInSeconds": 320000001`,
+			expectedError: "the interval window must be between 1 and 320000000 seconds",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+
+			if tc.expectedError == "" {
+				mockGetInterpolatedAssetPropertyValuesPageAggregation(mockSw, nil, Pointer(1.1), func(input *iotsitewise.GetInterpolatedAssetPropertyValuesInput) bool {
+					return *input.Type == tc.expectedType && cmp.Equal(tc.expectedIntervalWindow, input.IntervalWindowInSeconds)
+				})
+				mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+					AssetId:   Pointer(mockAssetId),
+					AssetName: Pointer("Demo Turbine Asset 1"),
+					AssetProperty: &iotsitewisetypes.Property{
+						DataType: iotsitewisetypes.PropertyDataTypeDouble,
+						Name:     Pointer("Wind Speed"),
+						Unit:     Pointer("m/s"),
+						Id:       aws.String(mockPropertyId),
+					},
+				}, nil)
+			}
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			sitewise.GetCache = func() *cache.Cache {
+				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
+			}
+
+			qdr, err := srvr.HandleInterpolatedPropertyValue(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{},
+				Queries: []backend.DataQuery{
+					{
+						RefID:     "A",
+						QueryType: models.QueryTypePropertyInterpolated,
+						TimeRange: timeRange,
+						JSON: []byte(fmt.Sprintf(`{
+							"assetId": "%s",
+							"propertyId": "%s",
+							%s
+						}`, mockAssetId, mockPropertyId, tc.options)),
+					},
+				},
+			})
+			require.Nil(t, err)
+			res, ok := qdr.Responses["A"]
+			require.True(t, ok)
+
+			if tc.expectedError != "" {
+				require.ErrorContains(t, res.Error, tc.expectedError)
+				mockSw.AssertNotCalled(t, "GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.Nil(t, res.Error)
+			expectedFrames := data.Frames{
+				data.NewFrame("Demo Turbine Asset 1",
+					data.NewField("time", nil, []time.Time{time.Unix(1612207200, 0)}),
+					data.NewField("Wind Speed", nil, []float64{1.1}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+				).SetMeta(&data.FrameMeta{
+					Custom: models.SitewiseCustomMeta{
+						EntryId:    *mockAssetPropertyEntryId,
+						Resolution: "1m",
+					},
+				}),
+			}
+			if diff := cmp.Diff(expectedFrames, res.Frames, data.FrameTestCompareOptions()...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+			mockSw.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// alarmActivationMaxPages bounds the pages of state history read to find when the triggered alarms were raised
+const alarmActivationMaxPages = 5
+
+// ListAlarms fetches the current state of every alarm of the assets in one batch, and the state history
+// of the triggered alarms in the time range to know when they were raised and acknowledged
+func ListAlarms(ctx context.Context, sw client.SitewiseAPIClient, query models.AlarmQuery) (*framer.AssetAlarms, error) {
+	alarms, err := discoverAlarms(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []*iotsitewise.BatchGetAssetPropertyValueOutput{}
+	if len(alarms) == 0 {
+		return &framer.AssetAlarms{Alarms: alarms, Responses: responses}, nil
+	}
+
+	for _, q := range batchQueries(alarmStateQuery(query, alarms), BatchGetAssetPropertyValueMaxEntries) {
+		resp, err := sw.BatchGetAssetPropertyValue(ctx, valueBatchQueryToInput(q))
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+
+	activations, err := getAlarmActivations(ctx, sw, query, triggeredAlarms(alarms, responses))
+	if err != nil {
+		return nil, err
+	}
+
+	return &framer.AssetAlarms{Alarms: alarms, Responses: responses, Activations: activations}, nil
+}
+
+// triggeredAlarms returns the alarms whose current state is raised
+func triggeredAlarms(alarms []framer.AssetAlarm, responses []*iotsitewise.BatchGetAssetPropertyValueOutput) []framer.AssetAlarm {
+	triggered := map[string]bool{}
+	for _, r := range responses {
+		for _, s := range r.SuccessEntries {
+			if s.AssetPropertyValue == nil || s.AssetPropertyValue.Value == nil || s.AssetPropertyValue.Value.StringValue == nil {
+				continue
+			}
+			if state, err := models.ParseAlarmState(*s.AssetPropertyValue.Value.StringValue); err == nil && state.Triggered() {
+				triggered[util.Dereference(s.EntryId)] = true
+			}
+		}
+	}
+
+	result := []framer.AssetAlarm{}
+	for _, alarm := range alarms {
+		if triggered[*util.GetEntryIdFromAssetProperty(alarm.AssetId, alarm.StatePropertyId)] {
+			result = append(result, alarm)
+		}
+	}
+	return result
+}
+
+// getAlarmActivations fetches the state history of the triggered alarms in the time range of the query,
+// newest first, back to the last state in which they were not raised, which is where their current
+// activation started. The activations started before the time range begin at its first raised state.
+func getAlarmActivations(ctx context.Context, sw client.SitewiseAPIClient, query models.AlarmQuery, alarms []framer.AssetAlarm) ([]*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+	responses := []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+	if len(alarms) == 0 {
+		return responses, nil
+	}
+
+	stateQuery := alarmStateQuery(query, alarms)
+	stateQuery.TimeOrdering = iotsitewisetypes.TimeOrderingDescending
+
+	for _, q := range batchQueries(stateQuery, BatchGetAssetPropertyValueHistoryMaxEntries) {
+		req := historyBatchQueryToInput(q)
+		resp, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, req, 1, BatchGetAssetPropertyValueHistoryMaxResults)
+		if err != nil {
+			return nil, err
+		}
+		for pages := 1; pages < alarmActivationMaxPages && !activationsComplete(resp) && util.Dereference(resp.NextToken) != ""; pages++ {
+			next := *req
+			next.NextToken = resp.NextToken
+			page, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, &next, 1, BatchGetAssetPropertyValueHistoryMaxResults)
+			if err != nil {
+				return nil, err
+			}
+			resp = mergeHistoryPage(resp, page)
+		}
+		responses = append(responses, resp)
+	}
+
+	return responses, nil
+}
+
+// activationsComplete is true once the history of every alarm reaches a state in which it was not raised
+func activationsComplete(resp *iotsitewise.BatchGetAssetPropertyValueHistoryOutput) bool {
+	for _, entry := range resp.SuccessEntries {
+		complete := false
+		for _, value := range entry.AssetPropertyValueHistory {
+			if value.Value == nil || value.Value.StringValue == nil {
+				continue
+			}
+			if state, err := models.ParseAlarmState(*value.Value.StringValue); err == nil && !state.Triggered() {
+				complete = true
+				break
+			}
+		}
+		if !complete {
+			return false
+		}
+	}
+	return true
+}
+
+// GetAlarmHistory fetches every state change of the alarms of the assets in the time range
+func GetAlarmHistory(ctx context.Context, sw client.SitewiseAPIClient, query models.AlarmQuery) (*framer.AssetAlarmHistory, error) {
+	alarms, err := discoverAlarms(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+	if len(alarms) == 0 {
+		return &framer.AssetAlarmHistory{Alarms: alarms, Responses: responses}, nil
+	}
+
+	stateQuery := alarmStateQuery(query, alarms)
+	stateQuery.AutoPaginate = true
+	stateQuery.TimeOrdering = iotsitewisetypes.TimeOrderingAscending
+
+	budget := newPaginationBudget(stateQuery)
+	for _, q := range batchQueries(stateQuery, BatchGetAssetPropertyValueHistoryMaxEntries) {
+		req := historyBatchQueryToInput(q)
+		resp, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, req, q.MaxPageAggregations, int(q.MaxDataPoints))
+		if err != nil {
+			return nil, err
+		}
+		resp, err = paginateHistory(ctx, sw, req, resp, budget)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+
+	return &framer.AssetAlarmHistory{Alarms: alarms, Responses: responses}, nil
+}
+
+// alarmStateQuery targets the AWS/ALARM_STATE property of every alarm
+func alarmStateQuery(query models.AlarmQuery, alarms []framer.AssetAlarm) models.AssetPropertyValueQuery {
+	stateQuery := query.AssetPropertyValueQuery
+	stateQuery.NextToken = ""
+	stateQuery.NextTokens = nil
+	stateQuery.AssetPropertyEntries = []models.AssetPropertyEntry{}
+	for _, alarm := range alarms {
+		stateQuery.AssetPropertyEntries = append(stateQuery.AssetPropertyEntries, models.AssetPropertyEntry{
+			AssetId:    alarm.AssetId,
+			PropertyId: alarm.StatePropertyId,
+		})
+	}
+	return stateQuery
+}
+
+// discoverAlarms collects the AWS/ALARM composite models of the assets,
+// and of every descendant in the asset hierarchy when LoadAllChildren is set
+func discoverAlarms(ctx context.Context, sw client.SitewiseAPIClient, query models.AlarmQuery) ([]framer.AssetAlarm, error) {
+	alarms := []framer.AssetAlarm{}
+	seenAssetIds := make(map[string]bool)
+
+	pending := slices.Clone(query.AssetIds)
+	for len(pending) > 0 {
+		assetId := pending[0]
+		pending = pending[1:]
+		if seenAssetIds[assetId] {
+			continue
+		}
+		seenAssetIds[assetId] = true
+
+		asset, err := sw.DescribeAsset(ctx, &iotsitewise.DescribeAssetInput{AssetId: aws.String(assetId)})
+		if err != nil {
+			return nil, err
+		}
+		alarms = append(alarms, assetAlarms(asset)...)
+
+		if !query.LoadAllChildren {
+			continue
+		}
+		children, err := listChildAssetIds(ctx, sw, asset)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, children...)
+	}
+
+	return alarms, nil
+}
+
+func assetAlarms(asset *iotsitewise.DescribeAssetOutput) []framer.AssetAlarm {
+	alarms := []framer.AssetAlarm{}
+	for _, cm := range asset.AssetCompositeModels {
+		if util.Dereference(cm.Type) != models.AlarmCompositeModelType {
+			continue
+		}
+		for _, p := range cm.Properties {
+			if util.Dereference(p.Name) == models.AlarmStatePropertyName {
+				alarms = append(alarms, framer.AssetAlarm{
+					AssetId:         util.Dereference(asset.AssetId),
+					AssetName:       util.Dereference(asset.AssetName),
+					Name:            util.Dereference(cm.Name),
+					StatePropertyId: util.Dereference(p.Id),
+				})
+			}
+		}
+	}
+	return alarms
+}
+
+func listChildAssetIds(ctx context.Context, sw client.SitewiseAPIClient, asset *iotsitewise.DescribeAssetOutput) ([]string, error) {
+	children := []string{}
+	for _, h := range asset.AssetHierarchies {
+		var nextToken *string = nil
+
+		for {
+			resp, err := sw.ListAssociatedAssets(ctx, &iotsitewise.ListAssociatedAssetsInput{
+				AssetId:     asset.AssetId,
+				HierarchyId: h.Id,
+				MaxResults:  MaxSitewiseResults,
+				NextToken:   nextToken,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, assetSummary := range resp.AssetSummaries {
+				children = append(children, util.Dereference(assetSummary.Id))
+			}
+
+			if resp.NextToken == nil {
+				break
+			}
+			nextToken = resp.NextToken
+		}
+	}
+	return children, nil
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// hierarchyAsset is the part of an asset description or summary needed to walk the hierarchy
+type hierarchyAsset struct {
+	id          string
+	name        string
+	modelId     string
+	status      string
+	hierarchies []iotsitewisetypes.AssetHierarchy
+}
+
+func newHierarchyAsset(summary iotsitewisetypes.AssociatedAssetsSummary) hierarchyAsset {
+	asset := hierarchyAsset{
+		id:          util.Dereference(summary.Id),
+		name:        util.Dereference(summary.Name),
+		modelId:     util.Dereference(summary.AssetModelId),
+		hierarchies: summary.Hierarchies,
+	}
+	if summary.Status != nil {
+		asset.status = string(summary.Status.State)
+	}
+	return asset
+}
+
+type hierarchyWalker struct {
+	sw     client.SitewiseAPIClient
+	seen   map[string]bool
+	assets []hierarchyAsset
+	edges  []framer.AssetHierarchyEdge
+	// descriptions of the assets described so far
+	descriptions map[string]*iotsitewise.DescribeAssetOutput
+}
+
+func newHierarchyWalker(sw client.SitewiseAPIClient) *hierarchyWalker {
+	return &hierarchyWalker{
+		sw:           sw,
+		seen:         make(map[string]bool),
+		descriptions: make(map[string]*iotsitewise.DescribeAssetOutput),
+	}
+}
+
+// GetAssetHierarchy walks the hierarchy below or above the assets down to the depth of the query
+func GetAssetHierarchy(ctx context.Context, sw client.SitewiseAPIClient, query models.AssetHierarchyQuery) (*framer.AssetHierarchy, error) {
+	w := newHierarchyWalker(sw)
+	if err := w.walk(ctx, query.AssetIds, query.Depth, query.TraversalDirection); err != nil {
+		return nil, err
+	}
+
+	return w.hierarchy(ctx)
+}
+
+// walk visits the assets, then the assets related to them in the direction, level by level down to the depth
+func (w *hierarchyWalker) walk(ctx context.Context, assetIds []string, depth int, direction iotsitewisetypes.TraversalDirection) error {
+	level := []hierarchyAsset{}
+	for _, assetId := range assetIds {
+		resp, err := w.describe(ctx, assetId)
+		if err != nil {
+			return err
+		}
+		asset := hierarchyAsset{
+			id:          util.Dereference(resp.AssetId),
+			name:        util.Dereference(resp.AssetName),
+			modelId:     util.Dereference(resp.AssetModelId),
+			hierarchies: resp.AssetHierarchies,
+		}
+		if resp.AssetStatus != nil {
+			asset.status = string(resp.AssetStatus.State)
+		}
+		if w.visit(asset) {
+			level = append(level, asset)
+		}
+	}
+
+	for d := 0; d < depth && len(level) > 0; d++ {
+		next := []hierarchyAsset{}
+		for _, asset := range level {
+			var (
+				related []hierarchyAsset
+				err     error
+			)
+			if direction == iotsitewisetypes.TraversalDirectionParent {
+				related, err = w.parents(ctx, asset)
+			} else {
+				related, err = w.children(ctx, asset)
+			}
+			if err != nil {
+				return err
+			}
+			next = append(next, related...)
+		}
+		level = next
+	}
+
+	return nil
+}
+
+// describe returns the description of the asset, each asset is described once
+func (w *hierarchyWalker) describe(ctx context.Context, assetId string) (*iotsitewise.DescribeAssetOutput, error) {
+	if resp, ok := w.descriptions[assetId]; ok {
+		return resp, nil
+	}
+
+	resp, err := w.sw.DescribeAsset(ctx, &iotsitewise.DescribeAssetInput{AssetId: aws.String(assetId)})
+	if err != nil {
+		return nil, err
+	}
+	w.descriptions[assetId] = resp
+	return resp, nil
+}
+
+// visit records the asset as a node, and returns false if it was already part of the graph
+func (w *hierarchyWalker) visit(asset hierarchyAsset) bool {
+	if w.seen[asset.id] {
+		return false
+	}
+	w.seen[asset.id] = true
+	w.assets = append(w.assets, asset)
+	return true
+}
+
+// children adds the edges to every child of the asset and returns the children not visited yet
+func (w *hierarchyWalker) children(ctx context.Context, asset hierarchyAsset) ([]hierarchyAsset, error) {
+	unvisited := []hierarchyAsset{}
+	for _, h := range asset.hierarchies {
+		children, err := w.listAssociatedAssets(ctx, asset.id, h.Id, iotsitewisetypes.TraversalDirectionChild)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			w.edges = append(w.edges, framer.AssetHierarchyEdge{
+				Source:        asset.id,
+				Target:        child.id,
+				HierarchyId:   util.Dereference(h.Id),
+				HierarchyName: util.Dereference(h.Name),
+			})
+			if w.visit(child) {
+				unvisited = append(unvisited, child)
+			}
+		}
+	}
+	return unvisited, nil
+}
+
+// parents adds the edge from the parent of the asset and returns the parent if it was not visited yet
+func (w *hierarchyWalker) parents(ctx context.Context, asset hierarchyAsset) ([]hierarchyAsset, error) {
+	parents, err := w.listAssociatedAssets(ctx, asset.id, nil, iotsitewisetypes.TraversalDirectionParent)
+	if err != nil {
+		return nil, err
+	}
+
+	unvisited := []hierarchyAsset{}
+	for _, parent := range parents {
+		h, err := w.parentHierarchy(ctx, parent, asset.id)
+		if err != nil {
+			return nil, err
+		}
+		w.edges = append(w.edges, framer.AssetHierarchyEdge{
+			Source:        parent.id,
+			Target:        asset.id,
+			HierarchyId:   util.Dereference(h.Id),
+			HierarchyName: util.Dereference(h.Name),
+		})
+		if w.visit(parent) {
+			unvisited = append(unvisited, parent)
+		}
+	}
+	return unvisited, nil
+}
+
+// parentHierarchy finds the hierarchy of the parent the child is associated to.
+// The parent traversal does not return it, so the children of each hierarchy are listed when there is more than one.
+func (w *hierarchyWalker) parentHierarchy(ctx context.Context, parent hierarchyAsset, childId string) (iotsitewisetypes.AssetHierarchy, error) {
+	if len(parent.hierarchies) == 1 {
+		return parent.hierarchies[0], nil
+	}
+
+	for _, h := range parent.hierarchies {
+		children, err := w.listAssociatedAssets(ctx, parent.id, h.Id, iotsitewisetypes.TraversalDirectionChild)
+		if err != nil {
+			return iotsitewisetypes.AssetHierarchy{}, err
+		}
+		for _, child := range children {
+			if child.id == childId {
+				return h, nil
+			}
+		}
+	}
+	return iotsitewisetypes.AssetHierarchy{}, nil
+}
+
+func (w *hierarchyWalker) listAssociatedAssets(ctx context.Context, assetId string, hierarchyId *string,
+	traversalDirection iotsitewisetypes.TraversalDirection) ([]hierarchyAsset, error) {
+	assets := []hierarchyAsset{}
+	var nextToken *string = nil
+
+	for {
+		resp, err := w.sw.ListAssociatedAssets(ctx, &iotsitewise.ListAssociatedAssetsInput{
+			AssetId:            aws.String(assetId),
+			HierarchyId:        hierarchyId,
+			MaxResults:         MaxSitewiseResults,
+			NextToken:          nextToken,
+			TraversalDirection: traversalDirection,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range resp.AssetSummaries {
+			assets = append(assets, newHierarchyAsset(summary))
+		}
+
+		if resp.NextToken == nil {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+
+	return assets, nil
+}
+
+// hierarchy resolves the model names used as node subtitles, each model is described once
+func (w *hierarchyWalker) hierarchy(ctx context.Context) (*framer.AssetHierarchy, error) {
+	modelNames := make(map[string]string)
+	nodes := []framer.AssetHierarchyNode{}
+	for _, asset := range w.assets {
+		modelName, ok := modelNames[asset.modelId]
+		if !ok && asset.modelId != "" {
+			resp, err := w.sw.DescribeAssetModel(ctx, &iotsitewise.DescribeAssetModelInput{AssetModelId: aws.String(asset.modelId)})
+			if err != nil {
+				return nil, err
+			}
+			modelName = util.Dereference(resp.AssetModelName)
+			modelNames[asset.modelId] = modelName
+		}
+		nodes = append(nodes, framer.AssetHierarchyNode{
+			Id:        asset.id,
+			Name:      asset.name,
+			ModelName: modelName,
+			Status:    asset.status,
+		})
+	}
+
+	return &framer.AssetHierarchy{Nodes: nodes, Edges: w.edges}, nil
+}
+
+// getHierarchyEntries expands the query to the properties matching the property name or pattern
+// of the assets and their descendants, down to the hierarchy depth of the query
+func getHierarchyEntries(ctx context.Context, sw client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]models.AssetPropertyEntry, error) {
+	w := newHierarchyWalker(sw)
+	if err := w.walk(ctx, query.AssetIds, query.HierarchyDepth, iotsitewisetypes.TraversalDirectionChild); err != nil {
+		return nil, err
+	}
+
+	pattern := util.GlobToRegexp(query.PropertyName)
+	entries := []models.AssetPropertyEntry{}
+	for _, asset := range w.assets {
+		resp, err := w.describe(ctx, asset.id)
+		if err != nil {
+			return nil, err
+		}
+		for _, property := range resp.AssetProperties {
+			if pattern.MatchString(util.Dereference(property.Name)) {
+				entries = append(entries, models.AssetPropertyEntry{
+					AssetId:    asset.id,
+					PropertyId: util.Dereference(property.Id),
+				})
+			}
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// GetCache returns the cache of the assets of the asset models, so that the pages of a query on an
+// asset model do not list the assets again. It is set by the datasource, nothing is cached when nil.
+var GetCache func() *cache.Cache
+
+// getAssetModelEntries expands a query on an asset model to the property of every asset of the model.
+// The ids of the asset properties are the ids of the asset model properties.
+func getAssetModelEntries(ctx context.Context, sw client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]models.AssetPropertyEntry, error) {
+	propertyIds := query.PropertyIds
+	if query.PropertyName != "" {
+		propertyId, err := getAssetModelPropertyId(ctx, sw, query.AssetModelId, query.PropertyName)
+		if err != nil {
+			return nil, err
+		}
+		propertyIds = []string{propertyId}
+	}
+
+	assetIds, err := getAssetModelAssetIds(ctx, sw, query.AwsRegion, query.AssetModelId)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.AssetPropertyEntry{}
+	for _, assetId := range assetIds {
+		for _, propertyId := range propertyIds {
+			entries = append(entries, models.AssetPropertyEntry{
+				AssetId:    assetId,
+				PropertyId: propertyId,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// getAssetModelAssetIds lists the ids of every asset of the asset model, or returns them from the cache
+func getAssetModelAssetIds(ctx context.Context, sw client.SitewiseAPIClient, region string, modelId string) ([]string, error) {
+	key := fmt.Sprintf("assetModelAssets/%s/%s", region, modelId)
+	if GetCache != nil {
+		if val, ok := GetCache().Get(key); ok {
+			if assetIds, ok := val.([]string); ok {
+				return assetIds, nil
+			}
+		}
+	}
+
+	assetIds := []string{}
+	var nextToken *string
+	for {
+		resp, err := sw.ListAssets(ctx, &iotsitewise.ListAssetsInput{
+			AssetModelId: aws.String(modelId),
+			Filter:       iotsitewisetypes.ListAssetsFilterAll,
+			MaxResults:   MaxSitewiseResults,
+			NextToken:    nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, asset := range resp.AssetSummaries {
+			assetIds = append(assetIds, util.Dereference(asset.Id))
+		}
+
+		if resp.NextToken == nil {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+
+	if GetCache != nil {
+		GetCache().Set(key, assetIds, cache.DefaultExpiration)
+	}
+	return assetIds, nil
+}
+
+func getAssetModelPropertyId(ctx context.Context, sw client.SitewiseAPIClient, modelId string, propertyName string) (string, error) {
+	resp, err := sw.DescribeAssetModel(ctx, &iotsitewise.DescribeAssetModelInput{
+		AssetModelId: aws.String(modelId),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, property := range resp.AssetModelProperties {
+		if util.Dereference(property.Name) == propertyName {
+			return util.Dereference(property.Id), nil
+		}
+	}
+
+	return "", fmt.Errorf("asset model %s has no property %s", modelId, propertyName)
+}
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
@@ -24,9 +26,76 @@ func GetAssetPropertyDescription(ctx context.Context, sw client.SitewiseAPIClien
 	}
 
 	return &framer.AssetProperty{
-		AssetId:       resp.AssetId,
-		AssetModelId:  resp.AssetModelId,
-		AssetName:     resp.AssetName,
-		AssetProperty: resp.AssetProperty,
+		AssetId:        resp.AssetId,
+		AssetModelId:   resp.AssetModelId,
+		AssetName:      resp.AssetName,
+		AssetProperty:  resp.AssetProperty,
+		CompositeModel: resp.CompositeModel,
 	}, nil
 }
+
+// GetAssetPropertyDescriptions describes the properties of the assets, every property of an asset when the query
+// has no property ids, and the properties of the aliases. Aliases of data streams that are not associated to
+// an asset property are returned with the alias only.
+func GetAssetPropertyDescriptions(ctx context.Context, sw client.SitewiseAPIClient, query models.DescribeAssetPropertyQuery) (framer.AssetPropertyDescriptions, error) {
+	var entries []models.AssetPropertyEntry
+	if len(query.PropertyAliases) == 0 && len(query.PropertyIds) == 0 {
+		assetEntries, err := getAssetPropertyEntries(ctx, sw, query.AssetIds)
+		if err != nil {
+			return nil, err
+		}
+		entries = assetEntries
+	} else {
+		entriesQuery, err := getAssetIdAndPropertyId(models.AssetPropertyValueQuery{BaseQuery: query.BaseQuery}, sw, ctx)
+		if err != nil {
+			return nil, err
+		}
+		entries = entriesQuery.AssetPropertyEntries
+	}
+
+	properties := framer.AssetPropertyDescriptions{}
+	for _, entry := range entries {
+		if entry.AssetId == "" || entry.PropertyId == "" {
+			properties = append(properties, framer.AssetProperty{
+				AssetProperty: &iotsitewisetypes.Property{Alias: aws.String(entry.PropertyAlias)},
+			})
+			continue
+		}
+
+		resp, err := sw.DescribeAssetProperty(ctx, &iotsitewise.DescribeAssetPropertyInput{
+			AssetId:    aws.String(entry.AssetId),
+			PropertyId: aws.String(entry.PropertyId),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		properties = append(properties, framer.AssetProperty{
+			AssetId:        resp.AssetId,
+			AssetModelId:   resp.AssetModelId,
+			AssetName:      resp.AssetName,
+			AssetProperty:  resp.AssetProperty,
+			CompositeModel: resp.CompositeModel,
+		})
+	}
+
+	return properties, nil
+}
+
+// getAssetPropertyEntries returns an entry for every property of the assets
+func getAssetPropertyEntries(ctx context.Context, sw client.SitewiseAPIClient, assetIds []string) ([]models.AssetPropertyEntry, error) {
+	entries := []models.AssetPropertyEntry{}
+	for _, assetId := range assetIds {
+		resp, err := sw.DescribeAsset(ctx, &iotsitewise.DescribeAssetInput{AssetId: aws.String(assetId)})
+		if err != nil {
+			return nil, err
+		}
+		for _, property := range resp.AssetProperties {
+			entries = append(entries, models.AssetPropertyEntry{
+				AssetId:    assetId,
+				PropertyId: util.Dereference(property.Id),
+			})
+		}
+	}
+	return entries, nil
+}
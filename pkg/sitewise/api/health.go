@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+// The health check probes reference resources that do not exist. IAM is evaluated before the
+// request is validated, so a not found or validation error still proves the call is allowed.
+const (
+	healthCheckId    = "00000000-0000-0000-0000-000000000000"
+	healthCheckAlias = "/grafana/health-check"
+	healthCheckEntry = "healthcheck"
+)
+
+type healthProbe struct {
+	api string
+	// batch APIs and ExecuteQuery are not available at the edge
+	cloudOnly bool
+	call      func(ctx context.Context, sw client.SitewiseAPIClient) error
+}
+
+var healthProbes = []healthProbe{
+	{api: "ListAssetModels", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssetModels(ctx, &iotsitewise.ListAssetModelsInput{MaxResults: aws.Int32(1)})
+		return err
+	}},
+	{api: "ListAssets", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssets(ctx, &iotsitewise.ListAssetsInput{MaxResults: aws.Int32(1), Filter: iotsitewisetypes.ListAssetsFilterTopLevel})
+		return err
+	}},
+	{api: "ListAssetProperties", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssetProperties(ctx, &iotsitewise.ListAssetPropertiesInput{AssetId: aws.String(healthCheckId), MaxResults: aws.Int32(1)})
+		return err
+	}},
+	{api: "ListAssociatedAssets", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssociatedAssets(ctx, &iotsitewise.ListAssociatedAssetsInput{
+			AssetId:            aws.String(healthCheckId),
+			TraversalDirection: iotsitewisetypes.TraversalDirectionParent,
+			MaxResults:         aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "ListTimeSeries", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListTimeSeries(ctx, &iotsitewise.ListTimeSeriesInput{MaxResults: aws.Int32(1)})
+		return err
+	}},
+	{api: "DescribeAsset", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeAsset(ctx, &iotsitewise.DescribeAssetInput{AssetId: aws.String(healthCheckId)})
+		return err
+	}},
+	{api: "DescribeAssetModel", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeAssetModel(ctx, &iotsitewise.DescribeAssetModelInput{AssetModelId: aws.String(healthCheckId)})
+		return err
+	}},
+	{api: "DescribeAssetProperty", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeAssetProperty(ctx, &iotsitewise.DescribeAssetPropertyInput{AssetId: aws.String(healthCheckId), PropertyId: aws.String(healthCheckId)})
+		return err
+	}},
+	{api: "DescribeTimeSeries", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeTimeSeries(ctx, &iotsitewise.DescribeTimeSeriesInput{Alias: aws.String(healthCheckAlias)})
+		return err
+	}},
+	{api: "GetAssetPropertyValue", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.GetAssetPropertyValue(ctx, &iotsitewise.GetAssetPropertyValueInput{PropertyAlias: aws.String(healthCheckAlias)})
+		return err
+	}},
+	{api: "GetAssetPropertyValueHistory", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		from, to := healthCheckTimeRange()
+		_, err := sw.GetAssetPropertyValueHistory(ctx, &iotsitewise.GetAssetPropertyValueHistoryInput{
+			PropertyAlias: aws.String(healthCheckAlias),
+			StartDate:     from,
+			EndDate:       to,
+			MaxResults:    aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "GetAssetPropertyAggregates", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		from, to := healthCheckTimeRange()
+		_, err := sw.GetAssetPropertyAggregates(ctx, &iotsitewise.GetAssetPropertyAggregatesInput{
+			PropertyAlias:  aws.String(healthCheckAlias),
+			AggregateTypes: []iotsitewisetypes.AggregateType{iotsitewisetypes.AggregateTypeAverage},
+			Resolution:     aws.String("1h"),
+			StartDate:      from,
+			EndDate:        to,
+			MaxResults:     aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "GetInterpolatedAssetPropertyValues", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		from, to := healthCheckTimeRange()
+		_, err := sw.GetInterpolatedAssetPropertyValues(ctx, &iotsitewise.GetInterpolatedAssetPropertyValuesInput{
+			PropertyAlias:      aws.String(healthCheckAlias),
+			StartTimeInSeconds: aws.Int64(from.Unix()),
+			EndTimeInSeconds:   aws.Int64(to.Unix()),
+			IntervalInSeconds:  aws.Int64(3600),
+			Quality:            iotsitewisetypes.QualityGood,
+			Type:               aws.String(LINEAR_INTERPOLATION),
+			MaxResults:         aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "BatchGetAssetPropertyValue", cloudOnly: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.BatchGetAssetPropertyValue(ctx, &iotsitewise.BatchGetAssetPropertyValueInput{
+			Entries: []iotsitewisetypes.BatchGetAssetPropertyValueEntry{{
+				EntryId:       aws.String(healthCheckEntry),
+				PropertyAlias: aws.String(healthCheckAlias),
+			}},
+		})
+		return err
+	}},
+	{api: "BatchGetAssetPropertyValueHistory", cloudOnly: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		from, to := healthCheckTimeRange()
+		_, err := sw.BatchGetAssetPropertyValueHistory(ctx, &iotsitewise.BatchGetAssetPropertyValueHistoryInput{
+			Entries: []iotsitewisetypes.BatchGetAssetPropertyValueHistoryEntry{{
+				EntryId:       aws.String(healthCheckEntry),
+				PropertyAlias: aws.String(healthCheckAlias),
+				StartDate:     from,
+				EndDate:       to,
+			}},
+			MaxResults: aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "BatchGetAssetPropertyAggregates", cloudOnly: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		from, to := healthCheckTimeRange()
+		_, err := sw.BatchGetAssetPropertyAggregates(ctx, &iotsitewise.BatchGetAssetPropertyAggregatesInput{
+			Entries: []iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{{
+				EntryId:        aws.String(healthCheckEntry),
+				PropertyAlias:  aws.String(healthCheckAlias),
+				AggregateTypes: []iotsitewisetypes.AggregateType{iotsitewisetypes.AggregateTypeAverage},
+				Resolution:     aws.String("1h"),
+				StartDate:      from,
+				EndDate:        to,
+			}},
+			MaxResults: aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "ExecuteQuery", cloudOnly: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ExecuteQuery(ctx, &iotsitewise.ExecuteQueryInput{
+			QueryStatement: aws.String("SELECT asset_id FROM asset"),
+			MaxResults:     aws.Int32(1),
+		})
+		return err
+	}},
+}
+
+func healthCheckTimeRange() (*time.Time, *time.Time) {
+	to := time.Now().Truncate(time.Hour)
+	from := to.Add(-time.Hour)
+	return &from, &to
+}
+
+// CheckPermissions calls every SiteWise API the plugin depends on and reports which of them
+// the datasource credentials are allowed to use
+func CheckPermissions(ctx context.Context, sw client.SitewiseAPIClient, edge bool) *models.HealthReport {
+	report := &models.HealthReport{APIs: make([]models.HealthProbe, len(healthProbes))}
+
+	var wg sync.WaitGroup
+	for i, probe := range healthProbes {
+		report.APIs[i] = models.HealthProbe{API: probe.api}
+		if probe.cloudOnly && edge {
+			report.APIs[i].Status = models.HealthProbeSkipped
+			report.APIs[i].Message = "not available at the edge"
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, probe healthProbe) {
+			defer wg.Done()
+			report.APIs[i].Status, report.APIs[i].Message = healthProbeStatus(probe.call(ctx, sw))
+		}(i, probe)
+	}
+	wg.Wait()
+
+	passed, failed := 0, 0
+	for _, probe := range report.APIs {
+		switch probe.Status {
+		case models.HealthProbePass:
+			passed++
+		case models.HealthProbeFail, models.HealthProbeDenied:
+			failed++
+		}
+	}
+
+	// unknown probes do not tell whether the credentials work, at least one probe has to pass
+	switch {
+	case passed == 0:
+		report.Status = models.HealthReportError
+	case failed == 0:
+		report.Status = models.HealthReportOk
+	default:
+		report.Status = models.HealthReportDegraded
+	}
+
+	return report
+}
+
+func healthProbeStatus(err error) (string, string) {
+	if err == nil {
+		return models.HealthProbePass, ""
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDeniedException":
+			return models.HealthProbeDenied, apiErr.ErrorMessage()
+		case "ResourceNotFoundException", "InvalidRequestException", "ValidationException":
+			return models.HealthProbePass, ""
+		}
+	}
+
+	// throttling, server side and other transient errors do not tell whether the call is allowed
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err).Bool() ||
+		(apiErr != nil && apiErr.ErrorFault() == smithy.FaultServer) {
+		return models.HealthProbeUnknown, err.Error()
+	}
+
+	return models.HealthProbeFail, err.Error()
+}
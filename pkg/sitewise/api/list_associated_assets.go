@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"errors"
+
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -107,3 +109,32 @@ func ListAssociatedAssets(ctx context.Context, client client.SitewiseAPIClient,
 		AssetSummaries: results,
 	}, nil
 }
+
+// ListChildAssets returns a single page of the children of the first asset of the query in its hierarchy,
+// starting at the next token of the query. MaxSitewiseResults is used when maxResults is not set.
+func ListChildAssets(ctx context.Context, client client.SitewiseAPIClient, query models.ListAssociatedAssetsQuery, maxResults int32) (*framer.AssociatedAssets, error) {
+	if len(query.AssetIds) == 0 || query.HierarchyId == "" {
+		return nil, errors.New("listing child assets requires an asset id and a hierarchy id")
+	}
+
+	limit := MaxSitewiseResults
+	if maxResults > 0 {
+		limit = aws.Int32(maxResults)
+	}
+
+	resp, err := client.ListAssociatedAssets(ctx, &iotsitewise.ListAssociatedAssetsInput{
+		AssetId:            aws.String(query.AssetIds[0]),
+		HierarchyId:        aws.String(query.HierarchyId),
+		MaxResults:         limit,
+		NextToken:          getNextToken(query.BaseQuery),
+		TraversalDirection: iotsitewisetypes.TraversalDirectionChild,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &framer.AssociatedAssets{
+		AssetSummaries: resp.AssetSummaries,
+		NextToken:      resp.NextToken,
+	}, nil
+}
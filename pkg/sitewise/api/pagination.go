@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+// paginationBudget bounds how long and how many data points an auto paginated query may fetch.
+// It is shared by every batch of the same query.
+type paginationBudget struct {
+	deadline  time.Time
+	remaining int
+}
+
+func newPaginationBudget(query models.AssetPropertyValueQuery) *paginationBudget {
+	return &paginationBudget{
+		deadline:  time.Now().Add(query.AutoPaginateTimeout()),
+		remaining: query.AutoPaginateDataPoints(),
+	}
+}
+
+func (b *paginationBudget) exhausted() bool {
+	return b.remaining <= 0 || !time.Now().Before(b.deadline)
+}
+
+func (b *paginationBudget) spend(dataPoints int) {
+	b.remaining -= dataPoints
+}
+
+func historyDataPoints(resp *iotsitewise.BatchGetAssetPropertyValueHistoryOutput) int {
+	count := 0
+	for _, entry := range resp.SuccessEntries {
+		count += len(entry.AssetPropertyValueHistory)
+	}
+	return count
+}
+
+func aggregatesDataPoints(resp *iotsitewise.BatchGetAssetPropertyAggregatesOutput) int {
+	count := 0
+	for _, entry := range resp.SuccessEntries {
+		count += len(entry.AggregatedValues)
+	}
+	return count
+}
+
+// paginateHistory follows the next token of a history batch until every entry is complete or the budget is exhausted.
+// The returned response keeps the last next token, so a truncated response can still be continued by the caller.
+func paginateHistory(ctx context.Context, sw client.SitewiseAPIClient, req *iotsitewise.BatchGetAssetPropertyValueHistoryInput,
+	resp *iotsitewise.BatchGetAssetPropertyValueHistoryOutput, budget *paginationBudget) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+	budget.spend(historyDataPoints(resp))
+
+	for resp.NextToken != nil && *resp.NextToken != "" && !budget.exhausted() {
+		next := *req
+		next.NextToken = resp.NextToken
+		page, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, &next, 1, budget.remaining)
+		if err != nil {
+			return nil, err
+		}
+		budget.spend(historyDataPoints(page))
+		resp = mergeHistoryPage(resp, page)
+	}
+
+	if resp.NextToken != nil && *resp.NextToken != "" {
+		backend.Logger.FromContext(ctx).Debug("auto pagination budget exhausted", "api", "BatchGetAssetPropertyValueHistory")
+	}
+
+	return resp, nil
+}
+
+func mergeHistoryPage(resp *iotsitewise.BatchGetAssetPropertyValueHistoryOutput, page *iotsitewise.BatchGetAssetPropertyValueHistoryOutput) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+	merged := &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: slices.Clone(resp.SuccessEntries),
+		SkippedEntries: slices.Concat(resp.SkippedEntries, page.SkippedEntries),
+		ErrorEntries:   slices.Concat(resp.ErrorEntries, page.ErrorEntries),
+		NextToken:      page.NextToken,
+	}
+
+	for _, pageEntry := range page.SuccessEntries {
+		found := false
+		for i, entry := range merged.SuccessEntries {
+			if *entry.EntryId == *pageEntry.EntryId {
+				merged.SuccessEntries[i].AssetPropertyValueHistory = slices.Concat(entry.AssetPropertyValueHistory, pageEntry.AssetPropertyValueHistory)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.SuccessEntries = append(merged.SuccessEntries, pageEntry)
+		}
+	}
+
+	return merged
+}
+
+// paginateAggregates follows the next token of an aggregates batch until every entry is complete or the budget is exhausted.
+// The returned response keeps the last next token, so a truncated response can still be continued by the caller.
+func paginateAggregates(ctx context.Context, sw client.SitewiseAPIClient, req *iotsitewise.BatchGetAssetPropertyAggregatesInput,
+	resp *iotsitewise.BatchGetAssetPropertyAggregatesOutput, budget *paginationBudget) (*iotsitewise.BatchGetAssetPropertyAggregatesOutput, error) {
+	budget.spend(aggregatesDataPoints(resp))
+
+	for resp.NextToken != nil && *resp.NextToken != "" && !budget.exhausted() {
+		next := *req
+		next.NextToken = resp.NextToken
+		page, err := sw.BatchGetAssetPropertyAggregatesPageAggregation(ctx, &next, 1, budget.remaining)
+		if err != nil {
+			return nil, err
+		}
+		budget.spend(aggregatesDataPoints(page))
+		resp = mergeAggregatesPage(resp, page)
+	}
+
+	if resp.NextToken != nil && *resp.NextToken != "" {
+		backend.Logger.FromContext(ctx).Debug("auto pagination budget exhausted", "api", "BatchGetAssetPropertyAggregates")
+	}
+
+	return resp, nil
+}
+
+func mergeAggregatesPage(resp *iotsitewise.BatchGetAssetPropertyAggregatesOutput, page *iotsitewise.BatchGetAssetPropertyAggregatesOutput) *iotsitewise.BatchGetAssetPropertyAggregatesOutput {
+	merged := &iotsitewise.BatchGetAssetPropertyAggregatesOutput{
+		SuccessEntries: slices.Clone(resp.SuccessEntries),
+		SkippedEntries: slices.Concat(resp.SkippedEntries, page.SkippedEntries),
+		ErrorEntries:   slices.Concat(resp.ErrorEntries, page.ErrorEntries),
+		NextToken:      page.NextToken,
+	}
+
+	for _, pageEntry := range page.SuccessEntries {
+		found := false
+		for i, entry := range merged.SuccessEntries {
+			if *entry.EntryId == *pageEntry.EntryId {
+				merged.SuccessEntries[i].AggregatedValues = slices.Concat(entry.AggregatedValues, pageEntry.AggregatedValues)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.SuccessEntries = append(merged.SuccessEntries, pageEntry)
+		}
+	}
+
+	return merged
+}
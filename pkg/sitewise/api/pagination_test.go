@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func historyPage(nextToken *string, timestamps ...int64) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+	values := []iotsitewisetypes.AssetPropertyValue{}
+	for _, ts := range timestamps {
+		values = append(values, iotsitewisetypes.AssetPropertyValue{
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(ts)},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(ts))},
+		})
+	}
+	return &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+			EntryId:                   util.GetEntryIdFromAssetProperty("asset", "prop"),
+			AssetPropertyValueHistory: values,
+		}},
+		NextToken: nextToken,
+	}
+}
+
+func withNextToken(token string) interface{} {
+	return mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return util.Dereference(input.NextToken) == token
+	})
+}
+
+func historyQuery(autoPaginate bool, maxDataPoints int64) models.AssetPropertyValueQuery {
+	return models.AssetPropertyValueQuery{
+		BaseQuery: models.BaseQuery{
+			AssetIds:            []string{"asset"},
+			PropertyIds:         []string{"prop"},
+			MaxPageAggregations: 1,
+			MaxDataPoints:       100,
+			TimeRange:           backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(100, 0)},
+		},
+		PaginationOptions: models.PaginationOptions{
+			AutoPaginate:              autoPaginate,
+			AutoPaginateMaxDataPoints: maxDataPoints,
+		},
+	}
+}
+
+func TestBatchGetAssetPropertyValuesAutoPaginate(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken(""), mock.Anything, mock.Anything).Return(historyPage(aws.String("page-2"), 1, 2), nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken("page-2"), mock.Anything, mock.Anything).Return(historyPage(aws.String("page-3"), 3, 4), nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken("page-3"), mock.Anything, mock.Anything).Return(historyPage(nil, 5), nil)
+
+	t.Run("disabled returns the first page", func(t *testing.T) {
+		_, batch, err := api.BatchGetAssetPropertyValues(context.Background(), mockSw, historyQuery(false, 0))
+		require.NoError(t, err)
+
+		require.Len(t, batch.Responses, 1)
+		assert.Len(t, batch.Responses[0].SuccessEntries[0].AssetPropertyValueHistory, 2)
+		assert.Equal(t, "page-2", *batch.Responses[0].NextToken)
+	})
+
+	t.Run("follows next tokens until the range is complete", func(t *testing.T) {
+		_, batch, err := api.BatchGetAssetPropertyValues(context.Background(), mockSw, historyQuery(true, 0))
+		require.NoError(t, err)
+
+		require.Len(t, batch.Responses, 1)
+		require.Len(t, batch.Responses[0].SuccessEntries, 1)
+		assert.Len(t, batch.Responses[0].SuccessEntries[0].AssetPropertyValueHistory, 5)
+		assert.Nil(t, batch.Responses[0].NextToken)
+	})
+
+	t.Run("stops at the data point budget", func(t *testing.T) {
+		_, batch, err := api.BatchGetAssetPropertyValues(context.Background(), mockSw, historyQuery(true, 3))
+		require.NoError(t, err)
+
+		require.Len(t, batch.Responses, 1)
+		assert.Len(t, batch.Responses[0].SuccessEntries[0].AssetPropertyValueHistory, 4)
+		assert.Equal(t, "page-3", *batch.Responses[0].NextToken)
+	})
+}
+
+func TestBatchGetAssetPropertyValuesAutoPaginateKeepsPagesIntact(t *testing.T) {
+	first := historyPage(aws.String("page-2"), 1, 2)
+	// spare capacity would let an append write the next page into the first one
+	values := make([]iotsitewisetypes.AssetPropertyValue, 2, 8)
+	copy(values, first.SuccessEntries[0].AssetPropertyValueHistory)
+	first.SuccessEntries[0].AssetPropertyValueHistory = values
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken(""), mock.Anything, mock.Anything).Return(first, nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken("page-2"), mock.Anything, mock.Anything).Return(historyPage(nil, 3, 4), nil)
+
+	_, batch, err := api.BatchGetAssetPropertyValues(context.Background(), mockSw, historyQuery(true, 0))
+	require.NoError(t, err)
+
+	assert.Len(t, batch.Responses[0].SuccessEntries[0].AssetPropertyValueHistory, 4)
+	assert.Len(t, first.SuccessEntries[0].AssetPropertyValueHistory, 2)
+	assert.Equal(t, make([]iotsitewisetypes.AssetPropertyValue, 6), values[2:cap(values)])
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
@@ -25,13 +26,7 @@ func aggregateQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.Ge
 		}
 	}
 
-	qualities := make([]iotsitewisetypes.Quality, 1)
-
-	if query.Quality == "" || query.Quality == "ANY" {
-		qualities[0] = iotsitewisetypes.QualityGood
-	} else {
-		qualities[0] = query.Quality
-	}
+	qualities := query.RequestQualities()
 
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
@@ -61,6 +56,15 @@ func aggregateQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.Ge
 
 func GetAssetPropertyAggregates(ctx context.Context, sw client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyAggregates, error) {
+	if query.FiltersQualities() {
+		return models.AssetPropertyValueQuery{}, nil, errAggregateQualities
+	}
+	if len(query.SyntheticAggregateTypes()) > 0 {
+		return models.AssetPropertyValueQuery{}, nil, fmt.Errorf("synthetic aggregates are not supported by the SiteWise Edge API")
+	}
+	if _, rollup, err := propvals.Rollup(query); err != nil || rollup != nil {
+		return models.AssetPropertyValueQuery{}, nil, fmt.Errorf("resolution %s is not supported by the SiteWise Edge API", query.Resolution)
+	}
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, sw, ctx)
 	if err != nil {
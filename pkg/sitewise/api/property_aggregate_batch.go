@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,9 +15,11 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
-// `query.MaxDataPoints` is ignored and it always requests with the maximum number of data points the SiteWise API can support
-func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.BatchGetAssetPropertyAggregatesInput {
+// errAggregateQualities is returned for a selection of qualities the aggregates can not be filtered by
+var errAggregateQualities = errors.New("aggregates support a single quality or ANY")
 
+// aggregateResolution resolves the AUTO resolution of the query
+func aggregateResolution(query models.AssetPropertyValueQuery) string {
 	resolution := query.Resolution
 	if resolution == "AUTO" {
 		resolution = propvals.Resolution(query.BaseQuery)
@@ -25,13 +28,15 @@ func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewi
 			resolution = propvals.ResolutionMinute
 		}
 	}
+	return resolution
+}
 
-	qualities := make([]iotsitewisetypes.Quality, 1)
-	if query.Quality == "" || query.Quality == "ANY" {
-		qualities[0] = iotsitewisetypes.QualityGood
-	} else {
-		qualities[0] = query.Quality
-	}
+// `query.MaxDataPoints` is ignored and it always requests with the maximum number of data points the SiteWise API can support
+func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.BatchGetAssetPropertyAggregatesInput {
+
+	resolution := aggregateResolution(query)
+
+	qualities := query.RequestQualities()
 
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
@@ -76,28 +81,72 @@ func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewi
 func BatchGetAssetPropertyAggregates(ctx context.Context, client client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyAggregatesBatch, error) {
 	maxDps := int(query.MaxDataPoints)
+	if query.FiltersQualities() {
+		return models.AssetPropertyValueQuery{}, nil, errAggregateQualities
+	}
+
+	native, rollup, err := aggregateRollup(query)
+	if err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
+	}
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	batchedQueries := batchQueries(modifiedQuery, BatchGetAssetPropertyAggregatesMaxEntries)
+	// A derived resolution requests the native aggregates it is re-aggregated from, and follows
+	// the next tokens so that the buckets at the page boundaries are complete
+	requestQuery := modifiedQuery
+	requestQuery.AggregateTypes = query.NativeAggregateTypes()
+	if rollup != nil {
+		modifiedQuery.AutoPaginate = true
+		requestQuery.AutoPaginate = true
+		requestQuery.Resolution = native
+		requestQuery.AggregateTypes = rollupAggregateTypes(requestQuery.AggregateTypes)
+	}
+
+	// the native aggregates are not requested when the query only has synthetic aggregates
+	batchedQueries := []models.AssetPropertyValueQuery{}
+	if len(requestQuery.AggregateTypes) > 0 || len(query.SyntheticAggregateTypes()) == 0 {
+		batchedQueries = batchQueries(requestQuery, BatchGetAssetPropertyAggregatesMaxEntries)
+	}
 	requests := []iotsitewise.BatchGetAssetPropertyAggregatesInput{}
 	responses := []iotsitewise.BatchGetAssetPropertyAggregatesOutput{}
+	budget := newPaginationBudget(requestQuery)
 	for _, q := range batchedQueries {
 		awsReq := aggregateBatchQueryToInput(q)
 		requests = append(requests, *awsReq)
-		resp, err := client.BatchGetAssetPropertyAggregatesPageAggregation(ctx, awsReq, modifiedQuery.MaxPageAggregations, maxDps)
+		resp, err := client.BatchGetAssetPropertyAggregatesPageAggregation(ctx, awsReq, requestQuery.MaxPageAggregations, maxDps)
 		if err != nil {
 			return models.AssetPropertyValueQuery{}, nil, err
 		}
+		if requestQuery.AutoPaginate {
+			resp, err = paginateAggregates(ctx, client, awsReq, resp, budget)
+			if err != nil {
+				return models.AssetPropertyValueQuery{}, nil, err
+			}
+		}
 		responses = append(responses, *resp)
 	}
 
-	return modifiedQuery,
-		&framer.AssetPropertyAggregatesBatch{
-			Requests:  requests,
-			Responses: responses,
-		}, nil
+	aggregates := &framer.AssetPropertyAggregatesBatch{
+		Requests:  requests,
+		Responses: responses,
+		Query:     modifiedQuery,
+		Rollup:    rollup,
+	}
+	if len(query.SyntheticAggregateTypes()) > 0 {
+		aggregates.RawValues, aggregates.RawTruncation, err = getRawValues(ctx, client, modifiedQuery)
+		if err != nil {
+			return models.AssetPropertyValueQuery{}, nil, err
+		}
+		aggregates.RawResolution, aggregates.RawBuckets = rawRollup(modifiedQuery, rollup)
+		aggregates.RawGrid = rollupGrid(modifiedQuery, aggregates.RawBuckets)
+		if query.TimeWeighted() {
+			aggregates.RawLastObservations = getLastObservations(ctx, client, modifiedQuery)
+		}
+	}
+
+	return modifiedQuery, aggregates, nil
 }
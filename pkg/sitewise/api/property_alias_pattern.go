@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// getPropertyAliasPatternEntries expands the property alias pattern of the query to the data streams
+// listed with the literal prefix of the pattern. It returns true when more streams than the cap matched.
+func getPropertyAliasPatternEntries(ctx context.Context, sw client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]models.AssetPropertyEntry, bool, error) {
+	pattern, prefix, err := compilePropertyAliasPattern(query.PropertyAliasPattern, query.PropertyAliasRegex)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var aliasPrefix *string
+	if prefix != "" {
+		aliasPrefix = aws.String(prefix)
+	}
+
+	maxMatches := query.PropertyAliasMatches()
+	entries := []models.AssetPropertyEntry{}
+	var nextToken *string
+	for {
+		resp, err := sw.ListTimeSeries(ctx, &iotsitewise.ListTimeSeriesInput{
+			AliasPrefix: aliasPrefix,
+			MaxResults:  MaxSitewiseResults,
+			NextToken:   nextToken,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, ts := range resp.TimeSeriesSummaries {
+			alias := util.Dereference(ts.Alias)
+			if alias == "" || !pattern.MatchString(alias) {
+				continue
+			}
+			if len(entries) == maxMatches {
+				return entries, true, nil
+			}
+
+			entry := models.AssetPropertyEntry{PropertyAlias: alias}
+			if ts.AssetId != nil && ts.PropertyId != nil {
+				entry.AssetId = *ts.AssetId
+				entry.PropertyId = *ts.PropertyId
+			}
+			entries = append(entries, entry)
+		}
+
+		if resp.NextToken == nil {
+			return entries, false, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+// compilePropertyAliasPattern compiles the glob or regular expression, and returns the literal prefix
+// every matching alias starts with
+func compilePropertyAliasPattern(pattern string, isRegex bool) (*regexp.Regexp, string, error) {
+	if !isRegex {
+		prefix, _, _ := strings.Cut(pattern, "*")
+		prefix, _, _ = strings.Cut(prefix, "?")
+		return util.GlobToRegexp(pattern), prefix, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid property alias pattern: %w", err)
+	}
+
+	return re, regexpLiteralPrefix(pattern), nil
+}
+
+// regexpLiteralPrefix returns the literal following the start of text anchor of the regular expression.
+// Unanchored expressions can match anywhere in the alias and have no prefix.
+func regexpLiteralPrefix(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat || len(re.Sub) == 0 || re.Sub[0].Op != syntax.OpBeginText {
+		return ""
+	}
+
+	var prefix strings.Builder
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		prefix.WriteString(string(sub.Rune))
+	}
+	return prefix.String()
+}
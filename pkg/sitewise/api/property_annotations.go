@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+// GetPropertyAnnotations fetches the complete history of the time range in ascending order,
+// annotation regions are built from the transitions between values. The last value before the
+// time range is the value in effect at its start.
+func GetPropertyAnnotations(ctx context.Context, sw client.SitewiseAPIClient,
+	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyAnnotations, error) {
+	query.AutoPaginate = true
+	query.TimeOrdering = iotsitewisetypes.TimeOrderingAscending
+	query.NextToken = ""
+	query.NextTokens = nil
+
+	modifiedQuery, history, err := BatchGetAssetPropertyValues(ctx, sw, query)
+	if err != nil {
+		return modifiedQuery, nil, err
+	}
+
+	return modifiedQuery, &framer.AssetPropertyAnnotations{
+		History:          history,
+		LastObservations: getLastObservations(ctx, sw, modifiedQuery),
+	}, nil
+}
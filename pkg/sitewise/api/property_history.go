@@ -5,7 +5,6 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
-	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
@@ -18,11 +17,6 @@ import (
 // If an invalid combo of assetId/propertyId/propertyAlias are sent to the API, an exception will be returned.
 // The Framer consumer should bubble up that error to the user.
 func historyQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.GetAssetPropertyValueHistoryInput {
-	quality := query.Quality
-	if quality == "" || quality == "ANY" {
-		quality = iotsitewisetypes.QualityGood
-	}
-
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
 	if query.MaxDataPoints < 1 || query.MaxDataPoints > 20000 {
@@ -38,7 +32,7 @@ func historyQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.GetA
 		PropertyId:    getFirstPropertyId(query.BaseQuery),
 		PropertyAlias: getFirstPropertyAlias(query.BaseQuery),
 		TimeOrdering:  query.TimeOrdering,
-		Qualities:     []iotsitewisetypes.Quality{quality},
+		Qualities:     query.RequestQualities(),
 	}
 }
 
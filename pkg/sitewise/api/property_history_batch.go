@@ -19,10 +19,7 @@ import (
 // The Framer consumer should bubble up that error to the user.
 // `query.MaxDataPoints` is ignored and it always requests with the maximum number of data points the SiteWise API can support
 func historyBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.BatchGetAssetPropertyValueHistoryInput {
-	qualities := []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
-	if query.Quality != "" && query.Quality != "ANY" {
-		qualities[0] = query.Quality
-	}
+	qualities := query.RequestQualities()
 
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
@@ -68,12 +65,19 @@ func BatchGetAssetPropertyValues(ctx context.Context, client client.SitewiseAPIC
 
 	batchedQueries := batchQueries(modifiedQuery, BatchGetAssetPropertyValueHistoryMaxEntries)
 	responses := []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+	budget := newPaginationBudget(query)
 	for _, q := range batchedQueries {
 		awsReq := historyBatchQueryToInput(q)
 		resp, err := client.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, awsReq, query.MaxPageAggregations, maxDps)
 		if err != nil {
 			return models.AssetPropertyValueQuery{}, nil, err
 		}
+		if query.AutoPaginate {
+			resp, err = paginateHistory(ctx, client, awsReq, resp, budget)
+			if err != nil {
+				return models.AssetPropertyValueQuery{}, nil, err
+			}
+		}
 		responses = append(responses, resp)
 	}
 
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -21,23 +23,49 @@ var (
 	LINEAR_INTERPOLATION string = "LINEAR_INTERPOLATION"
 )
 
+// MaxIntervalWindowInSeconds is the largest interval window accepted by GetInterpolatedAssetPropertyValues
+const MaxIntervalWindowInSeconds = 320000000
+
+// validateInterpolation checks the interpolation type and interval window against the SiteWise constraints
+func validateInterpolation(query models.AssetPropertyValueQuery) error {
+	switch query.InterpolationType {
+	case "", LINEAR_INTERPOLATION, LOCF_INTERPOLATION:
+	default:
+		return fmt.Errorf("invalid interpolation type %s, expected %s or %s", query.InterpolationType, LINEAR_INTERPOLATION, LOCF_INTERPOLATION)
+	}
+
+	if query.IntervalWindowInSeconds == 0 {
+		return nil
+	}
+	if query.InterpolationType == LOCF_INTERPOLATION {
+		return fmt.Errorf("the interval window is only supported by %s", LINEAR_INTERPOLATION)
+	}
+	if query.IntervalWindowInSeconds < 1 || query.IntervalWindowInSeconds > MaxIntervalWindowInSeconds {
+		return fmt.Errorf("the interval window must be between 1 and %d seconds", MaxIntervalWindowInSeconds)
+	}
+	return nil
+}
+
 type responseWrapper struct {
 	DataResponse *iotsitewise.GetInterpolatedAssetPropertyValuesOutput
 	EntryId      string
 }
 
-func interpolatedQueryToInputs(query models.AssetPropertyValueQuery) []*iotsitewise.GetInterpolatedAssetPropertyValuesInput {
+func interpolatedQueryToInputs(query models.AssetPropertyValueQuery, quality types.Quality) []*iotsitewise.GetInterpolatedAssetPropertyValuesInput {
 
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 	startTimeInSeconds := from.Unix()
 	endTimeInSeconds := to.Unix()
 
-	quality := query.Quality
-	if quality == "" || quality == "ANY" {
-		quality = types.QualityGood
+	interpolationType := LINEAR_INTERPOLATION
+	if query.InterpolationType != "" {
+		interpolationType = query.InterpolationType
 	}
 
-	interpolationType := LINEAR_INTERPOLATION
+	var intervalWindowInSeconds *int64
+	if query.IntervalWindowInSeconds > 0 {
+		intervalWindowInSeconds = aws.Int64(query.IntervalWindowInSeconds)
+	}
 
 	intervalInSeconds := int64(propvals.ResolutionToDuration(propvals.InterpolatedResolution(query)).Seconds())
 	if query.Resolution != "AUTO" && query.Resolution != "" {
@@ -58,12 +86,13 @@ func interpolatedQueryToInputs(query models.AssetPropertyValueQuery) []*iotsitew
 	// separate GetInterpolatedAssetPropertyValues requests
 	for _, entry := range query.AssetPropertyEntries {
 		interpolatedInput := iotsitewise.GetInterpolatedAssetPropertyValuesInput{
-			StartTimeInSeconds: &startTimeInSeconds,
-			EndTimeInSeconds:   &endTimeInSeconds,
-			IntervalInSeconds:  aws.Int64(intervalInSeconds),
-			MaxResults:         aws.Int32(10),
-			Quality:            quality,
-			Type:               &interpolationType,
+			StartTimeInSeconds:      &startTimeInSeconds,
+			EndTimeInSeconds:        &endTimeInSeconds,
+			IntervalInSeconds:       aws.Int64(intervalInSeconds),
+			MaxResults:              aws.Int32(10),
+			Quality:                 quality,
+			Type:                    &interpolationType,
+			IntervalWindowInSeconds: intervalWindowInSeconds,
 		}
 		var entryId *string
 		if entry.AssetId != "" && entry.PropertyId != "" {
@@ -87,16 +116,43 @@ func interpolatedQueryToInputs(query models.AssetPropertyValueQuery) []*iotsitew
 	return awsReqs
 }
 
+// GetInterpolatedAssetPropertyValues interpolates the values of each selected quality,
+// a request accepts a single quality so several qualities are framed as separate series
 func GetInterpolatedAssetPropertyValues(ctx context.Context, client client.SitewiseAPIClient,
-	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.InterpolatedAssetPropertyValue, error) {
-	maxDps := int(query.MaxDataPoints)
+	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, framer.InterpolatedAssetPropertyValueQualities, error) {
+	if err := validateInterpolation(query); err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
+	}
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	awsReqs := interpolatedQueryToInputs(modifiedQuery)
+	qualities := modifiedQuery.SelectedQualities()
+	series := framer.InterpolatedAssetPropertyValueQualities{}
+	for _, quality := range qualities {
+		responses, err := getInterpolatedValues(ctx, client, modifiedQuery, quality)
+		if err != nil {
+			return models.AssetPropertyValueQuery{}, nil, err
+		}
+		interpolated := framer.InterpolatedAssetPropertyValue{
+			Responses: responses,
+			Query:     modifiedQuery,
+		}
+		if len(qualities) > 1 {
+			interpolated.Quality = quality
+		}
+		series = append(series, interpolated)
+	}
+	return modifiedQuery, series, nil
+}
+
+func getInterpolatedValues(ctx context.Context, client client.SitewiseAPIClient,
+	query models.AssetPropertyValueQuery, quality types.Quality) (map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, error) {
+	maxDps := int(query.MaxDataPoints)
+
+	awsReqs := interpolatedQueryToInputs(query, quality)
 
 	resultChan := make(chan *responseWrapper, len(awsReqs))
 	eg, ectx := errgroup.WithContext(ctx)
@@ -122,10 +178,10 @@ func GetInterpolatedAssetPropertyValues(ctx context.Context, client client.Sitew
 		})
 	}
 
-	err = eg.Wait()
+	err := eg.Wait()
 	close(resultChan)
 	if err != nil {
-		return models.AssetPropertyValueQuery{}, nil, err
+		return nil, err
 	}
 
 	responses := make(map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, len(awsReqs))
@@ -133,9 +189,5 @@ func GetInterpolatedAssetPropertyValues(ctx context.Context, client client.Sitew
 		responses[result.EntryId] = result.DataResponse
 	}
 
-	return modifiedQuery,
-		&framer.InterpolatedAssetPropertyValue{
-			Responses: responses,
-			Query:     modifiedQuery,
-		}, nil
+	return responses, nil
 }
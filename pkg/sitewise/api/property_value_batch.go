@@ -68,6 +68,7 @@ func BatchGetAssetPropertyValue(ctx context.Context, client client.SitewiseAPICl
 			Responses:       responses,
 			AnomalyAssetIds: anomalyAssetIds,
 			SitewiseClient:  client,
+			Query:           modifiedQuery,
 		},
 		nil
 }
@@ -12,7 +12,8 @@ import (
 func BatchGetAssetPropertyValuesForTimeRange(ctx context.Context, sw client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValuesForTimeRangeBatch, error) {
 
-	if query.Resolution == "AUTO" {
+	// the synthetic aggregates are computed in the buckets of the aggregates
+	if query.Resolution == "AUTO" && len(query.SyntheticAggregateTypes()) == 0 {
 		resolution := propvals.Resolution(query.BaseQuery)
 
 		// todo: remove propvals.ResolutionSecond condition once 1s aggregation is supported
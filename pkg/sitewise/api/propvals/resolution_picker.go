@@ -1,7 +1,10 @@
 package propvals
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -24,6 +27,7 @@ const (
 	ResolutionHour           = "1h"
 	ResolutionTenHours       = "10h"
 	ResolutionDay            = "1d"
+	ResolutionWeek           = "1w"
 )
 
 func roundUp(num float64) int64 {
@@ -108,3 +112,140 @@ func ResolutionToDuration(resolution string) time.Duration {
 		return 24 * time.Hour
 	}
 }
+
+// nativeAggregateResolutions are the aggregate resolutions supported by SiteWise, from the finest
+var nativeAggregateResolutions = []string{ResolutionMinute, ResolutionFifteenMinutes, ResolutionHour, ResolutionDay}
+
+// RollupResolution maps a resolution SiteWise does not aggregate to, such as 5m or 8h, to the
+// coarsest native resolution it can be re-aggregated from and the duration of its buckets.
+// The duration is zero for AUTO and the resolutions supported by SiteWise.
+func RollupResolution(resolution string) (string, time.Duration, error) {
+	switch resolution {
+	case "", "AUTO", ResolutionRaw, ResolutionSecond, ResolutionTenSeconds, ResolutionMinute,
+		ResolutionTenMinutes, ResolutionFifteenMinutes, ResolutionHour, ResolutionTenHours, ResolutionDay:
+		return resolution, 0, nil
+	}
+
+	bucket, err := parseResolution(resolution)
+	if err != nil {
+		return "", 0, err
+	}
+
+	native := ""
+	for _, r := range nativeAggregateResolutions {
+		if d := ResolutionToDuration(r); d < bucket && bucket%d == 0 {
+			native = r
+		}
+	}
+	if native == "" {
+		return "", 0, fmt.Errorf("invalid resolution %s, expected a multiple of %s", resolution, ResolutionMinute)
+	}
+	return native, bucket, nil
+}
+
+// parseResolution parses a Go duration, with additional d and w units for days and weeks
+func parseResolution(resolution string) (time.Duration, error) {
+	for unit, d := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(resolution, unit); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 1 {
+				return 0, fmt.Errorf("invalid resolution %s", resolution)
+			}
+			return time.Duration(count) * d, nil
+		}
+	}
+	d, err := time.ParseDuration(resolution)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid resolution %s", resolution)
+	}
+	return d, nil
+}
+
+// Bucket returns the start and the end of the bucket of a time
+type Bucket func(time.Time) (time.Time, time.Time)
+
+// Rollup returns the native resolution the aggregates of the query are requested at, and the
+// bucket each native aggregate is re-aggregated into. The bucket is nil when the native
+// aggregates are returned as they are.
+func Rollup(query models.AssetPropertyValueQuery) (string, Bucket, error) {
+	if query.Timezone != "" || query.DayStartOffset != "" {
+		switch query.Resolution {
+		case ResolutionDay, ResolutionWeek, "7d":
+			return CalendarRollup(query)
+		}
+	}
+
+	native, bucket, err := RollupResolution(query.Resolution)
+	if err != nil || bucket == 0 {
+		return native, nil, err
+	}
+	return native, TruncateBucket(bucket), nil
+}
+
+// TruncateBucket returns buckets of the duration, durations dividing a day are aligned
+// to UTC midnight and weeks start on Monday
+func TruncateBucket(bucket time.Duration) Bucket {
+	return func(t time.Time) (time.Time, time.Time) {
+		start := t.UTC().Truncate(bucket)
+		return start, start.Add(bucket)
+	}
+}
+
+// CalendarRollup buckets the day or week aggregates of the query by the local production days,
+// which start at the day start offset in the wall-clock time of the timezone. Days are 23 or 25
+// hours long across DST transitions, and weeks start on Monday.
+func CalendarRollup(query models.AssetPropertyValueQuery) (string, Bucket, error) {
+	location, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid timezone %s", query.Timezone)
+	}
+
+	var offset time.Duration
+	if query.DayStartOffset != "" {
+		offset, err = time.ParseDuration(query.DayStartOffset)
+		if err != nil || offset < 0 || offset >= 24*time.Hour {
+			return "", nil, fmt.Errorf("invalid day start offset %s, expected a duration between 0 and 24h", query.DayStartOffset)
+		}
+	}
+
+	// hourly aggregates can not be split at the start of a day in a zone with a half-hour offset
+	native := ResolutionHour
+	for _, t := range []time.Time{query.TimeRange.From, query.TimeRange.To} {
+		_, zoneOffset := t.In(location).Zone()
+		if time.Duration(zoneOffset)*time.Second%time.Hour != 0 {
+			native = ResolutionFifteenMinutes
+		}
+	}
+	if offset%time.Hour != 0 {
+		native = ResolutionFifteenMinutes
+	}
+	if offset%(15*time.Minute) != 0 {
+		native = ResolutionMinute
+	}
+
+	dayStart := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, int(offset.Seconds()), 0, location)
+	}
+	start := func(t time.Time) time.Time {
+		local := t.In(location)
+		start := dayStart(local.Date())
+		if t.Before(start) {
+			start = dayStart(local.AddDate(0, 0, -1).Date())
+		}
+		return start
+	}
+	if query.Resolution == ResolutionDay {
+		return native, func(t time.Time) (time.Time, time.Time) {
+			day := start(t)
+			year, month, date := day.Date()
+			return day, dayStart(year, month, date+1)
+		}, nil
+	}
+
+	return native, func(t time.Time) (time.Time, time.Time) {
+		day := start(t)
+		year, month, date := day.Date()
+		monday := date - (int(day.Weekday())+6)%7
+		return dayStart(year, month, monday), dayStart(year, month, monday+7)
+	}, nil
+}
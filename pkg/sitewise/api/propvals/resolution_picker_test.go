@@ -2,6 +2,7 @@ package propvals
 
 import (
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
@@ -81,3 +82,137 @@ func TestResolution(t *testing.T) {
 		})
 	}
 }
+
+func TestRollupResolution(t *testing.T) {
+	for _, scene := range []struct {
+		resolution string
+		native     string
+		bucket     time.Duration
+		err        bool
+	}{
+		{resolution: "AUTO", native: "AUTO"},
+		{resolution: ResolutionFifteenMinutes, native: ResolutionFifteenMinutes},
+		{resolution: "5m", native: ResolutionMinute, bucket: 5 * time.Minute},
+		{resolution: "30m", native: ResolutionFifteenMinutes, bucket: 30 * time.Minute},
+		{resolution: "4h", native: ResolutionHour, bucket: 4 * time.Hour},
+		{resolution: "8h", native: ResolutionHour, bucket: 8 * time.Hour},
+		{resolution: "7d", native: ResolutionDay, bucket: 7 * 24 * time.Hour},
+		{resolution: "90s", err: true},
+		{resolution: "5x", err: true},
+	} {
+		t.Run(scene.resolution, func(t *testing.T) {
+			native, bucket, err := RollupResolution(scene.resolution)
+			if scene.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scene.native, native)
+			assert.Equal(t, scene.bucket, bucket)
+		})
+	}
+}
+
+func TestCalendarRollup(t *testing.T) {
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2021, month, day, hour, min, 0, 0, time.UTC)
+	}
+	year := backend.TimeRange{From: utc(time.January, 1, 0, 0), To: utc(time.December, 31, 0, 0)}
+	for _, scene := range []struct {
+		name     string
+		query    models.AssetPropertyValueQuery
+		native   string
+		buckets  map[time.Time]time.Time
+		errorMsg string
+	}{
+		{
+			name:   "production day before the spring forward is 23 hours long",
+			query:  models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, Timezone: "America/New_York", DayStartOffset: "6h"},
+			native: ResolutionHour,
+			buckets: map[time.Time]time.Time{
+				utc(time.March, 13, 11, 0): utc(time.March, 13, 11, 0), // 06:00 EST
+				utc(time.March, 14, 9, 30): utc(time.March, 13, 11, 0), // 05:30 EDT
+				utc(time.March, 14, 10, 0): utc(time.March, 14, 10, 0), // 06:00 EDT
+			},
+		},
+		{
+			name:   "production day before the fall back is 25 hours long",
+			query:  models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, Timezone: "America/New_York", DayStartOffset: "6h"},
+			native: ResolutionHour,
+			buckets: map[time.Time]time.Time{
+				utc(time.November, 6, 10, 0):  utc(time.November, 6, 10, 0), // 06:00 EDT
+				utc(time.November, 7, 10, 30): utc(time.November, 6, 10, 0), // 05:30 EST
+				utc(time.November, 7, 11, 0):  utc(time.November, 7, 11, 0), // 06:00 EST
+			},
+		},
+		{
+			name:   "local midnight across the spring forward",
+			query:  models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, Timezone: "Europe/Berlin"},
+			native: ResolutionHour,
+			buckets: map[time.Time]time.Time{
+				utc(time.March, 27, 22, 59): utc(time.March, 26, 23, 0), // 23:59 CET
+				utc(time.March, 27, 23, 0):  utc(time.March, 27, 23, 0), // 00:00 CET
+				utc(time.March, 28, 21, 59): utc(time.March, 27, 23, 0), // 23:59 CEST
+				utc(time.March, 28, 22, 0):  utc(time.March, 28, 22, 0), // 00:00 CEST
+			},
+		},
+		{
+			name:   "weeks start on Monday across the spring forward",
+			query:  models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionWeek, Timezone: "America/New_York"},
+			native: ResolutionHour,
+			buckets: map[time.Time]time.Time{
+				utc(time.March, 14, 12, 0): utc(time.March, 8, 5, 0), // Sunday, Monday 00:00 EST
+				utc(time.March, 15, 3, 59): utc(time.March, 8, 5, 0),
+				utc(time.March, 15, 4, 0):  utc(time.March, 15, 4, 0), // Monday 00:00 EDT
+			},
+		},
+		{
+			name:   "half-hour timezone is built from 15m aggregates",
+			query:  models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, Timezone: "Asia/Kolkata", DayStartOffset: "6h"},
+			native: ResolutionFifteenMinutes,
+			buckets: map[time.Time]time.Time{
+				utc(time.February, 1, 0, 15): utc(time.January, 31, 0, 30), // 05:45 IST
+				utc(time.February, 1, 0, 30): utc(time.February, 1, 0, 30), // 06:00 IST
+			},
+		},
+		{
+			name:     "invalid timezone",
+			query:    models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, Timezone: "Mars/Olympus_Mons"},
+			errorMsg: "invalid timezone Mars/Olympus_Mons",
+		},
+		{
+			name:     "invalid day start offset",
+			query:    models.AssetPropertyValueQuery{BaseQuery: models.BaseQuery{TimeRange: year}, Resolution: ResolutionDay, DayStartOffset: "25h"},
+			errorMsg: "invalid day start offset 25h, expected a duration between 0 and 24h",
+		},
+	} {
+		t.Run(scene.name, func(t *testing.T) {
+			native, bucket, err := Rollup(scene.query)
+			if scene.errorMsg != "" {
+				assert.EqualError(t, err, scene.errorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scene.native, native)
+			for ts, expected := range scene.buckets {
+				start, _ := bucket(ts)
+				assert.True(t, expected.Equal(start), "bucket of %s: expected %s, got %s", ts, expected, start.UTC())
+			}
+		})
+	}
+}
+
+func TestCalendarRollupBucketLength(t *testing.T) {
+	query := models.AssetPropertyValueQuery{Resolution: ResolutionDay, Timezone: "America/New_York", DayStartOffset: "6h"}
+	_, bucket, err := CalendarRollup(query)
+	assert.NoError(t, err)
+
+	for ts, expected := range map[time.Time]time.Duration{
+		time.Date(2021, 3, 13, 12, 0, 0, 0, time.UTC): 23 * time.Hour,
+		time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC):  24 * time.Hour,
+		time.Date(2021, 11, 6, 12, 0, 0, 0, time.UTC): 25 * time.Hour,
+	} {
+		start, end := bucket(ts)
+		assert.Equal(t, expected, end.Sub(start), "bucket of %s", ts)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
+)
+
+var errNoShiftCalendar = errors.New("the SHIFT resolution requires a shift calendar in the query or the datasource settings")
+
+// aggregateRollup returns the native resolution of the aggregates of the query, and the rollup
+// of the native aggregates into the query resolution, which is nil for a native resolution
+func aggregateRollup(query models.AssetPropertyValueQuery) (string, framer.Rollup, error) {
+	if query.Resolution == models.ResolutionShift {
+		return shiftRollup(query)
+	}
+
+	native, bucket, err := propvals.Rollup(query)
+	if err != nil || bucket == nil {
+		return native, nil, err
+	}
+	return native, bucketRollup(bucket), nil
+}
+
+func bucketRollup(bucket propvals.Bucket) framer.Rollup {
+	return func(t time.Time) (framer.RollupBucket, bool) {
+		start, end := bucket(t)
+		return framer.RollupBucket{Start: start, End: end}, true
+	}
+}
+
+// shiftRollup buckets the native aggregates by the shift occurrences of the time range. The
+// native resolution is the coarsest one the starts and ends of the occurrences are aligned to.
+func shiftRollup(query models.AssetPropertyValueQuery) (string, framer.Rollup, error) {
+	if query.ShiftCalendar == nil {
+		return "", nil, errNoShiftCalendar
+	}
+	occurrences, err := query.ShiftCalendar.Occurrences(query.TimeRange)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid shift calendar: %w", err)
+	}
+
+	native := propvals.ResolutionHour
+	for _, o := range occurrences {
+		for _, t := range []time.Time{o.Start, o.End} {
+			if t.Unix()%int64(time.Hour.Seconds()) != 0 && native == propvals.ResolutionHour {
+				native = propvals.ResolutionFifteenMinutes
+			}
+			if t.Unix()%int64((15*time.Minute).Seconds()) != 0 {
+				native = propvals.ResolutionMinute
+			}
+		}
+	}
+
+	return native, func(t time.Time) (framer.RollupBucket, bool) {
+		idx := slices.IndexFunc(occurrences, func(o models.ShiftOccurrence) bool {
+			return !t.Before(o.Start) && t.Before(o.End)
+		})
+		if idx < 0 {
+			return framer.RollupBucket{}, false
+		}
+		return framer.RollupBucket{Start: occurrences[idx].Start, End: occurrences[idx].End, Shift: occurrences[idx].Name}, true
+	}, nil
+}
+
+// rollupGrid returns every bucket of the rollup in the time range of the query, in ascending order
+func rollupGrid(query models.AssetPropertyValueQuery, rollup framer.Rollup) []framer.RollupBucket {
+	grid := []framer.RollupBucket{}
+	if query.Resolution == models.ResolutionShift && query.ShiftCalendar != nil {
+		occurrences, err := query.ShiftCalendar.Occurrences(query.TimeRange)
+		if err != nil {
+			return grid
+		}
+		for _, o := range occurrences {
+			grid = append(grid, framer.RollupBucket{Start: o.Start, End: o.End, Shift: o.Name})
+		}
+		return grid
+	}
+
+	for t := query.TimeRange.From; t.Before(query.TimeRange.To); {
+		bucket, ok := rollup(t)
+		if !ok || !bucket.End.After(t) {
+			break
+		}
+		grid = append(grid, bucket)
+		t = bucket.End
+	}
+	return grid
+}
+
+// rollupAggregateTypes adds the aggregate types needed to re-aggregate the averages and standard deviations
+func rollupAggregateTypes(aggregateTypes []iotsitewisetypes.AggregateType) []iotsitewisetypes.AggregateType {
+	types := slices.Clone(aggregateTypes)
+	if slices.Contains(types, iotsitewisetypes.AggregateTypeStandardDeviation) && !slices.Contains(types, iotsitewisetypes.AggregateTypeAverage) {
+		types = append(types, iotsitewisetypes.AggregateTypeAverage)
+	}
+	if slices.Contains(types, iotsitewisetypes.AggregateTypeAverage) && !slices.Contains(types, iotsitewisetypes.AggregateTypeCount) {
+		types = append(types, iotsitewisetypes.AggregateTypeCount)
+	}
+	return types
+}
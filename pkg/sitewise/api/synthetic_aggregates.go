@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// getRawValues fetches the raw values the synthetic aggregates are computed from, in ascending order and
+// up to the raw data points of the query. It returns why the raw values of the time range were truncated,
+// empty when they are complete. The entries of the batches left once the budget is exhausted have no values.
+func getRawValues(ctx context.Context, sw client.SitewiseAPIClient,
+	query models.AssetPropertyValueQuery) ([]*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, string, error) {
+	rawQuery := query
+	rawQuery.TimeOrdering = iotsitewisetypes.TimeOrderingAscending
+	// the next tokens of the query belong to the aggregates
+	rawQuery.NextToken = ""
+	rawQuery.NextTokens = nil
+
+	budget := &paginationBudget{
+		deadline:  time.Now().Add(query.AutoPaginateTimeout()),
+		remaining: query.RawDataPoints(),
+	}
+	responses := []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+	truncated := false
+	for _, q := range batchQueries(rawQuery, BatchGetAssetPropertyValueHistoryMaxEntries) {
+		awsReq := historyBatchQueryToInput(q)
+		if budget.exhausted() {
+			truncated = true
+			responses = append(responses, emptyHistoryBatch(awsReq))
+			continue
+		}
+		resp, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, awsReq, 1, budget.remaining)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err = paginateHistory(ctx, sw, awsReq, resp, budget)
+		if err != nil {
+			return nil, "", err
+		}
+		if util.Dereference(resp.NextToken) != "" {
+			truncated = true
+		}
+		responses = append(responses, resp)
+	}
+
+	switch {
+	case !truncated:
+		return responses, "", nil
+	case budget.remaining <= 0:
+		return responses, framer.RawTruncatedDataPoints, nil
+	default:
+		return responses, framer.RawTruncatedTimeout, nil
+	}
+}
+
+// emptyHistoryBatch returns a response without values for every entry of the request
+func emptyHistoryBatch(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+	resp := &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+	for _, entry := range req.Entries {
+		resp.SuccessEntries = append(resp.SuccessEntries, iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{EntryId: entry.EntryId})
+	}
+	return resp
+}
+
+// getLastObservations looks up the last raw value before the time range of each entry, the time
+// weighted aggregates hold it from the start of the first bucket. An entry without a value in the
+// lookback window, or whose lookup failed, starts at its first raw value instead.
+func getLastObservations(ctx context.Context, sw client.SitewiseAPIClient,
+	query models.AssetPropertyValueQuery) map[string]iotsitewisetypes.AssetPropertyValue {
+	from, _ := util.TimeRangeToUnix(query.TimeRange)
+
+	var mu sync.Mutex
+	observations := map[string]iotsitewisetypes.AssetPropertyValue{}
+	eg, ectx := errgroup.WithContext(ctx)
+	for _, entry := range query.AssetPropertyEntries {
+		input := &iotsitewise.GetAssetPropertyValueHistoryInput{
+			StartDate:    aws.Time(from.Add(-models.LastObservationLookback)),
+			EndDate:      aws.Time(from.Add(-time.Second)),
+			MaxResults:   aws.Int32(1),
+			Qualities:    query.RequestQualities(),
+			TimeOrdering: iotsitewisetypes.TimeOrderingDescending,
+		}
+		var entryId *string
+		if entry.AssetId != "" && entry.PropertyId != "" {
+			input.AssetId = aws.String(entry.AssetId)
+			input.PropertyId = aws.String(entry.PropertyId)
+			entryId = util.GetEntryIdFromAssetProperty(entry.AssetId, entry.PropertyId)
+		} else {
+			input.PropertyAlias = aws.String(entry.PropertyAlias)
+			entryId = util.GetEntryIdFromPropertyAlias(entry.PropertyAlias)
+		}
+		eg.Go(func() error {
+			resp, err := sw.GetAssetPropertyValueHistory(ectx, input)
+			if err != nil {
+				backend.Logger.FromContext(ctx).Debug("failed to fetch last observation", "entryId", *entryId, "error", err)
+				return nil
+			}
+			if len(resp.AssetPropertyValueHistory) > 0 {
+				mu.Lock()
+				observations[*entryId] = resp.AssetPropertyValueHistory[0]
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	return observations
+}
+
+// rawRollup returns the resolution and the buckets of the raw values of the synthetic aggregates,
+// which are the buckets of the native aggregates of the query
+func rawRollup(query models.AssetPropertyValueQuery, rollup framer.Rollup) (string, framer.Rollup) {
+	if rollup != nil {
+		return query.Resolution, rollup
+	}
+	resolution := aggregateResolution(query)
+	return resolution, bucketRollup(propvals.TruncateBucket(propvals.ResolutionToDuration(resolution)))
+}
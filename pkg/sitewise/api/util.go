@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"math"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -49,7 +50,29 @@ func getAssetIdAndPropertyId(query models.AssetPropertyValueQuery, client client
 	result.AssetPropertyEntries = []models.AssetPropertyEntry{}
 	// There should only be a list of property aliases OR lists for assetIds and propertyIds
 	// Look up the assetId and propertyId for a property alias
-	if len(query.PropertyAliases) > 0 {
+	if query.AssetModelId != "" {
+		if len(query.AssetIds) > 0 || len(query.PropertyAliases) > 0 {
+			return models.AssetPropertyValueQuery{}, fmt.Errorf("an asset model query cannot be combined with asset ids or property aliases")
+		}
+		entries, err := getAssetModelEntries(ctx, client, query)
+		if err != nil {
+			return models.AssetPropertyValueQuery{}, err
+		}
+		result.AssetPropertyEntries = entries
+	} else if query.PropertyName != "" {
+		entries, err := getHierarchyEntries(ctx, client, query)
+		if err != nil {
+			return models.AssetPropertyValueQuery{}, err
+		}
+		result.AssetPropertyEntries = entries
+	} else if query.PropertyAliasPattern != "" {
+		entries, truncated, err := getPropertyAliasPatternEntries(ctx, client, query)
+		if err != nil {
+			return models.AssetPropertyValueQuery{}, err
+		}
+		result.AssetPropertyEntries = entries
+		result.PropertyAliasMatchesTruncated = truncated
+	} else if len(query.PropertyAliases) > 0 {
 		for _, propertyAlias := range query.PropertyAliases {
 			resp, err := client.DescribeTimeSeries(ctx, &iotsitewise.DescribeTimeSeriesInput{
 				Alias: aws.String(propertyAlias),
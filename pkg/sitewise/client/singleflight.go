@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+// singleflightCalls is published with the plugin metrics, it counts the SiteWise calls of every datasource
+// by whether they were served by an identical call in flight (hit) or sent to SiteWise (miss)
+var singleflightCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "grafana_plugin",
+	Name:      "sitewise_singleflight_calls_total",
+	Help:      "SiteWise calls served by an identical call in flight (hit) or sent to SiteWise (miss)",
+}, []string{"api", "result"})
+
+// Singleflight coalesces identical SiteWise calls issued at the same time, for example by
+// several panels of a dashboard querying the same assets. It is shared by every client of a datasource.
+type Singleflight struct {
+	group  singleflight.Group
+	calls  atomic.Int64
+	misses atomic.Int64
+}
+
+func NewSingleflight() *Singleflight {
+	return &Singleflight{}
+}
+
+// Hits returns the number of calls that were served by a call already in flight
+func (s *Singleflight) Hits() int64 {
+	return s.calls.Load() - s.misses.Load()
+}
+
+// Misses returns the number of calls that were sent to SiteWise
+func (s *Singleflight) Misses() int64 {
+	return s.misses.Load()
+}
+
+// Client decorates a client so that concurrent calls with identical inputs share a single request.
+// The scope separates clients that must not share results, like clients of different regions.
+func (s *Singleflight) Client(sw SitewiseAPIClient, scope string) SitewiseAPIClient {
+	return &SingleflightClient{SitewiseAPIClient: sw, flight: s, scope: scope}
+}
+
+// SingleflightClient is a SitewiseAPIClient sharing in-flight calls with identical inputs.
+// Callers waiting on the same call each receive their own copy of the output and of its slices,
+// so a caller merging pages into its output does not race with the other callers.
+type SingleflightClient struct {
+	SitewiseAPIClient
+	flight *Singleflight
+	scope  string
+}
+
+func coalesce[I any, O any](ctx context.Context, c *SingleflightClient, api string, input *I, call func(context.Context) (*O, error), extra ...any) (*O, error) {
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return call(ctx)
+	}
+	key := fmt.Sprintf("%s|%s|%s|%v", c.scope, api, encoded, extra)
+	c.flight.calls.Add(1)
+
+	// the shared call must not be cancelled when only the first caller goes away
+	sent := false
+	ch := c.flight.group.DoChan(key, func() (any, error) {
+		sent = true
+		c.flight.misses.Add(1)
+		singleflightCalls.WithLabelValues(api, "miss").Inc()
+		return call(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		// the result is received after the call of this caller returned, if it made one
+		if !sent {
+			singleflightCalls.WithLabelValues(api, "hit").Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		shared, ok := res.Val.(*O)
+		if !ok || shared == nil {
+			return nil, nil
+		}
+		out := *shared
+		cloneOutput(&out)
+		return &out, nil
+	}
+}
+
+// cloneOutput replaces the slices of a shallow copied output with copies.
+// The values of the entries are cloned as well, they are what pagination appends onto.
+func cloneOutput(out any) {
+	switch out := out.(type) {
+	case *iotsitewise.BatchGetAssetPropertyValueHistoryOutput:
+		out.SuccessEntries = slices.Clone(out.SuccessEntries)
+		for i := range out.SuccessEntries {
+			out.SuccessEntries[i].AssetPropertyValueHistory = slices.Clone(out.SuccessEntries[i].AssetPropertyValueHistory)
+		}
+		out.SkippedEntries = slices.Clone(out.SkippedEntries)
+		out.ErrorEntries = slices.Clone(out.ErrorEntries)
+	case *iotsitewise.BatchGetAssetPropertyAggregatesOutput:
+		out.SuccessEntries = slices.Clone(out.SuccessEntries)
+		for i := range out.SuccessEntries {
+			out.SuccessEntries[i].AggregatedValues = slices.Clone(out.SuccessEntries[i].AggregatedValues)
+		}
+		out.SkippedEntries = slices.Clone(out.SkippedEntries)
+		out.ErrorEntries = slices.Clone(out.ErrorEntries)
+	case *iotsitewise.BatchGetAssetPropertyValueOutput:
+		out.SuccessEntries = slices.Clone(out.SuccessEntries)
+		out.SkippedEntries = slices.Clone(out.SkippedEntries)
+		out.ErrorEntries = slices.Clone(out.ErrorEntries)
+	case *iotsitewise.GetAssetPropertyValueHistoryOutput:
+		out.AssetPropertyValueHistory = slices.Clone(out.AssetPropertyValueHistory)
+	case *iotsitewise.GetAssetPropertyAggregatesOutput:
+		out.AggregatedValues = slices.Clone(out.AggregatedValues)
+	case *iotsitewise.GetInterpolatedAssetPropertyValuesOutput:
+		out.InterpolatedAssetPropertyValues = slices.Clone(out.InterpolatedAssetPropertyValues)
+	case *iotsitewise.DescribeAssetOutput:
+		out.AssetHierarchies = slices.Clone(out.AssetHierarchies)
+		out.AssetProperties = slices.Clone(out.AssetProperties)
+		out.AssetCompositeModelSummaries = slices.Clone(out.AssetCompositeModelSummaries)
+		out.AssetCompositeModels = slices.Clone(out.AssetCompositeModels)
+	case *iotsitewise.DescribeAssetModelOutput:
+		out.AssetModelHierarchies = slices.Clone(out.AssetModelHierarchies)
+		out.AssetModelProperties = slices.Clone(out.AssetModelProperties)
+		out.AssetModelCompositeModelSummaries = slices.Clone(out.AssetModelCompositeModelSummaries)
+		out.AssetModelCompositeModels = slices.Clone(out.AssetModelCompositeModels)
+		out.InterfaceDetails = slices.Clone(out.InterfaceDetails)
+	case *iotsitewise.ExecuteQueryOutput:
+		out.Columns = slices.Clone(out.Columns)
+		out.Rows = slices.Clone(out.Rows)
+	case *iotsitewise.ListAssetsOutput:
+		out.AssetSummaries = slices.Clone(out.AssetSummaries)
+	case *iotsitewise.ListAssetModelsOutput:
+		out.AssetModelSummaries = slices.Clone(out.AssetModelSummaries)
+	case *iotsitewise.ListAssetPropertiesOutput:
+		out.AssetPropertySummaries = slices.Clone(out.AssetPropertySummaries)
+	case *iotsitewise.ListAssociatedAssetsOutput:
+		out.AssetSummaries = slices.Clone(out.AssetSummaries)
+	case *iotsitewise.ListTimeSeriesOutput:
+		out.TimeSeriesSummaries = slices.Clone(out.TimeSeriesSummaries)
+	}
+}
+
+func (c *SingleflightClient) BatchGetAssetPropertyAggregates(ctx context.Context, params *iotsitewise.BatchGetAssetPropertyAggregatesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.BatchGetAssetPropertyAggregatesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyAggregates(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "BatchGetAssetPropertyAggregates", params, func(ctx context.Context) (*iotsitewise.BatchGetAssetPropertyAggregatesOutput, error) {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyAggregates(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) BatchGetAssetPropertyValue(ctx context.Context, params *iotsitewise.BatchGetAssetPropertyValueInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.BatchGetAssetPropertyValueOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyValue(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "BatchGetAssetPropertyValue", params, func(ctx context.Context) (*iotsitewise.BatchGetAssetPropertyValueOutput, error) {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyValue(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) BatchGetAssetPropertyValueHistory(ctx context.Context, params *iotsitewise.BatchGetAssetPropertyValueHistoryInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyValueHistory(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "BatchGetAssetPropertyValueHistory", params, func(ctx context.Context) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyValueHistory(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) DescribeAsset(ctx context.Context, params *iotsitewise.DescribeAssetInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.DescribeAssetOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.DescribeAsset(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "DescribeAsset", params, func(ctx context.Context) (*iotsitewise.DescribeAssetOutput, error) {
+		return c.SitewiseAPIClient.DescribeAsset(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) DescribeAssetModel(ctx context.Context, params *iotsitewise.DescribeAssetModelInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.DescribeAssetModelOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.DescribeAssetModel(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "DescribeAssetModel", params, func(ctx context.Context) (*iotsitewise.DescribeAssetModelOutput, error) {
+		return c.SitewiseAPIClient.DescribeAssetModel(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ExecuteQuery(ctx context.Context, params *iotsitewise.ExecuteQueryInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ExecuteQueryOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ExecuteQuery(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ExecuteQuery", params, func(ctx context.Context) (*iotsitewise.ExecuteQueryOutput, error) {
+		return c.SitewiseAPIClient.ExecuteQuery(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) GetAssetPropertyAggregates(ctx context.Context, params *iotsitewise.GetAssetPropertyAggregatesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.GetAssetPropertyAggregatesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.GetAssetPropertyAggregates(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "GetAssetPropertyAggregates", params, func(ctx context.Context) (*iotsitewise.GetAssetPropertyAggregatesOutput, error) {
+		return c.SitewiseAPIClient.GetAssetPropertyAggregates(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) GetAssetPropertyValueHistory(ctx context.Context, params *iotsitewise.GetAssetPropertyValueHistoryInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.GetAssetPropertyValueHistoryOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.GetAssetPropertyValueHistory(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "GetAssetPropertyValueHistory", params, func(ctx context.Context) (*iotsitewise.GetAssetPropertyValueHistoryOutput, error) {
+		return c.SitewiseAPIClient.GetAssetPropertyValueHistory(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) GetInterpolatedAssetPropertyValues(ctx context.Context, params *iotsitewise.GetInterpolatedAssetPropertyValuesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.GetInterpolatedAssetPropertyValues(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "GetInterpolatedAssetPropertyValues", params, func(ctx context.Context) (*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, error) {
+		return c.SitewiseAPIClient.GetInterpolatedAssetPropertyValues(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ListAssets(ctx context.Context, params *iotsitewise.ListAssetsInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ListAssetsOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ListAssets(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ListAssets", params, func(ctx context.Context) (*iotsitewise.ListAssetsOutput, error) {
+		return c.SitewiseAPIClient.ListAssets(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ListAssetModels(ctx context.Context, params *iotsitewise.ListAssetModelsInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ListAssetModelsOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ListAssetModels(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ListAssetModels", params, func(ctx context.Context) (*iotsitewise.ListAssetModelsOutput, error) {
+		return c.SitewiseAPIClient.ListAssetModels(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ListAssetProperties(ctx context.Context, params *iotsitewise.ListAssetPropertiesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ListAssetPropertiesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ListAssetProperties(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ListAssetProperties", params, func(ctx context.Context) (*iotsitewise.ListAssetPropertiesOutput, error) {
+		return c.SitewiseAPIClient.ListAssetProperties(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ListAssociatedAssets(ctx context.Context, params *iotsitewise.ListAssociatedAssetsInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ListAssociatedAssetsOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ListAssociatedAssets(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ListAssociatedAssets", params, func(ctx context.Context) (*iotsitewise.ListAssociatedAssetsOutput, error) {
+		return c.SitewiseAPIClient.ListAssociatedAssets(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) ListTimeSeries(ctx context.Context, params *iotsitewise.ListTimeSeriesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.ListTimeSeriesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.ListTimeSeries(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "ListTimeSeries", params, func(ctx context.Context) (*iotsitewise.ListTimeSeriesOutput, error) {
+		return c.SitewiseAPIClient.ListTimeSeries(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) DescribeAssetProperty(ctx context.Context, params *iotsitewise.DescribeAssetPropertyInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.DescribeAssetPropertyOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.DescribeAssetProperty(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "DescribeAssetProperty", params, func(ctx context.Context) (*iotsitewise.DescribeAssetPropertyOutput, error) {
+		return c.SitewiseAPIClient.DescribeAssetProperty(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) DescribeTimeSeries(ctx context.Context, params *iotsitewise.DescribeTimeSeriesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.DescribeTimeSeriesOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.DescribeTimeSeries(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "DescribeTimeSeries", params, func(ctx context.Context) (*iotsitewise.DescribeTimeSeriesOutput, error) {
+		return c.SitewiseAPIClient.DescribeTimeSeries(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) GetAssetPropertyValue(ctx context.Context, params *iotsitewise.GetAssetPropertyValueInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.GetAssetPropertyValueOutput, error) {
+	if len(optFns) > 0 {
+		return c.SitewiseAPIClient.GetAssetPropertyValue(ctx, params, optFns...)
+	}
+	return coalesce(ctx, c, "GetAssetPropertyValue", params, func(ctx context.Context) (*iotsitewise.GetAssetPropertyValueOutput, error) {
+		return c.SitewiseAPIClient.GetAssetPropertyValue(ctx, params)
+	})
+}
+
+func (c *SingleflightClient) BatchGetAssetPropertyValueHistoryPageAggregation(ctx context.Context, req *iotsitewise.BatchGetAssetPropertyValueHistoryInput, maxPages int, maxResults int) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+	return coalesce(ctx, c, "BatchGetAssetPropertyValueHistoryPageAggregation", req, func(ctx context.Context) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, req, maxPages, maxResults)
+	}, maxPages, maxResults)
+}
+
+func (c *SingleflightClient) GetAssetPropertyValueHistoryPageAggregation(ctx context.Context, req *iotsitewise.GetAssetPropertyValueHistoryInput, maxPages int, maxResults int) (*iotsitewise.GetAssetPropertyValueHistoryOutput, error) {
+	return coalesce(ctx, c, "GetAssetPropertyValueHistoryPageAggregation", req, func(ctx context.Context) (*iotsitewise.GetAssetPropertyValueHistoryOutput, error) {
+		return c.SitewiseAPIClient.GetAssetPropertyValueHistoryPageAggregation(ctx, req, maxPages, maxResults)
+	}, maxPages, maxResults)
+}
+
+func (c *SingleflightClient) GetAssetPropertyAggregatesPageAggregation(ctx context.Context, req *iotsitewise.GetAssetPropertyAggregatesInput, maxPages int, maxResults int) (*iotsitewise.GetAssetPropertyAggregatesOutput, error) {
+	return coalesce(ctx, c, "GetAssetPropertyAggregatesPageAggregation", req, func(ctx context.Context) (*iotsitewise.GetAssetPropertyAggregatesOutput, error) {
+		return c.SitewiseAPIClient.GetAssetPropertyAggregatesPageAggregation(ctx, req, maxPages, maxResults)
+	}, maxPages, maxResults)
+}
+
+func (c *SingleflightClient) BatchGetAssetPropertyAggregatesPageAggregation(ctx context.Context, req *iotsitewise.BatchGetAssetPropertyAggregatesInput, maxPages int, maxResults int) (*iotsitewise.BatchGetAssetPropertyAggregatesOutput, error) {
+	return coalesce(ctx, c, "BatchGetAssetPropertyAggregatesPageAggregation", req, func(ctx context.Context) (*iotsitewise.BatchGetAssetPropertyAggregatesOutput, error) {
+		return c.SitewiseAPIClient.BatchGetAssetPropertyAggregatesPageAggregation(ctx, req, maxPages, maxResults)
+	}, maxPages, maxResults)
+}
+
+func (c *SingleflightClient) GetInterpolatedAssetPropertyValuesPageAggregation(ctx context.Context, req *iotsitewise.GetInterpolatedAssetPropertyValuesInput, maxPages int, maxResults int) (*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, error) {
+	return coalesce(ctx, c, "GetInterpolatedAssetPropertyValuesPageAggregation", req, func(ctx context.Context) (*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, error) {
+		return c.SitewiseAPIClient.GetInterpolatedAssetPropertyValuesPageAggregation(ctx, req, maxPages, maxResults)
+	}, maxPages, maxResults)
+}
@@ -0,0 +1,247 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func describeTimeSeriesConcurrently(t *testing.T, sw client.SitewiseAPIClient, aliases ...string) []*iotsitewise.DescribeTimeSeriesOutput {
+	t.Helper()
+
+	outputs := make([]*iotsitewise.DescribeTimeSeriesOutput, len(aliases))
+	var wg sync.WaitGroup
+	for i, alias := range aliases {
+		wg.Add(1)
+		go func(i int, alias string) {
+			defer wg.Done()
+			out, err := sw.DescribeTimeSeries(context.Background(), &iotsitewise.DescribeTimeSeriesInput{Alias: aws.String(alias)})
+			assert.NoError(t, err)
+			outputs[i] = out
+		}(i, alias)
+	}
+	wg.Wait()
+
+	return outputs
+}
+
+func TestSingleflightCoalescesIdenticalCalls(t *testing.T) {
+	release := make(chan time.Time)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeTimeSeries", mock.Anything, mock.Anything).
+		WaitUntil(release).
+		Return(func(_ context.Context, input *iotsitewise.DescribeTimeSeriesInput, _ ...func(*iotsitewise.Options)) *iotsitewise.DescribeTimeSeriesOutput {
+			return &iotsitewise.DescribeTimeSeriesOutput{Alias: input.Alias}
+		}, nil)
+
+	flight := client.NewSingleflight()
+	sw := flight.Client(mockSw, "us-west-2")
+
+	go func() {
+		// let every caller join the in-flight calls before they complete
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	outputs := describeTimeSeriesConcurrently(t, sw, "/a", "/a", "/a", "/b")
+
+	for i, alias := range []string{"/a", "/a", "/a", "/b"} {
+		require.NotNil(t, outputs[i])
+		assert.Equal(t, alias, *outputs[i].Alias)
+	}
+	mockSw.AssertNumberOfCalls(t, "DescribeTimeSeries", 2)
+	assert.Equal(t, int64(2), flight.Misses())
+	assert.Equal(t, int64(2), flight.Hits())
+}
+
+func TestSingleflightScopes(t *testing.T) {
+	release := make(chan time.Time)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeTimeSeries", mock.Anything, mock.Anything).WaitUntil(release).Return(&iotsitewise.DescribeTimeSeriesOutput{}, nil)
+
+	flight := client.NewSingleflight()
+	west, east := flight.Client(mockSw, "us-west-2"), flight.Client(mockSw, "us-east-1")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	var wg sync.WaitGroup
+	for _, sw := range []client.SitewiseAPIClient{west, east} {
+		wg.Add(1)
+		go func(sw client.SitewiseAPIClient) {
+			defer wg.Done()
+			describeTimeSeriesConcurrently(t, sw, "/a")
+		}(sw)
+	}
+	wg.Wait()
+
+	mockSw.AssertNumberOfCalls(t, "DescribeTimeSeries", 2)
+	assert.Equal(t, int64(0), flight.Hits())
+}
+
+func TestSingleflightSharesErrorsButNotCompletedCalls(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, 1, 100).Return(nil, errors.New("throttled")).Once()
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, 1, 100).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{NextToken: aws.String("next")}, nil)
+
+	flight := client.NewSingleflight()
+	sw := flight.Client(mockSw, "us-west-2")
+	input := &iotsitewise.BatchGetAssetPropertyValueHistoryInput{MaxResults: aws.Int32(100)}
+
+	_, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(context.Background(), input, 1, 100)
+	require.EqualError(t, err, "throttled")
+
+	// sequential calls are not coalesced, the failed call is retried
+	out, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(context.Background(), input, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "next", *out.NextToken)
+
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 2)
+	assert.Equal(t, int64(0), flight.Hits())
+	assert.Equal(t, int64(2), flight.Misses())
+}
+
+func TestSingleflightCallerCancellation(t *testing.T) {
+	release := make(chan time.Time)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("ListAssetModels", mock.Anything, mock.Anything).WaitUntil(release).Return(&iotsitewise.ListAssetModelsOutput{}, nil)
+
+	flight := client.NewSingleflight()
+	sw := flight.Client(mockSw, "us-west-2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := sw.ListAssetModels(ctx, &iotsitewise.ListAssetModelsInput{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the shared call keeps running for the callers that are still waiting
+	done := make(chan error)
+	go func() {
+		_, err := sw.ListAssetModels(context.Background(), &iotsitewise.ListAssetModelsInput{})
+		done <- err
+	}()
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func historyPage(nextToken *string, timestamps ...int64) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+	// spare capacity lets an append onto a shared page write into the other callers' values
+	values := make([]iotsitewisetypes.AssetPropertyValue, 0, 16)
+	for _, ts := range timestamps {
+		values = append(values, iotsitewisetypes.AssetPropertyValue{
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(ts)},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(ts))},
+		})
+	}
+	return &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+			EntryId:                   util.GetEntryIdFromAssetProperty("asset", "prop"),
+			AssetPropertyValueHistory: values,
+		}},
+		NextToken: nextToken,
+	}
+}
+
+func TestSingleflightCoalescedCallersPaginateIndependently(t *testing.T) {
+	release := make(chan time.Time)
+	withNextToken := func(token string) interface{} {
+		return mock.MatchedBy(func(input *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+			return util.Dereference(input.NextToken) == token
+		})
+	}
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken(""), mock.Anything, mock.Anything).
+		WaitUntil(release).Return(historyPage(aws.String("page-2"), 1, 2), nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, withNextToken("page-2"), mock.Anything, mock.Anything).
+		Return(func(context.Context, *iotsitewise.BatchGetAssetPropertyValueHistoryInput, int, int) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+			return historyPage(nil, 3, 4)
+		}, nil)
+
+	sw := client.NewSingleflight().Client(mockSw, "us-west-2")
+	query := models.AssetPropertyValueQuery{
+		BaseQuery: models.BaseQuery{
+			AssetIds:            []string{"asset"},
+			PropertyIds:         []string{"prop"},
+			MaxPageAggregations: 1,
+			MaxDataPoints:       100,
+			TimeRange:           backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(100, 0)},
+		},
+		PaginationOptions: models.PaginationOptions{AutoPaginate: true},
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	histories := make([][]iotsitewisetypes.AssetPropertyValue, 2)
+	var wg sync.WaitGroup
+	for i := range histories {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, batch, err := api.BatchGetAssetPropertyValues(context.Background(), sw, query)
+			if assert.NoError(t, err) {
+				histories[i] = batch.Responses[0].SuccessEntries[0].AssetPropertyValueHistory
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, history := range histories {
+		require.Len(t, history, 4)
+		for i, value := range history {
+			assert.Equal(t, int64(i+1), *value.Timestamp.TimeInSeconds)
+		}
+	}
+}
+
+func TestSingleflightCopiesSharedOutputs(t *testing.T) {
+	release := make(chan time.Time)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, 1, 100).
+		WaitUntil(release).Return(historyPage(nil, 1, 2), nil)
+
+	sw := client.NewSingleflight().Client(mockSw, "us-west-2")
+	input := &iotsitewise.BatchGetAssetPropertyValueHistoryInput{MaxResults: aws.Int32(100)}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	outputs := make([]*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, 2)
+	var wg sync.WaitGroup
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(context.Background(), input, 1, 100)
+			assert.NoError(t, err)
+			outputs[i] = out
+		}(i)
+	}
+	wg.Wait()
+
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 1)
+	first, second := outputs[0].SuccessEntries[0], outputs[1].SuccessEntries[0]
+	first.AssetPropertyValueHistory = append(first.AssetPropertyValueHistory, iotsitewisetypes.AssetPropertyValue{})
+	outputs[0].SuccessEntries[0] = first
+	assert.NotSame(t, &outputs[0].SuccessEntries[0], &outputs[1].SuccessEntries[0])
+	assert.NotSame(t, &first.AssetPropertyValueHistory[0], &second.AssetPropertyValueHistory[0])
+	assert.Len(t, outputs[1].SuccessEntries[0].AssetPropertyValueHistory, 2)
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 
 	"github.com/aws/smithy-go/middleware"
@@ -33,6 +32,8 @@ type Datasource struct {
 	edgeAuthenticator *EdgeAuthenticator
 	proxyOptions      *proxy.Options
 	GetClient         clientGetterFunc
+	// singleflight shares identical in-flight calls between the queries of every panel
+	singleflight *client.Singleflight
 }
 
 type disableHostPrefixMiddleware struct{}
@@ -70,6 +71,7 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	ds := &Datasource{
 		Cfg:          cfg,
 		proxyOptions: proxyOptions,
+		singleflight: client.NewSingleflight(),
 	}
 
 	if cfg.Region == models.EDGE_REGION && cfg.EdgeAuthMode != models.EDGE_AUTH_MODE_DEFAULT {
@@ -139,13 +141,30 @@ func (ds *Datasource) getClient(ctx context.Context, region string) (client.Site
 		return nil, err
 	}
 
-	return &client.SitewiseClient{Client: iotsitewise.NewFromConfig(awsCfg, func(o *iotsitewise.Options) {
+	sw := &client.SitewiseClient{Client: iotsitewise.NewFromConfig(awsCfg, func(o *iotsitewise.Options) {
 		if ds.Cfg.Region == models.EDGE_REGION {
 			o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
 				return stack.Initialize.Add(&disableHostPrefixMiddleware{}, middleware.Before)
 			})
 		}
-	})}, nil
+	})}
+	if ds.singleflight == nil {
+		return sw, nil
+	}
+	return ds.singleflight.Client(sw, region), nil
+}
+
+// Singleflight returns the number of SiteWise calls served by an identical call in flight (hits) and sent to SiteWise (misses)
+func (ds *Datasource) Singleflight() (hits int64, misses int64) {
+	if ds.singleflight == nil {
+		return 0, 0
+	}
+	return ds.singleflight.Hits(), ds.singleflight.Misses()
+}
+
+// Client returns the SiteWise client for a region, falling back to the datasource region
+func (ds *Datasource) Client(ctx context.Context, region string) (client.SitewiseAPIClient, error) {
+	return ds.getClient(ctx, region)
 }
 
 func (ds *Datasource) invoke(ctx context.Context, _ *backend.QueryDataRequest, baseQuery *models.BaseQuery, invoker invokerFunc) (data.Frames, error) {
@@ -162,19 +181,32 @@ func (ds *Datasource) invoke(ctx context.Context, _ *backend.QueryDataRequest, b
 	return frameResponse(ctx, *baseQuery, fr, sw)
 }
 
-func (ds *Datasource) HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) error {
-
+// HealthCheck probes every SiteWise API used by the plugin with the datasource credentials
+func (ds *Datasource) HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) (*models.HealthReport, error) {
 	sw, err := ds.getClient(ctx, ds.Cfg.Region)
 	if err != nil {
-		return errors.Wrap(err, "unable to load settings")
+		return nil, errors.Wrap(err, "unable to load settings")
 	}
 
-	// todo: expand health check to test permission boundaries
-	_, err = sw.ListAssetModels(ctx, &iotsitewise.ListAssetModelsInput{MaxResults: aws.Int32(1)})
-	return errors.Wrap(err, "unable to test ListAssetModels")
+	report := api.CheckPermissions(ctx, sw, ds.Cfg.Region == models.EDGE_REGION)
+	if ds.Cfg.ShiftCalendar != nil {
+		if err := ds.Cfg.ShiftCalendar.Validate(); err != nil {
+			report.InvalidSettings = append(report.InvalidSettings, fmt.Sprintf("shift calendar: %s", err.Error()))
+		}
+	}
+	if len(report.InvalidSettings) > 0 && report.Status == models.HealthReportOk {
+		report.Status = models.HealthReportDegraded
+	}
+	return report, nil
 }
 
 func (ds *Datasource) HandleInterpolatedPropertyValueQuery(ctx context.Context, _ *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+	if query.TimeShift != "" {
+		return timeShifted(ctx, query, func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+			return ds.HandleInterpolatedPropertyValueQuery(ctx, nil, query)
+		})
+	}
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
@@ -183,10 +215,58 @@ func (ds *Datasource) HandleInterpolatedPropertyValueQuery(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return propertyValueResponse(ctx, modifiedQuery, fr, sw)
+}
+
+// HandleAnnotationsQuery builds annotation regions from the complete history of string and boolean properties
+func (ds *Datasource) HandleAnnotationsQuery(ctx context.Context, _ *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+	// Batch API is not available at the edge
+	if query.AwsRegion == EDGE_REGION {
+		return nil, errors.New("annotations are not supported at the edge")
+	}
+
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	modifiedQuery, fr, err := api.GetPropertyAnnotations(ctx, sw, *query)
+	if err != nil {
+		return nil, err
+	}
+
+	return propertyValueResponse(ctx, modifiedQuery, fr, sw)
+}
+
+// HandleListAlarmsQuery returns the current state of the alarms of the assets
+func (ds *Datasource) HandleListAlarmsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AlarmQuery) (data.Frames, error) {
+	// Batch API is not available at the edge
+	if query.AwsRegion == EDGE_REGION {
+		return nil, errors.New("alarms are not supported at the edge")
+	}
+
+	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		return api.ListAlarms(ctx, sw, *query)
+	})
+}
+
+// HandleAlarmHistoryQuery returns every state change of the alarms of the assets in the time range
+func (ds *Datasource) HandleAlarmHistoryQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AlarmQuery) (data.Frames, error) {
+	// Batch API is not available at the edge
+	if query.AwsRegion == EDGE_REGION {
+		return nil, errors.New("alarms are not supported at the edge")
+	}
+
+	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		return api.GetAlarmHistory(ctx, sw, *query)
+	})
 }
 
 func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+	if query.TimeShift != "" {
+		return timeShifted(ctx, query, ds.HandleGetAssetPropertyValueHistoryQuery)
+	}
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
@@ -199,7 +279,7 @@ func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Contex
 			return nil, err
 		}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 	}
 
 	modifiedQuery, fr, err := api.BatchGetAssetPropertyValues(ctx, sw, *query)
@@ -207,15 +287,23 @@ func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Contex
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 }
 
 func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+	if query.TimeShift != "" {
+		return timeShifted(ctx, query, ds.HandleGetAssetPropertyAggregateQuery)
+	}
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
 	}
 
+	if query.Resolution == models.ResolutionShift && query.ShiftCalendar == nil {
+		query.ShiftCalendar = ds.Cfg.ShiftCalendar
+	}
+
 	// Batch API is not available at the edge
 	if query.AwsRegion == EDGE_REGION {
 		modifiedQuery, fr, err := api.GetAssetPropertyValuesForTimeRange(ctx, sw, *query)
@@ -223,7 +311,7 @@ func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context,
 			return nil, err
 		}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 	}
 
 	modifiedQuery, fr, err := api.BatchGetAssetPropertyValuesForTimeRange(ctx, sw, *query)
@@ -231,7 +319,7 @@ func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context,
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 }
 
 func (ds *Datasource) HandleGetAssetPropertyValueQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
@@ -247,7 +335,7 @@ func (ds *Datasource) HandleGetAssetPropertyValueQuery(ctx context.Context, quer
 			return nil, err
 		}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 	}
 
 	modifiedQuery, fr, err := api.BatchGetAssetPropertyValue(ctx, sw, *query)
@@ -255,7 +343,7 @@ func (ds *Datasource) HandleGetAssetPropertyValueQuery(ctx context.Context, quer
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return propertyValueResponse(ctx, modifiedQuery, fr, sw)
 }
 
 func (ds *Datasource) HandleListAssetModelsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssetModelsQuery) (data.Frames, error) {
@@ -288,6 +376,18 @@ func (ds *Datasource) HandleDescribeAssetQuery(ctx context.Context, req *backend
 	})
 }
 
+func (ds *Datasource) HandleAssetHierarchyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AssetHierarchyQuery) (data.Frames, error) {
+	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		return api.GetAssetHierarchy(ctx, sw, *query)
+	})
+}
+
+func (ds *Datasource) HandleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetPropertyQuery) (data.Frames, error) {
+	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		return api.GetAssetPropertyDescriptions(ctx, sw, *query)
+	})
+}
+
 func (ds *Datasource) HandleDescribeAssetModelQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetModelQuery) (data.Frames, error) {
 	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
 		return api.DescribeAssetModel(ctx, sw, *query)
@@ -2,12 +2,14 @@ package sitewise
 
 import (
 	"context"
+	"fmt"
 	"github.com/patrickmn/go-cache"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/framer"
 )
@@ -25,8 +27,33 @@ var GetCache = func() func() *cache.Cache {
 	}
 }()
 
+func init() {
+	// the assets of asset model queries are cached with the resources
+	api.GetCache = func() *cache.Cache {
+		return GetCache()
+	}
+}
+
 func frameResponse(ctx context.Context, query models.BaseQuery, data framer.Framer, sw client.SitewiseAPIClient) (data.Frames, error) {
 	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), GetCache())
 	rp := resource.NewQueryResourceProvider(cp, query)
 	return data.Frames(ctx, rp)
 }
+
+// propertyValueResponse frames the response of a property query, with a notice
+// when the property alias pattern matched more data streams than the cap
+func propertyValueResponse(ctx context.Context, query models.AssetPropertyValueQuery, fr framer.Framer, sw client.SitewiseAPIClient) (data.Frames, error) {
+	frames, err := frameResponse(ctx, query.BaseQuery, fr, sw)
+	if err != nil || !query.PropertyAliasMatchesTruncated {
+		return frames, err
+	}
+
+	if len(frames) == 0 {
+		frames = data.Frames{data.NewFrame("")}
+	}
+	frames[0].AppendNotices(data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("property alias pattern %s matched more than %d data streams, only the first %d are queried", query.PropertyAliasPattern, query.PropertyAliasMatches(), query.PropertyAliasMatches()),
+	})
+	return frames, nil
+}
@@ -0,0 +1,88 @@
+package sitewise
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type propertyValueHandler func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
+
+// timeShifted queries the shifted time range of the query, and the time range of the query too when comparing
+// them. The timestamps of the shifted frames are moved back onto the time range of the query by the offset
+// between the starts of the ranges, and their fields are labelled with the period.
+func timeShifted(ctx context.Context, query *models.AssetPropertyValueQuery, handle propertyValueHandler) (data.Frames, error) {
+	shift, err := models.ParseTimeShift(query.TimeShift)
+	if err != nil {
+		return nil, err
+	}
+
+	currentQuery := *query
+	currentQuery.TimeShift = ""
+	shiftedQuery := currentQuery
+	shiftedQuery.TimeRange = backend.TimeRange{From: shift.Apply(query.TimeRange.From), To: shift.Apply(query.TimeRange.To)}
+	// the next tokens of the query belong to its own time range
+	shiftedQuery.NextToken = ""
+	shiftedQuery.NextTokens = nil
+
+	var current, shifted data.Frames
+	eg, ectx := errgroup.WithContext(ctx)
+	if query.TimeShiftCompare {
+		eg.Go(func() (err error) {
+			current, err = handle(ectx, &currentQuery)
+			return err
+		})
+	}
+	eg.Go(func() (err error) {
+		shifted, err = handle(ectx, &shiftedQuery)
+		return err
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	offset := shift.Offset(query.TimeRange.From)
+	for _, frame := range shifted {
+		realignShiftedFrame(frame, shift, offset)
+	}
+	return append(current, shifted...), nil
+}
+
+func realignShiftedFrame(frame *data.Frame, shift models.TimeShift, offset time.Duration) {
+	for _, field := range frame.Fields {
+		switch field.Type() {
+		case data.FieldTypeTime, data.FieldTypeNullableTime:
+			for i := 0; i < field.Len(); i++ {
+				if t, ok := field.ConcreteAt(i); ok {
+					field.SetConcrete(i, t.(time.Time).Add(offset))
+				}
+			}
+		case data.FieldTypeString:
+			if field.Name == fields.Quality {
+				continue
+			}
+			fallthrough
+		default:
+			if field.Labels == nil {
+				field.Labels = data.Labels{}
+			}
+			field.Labels[models.TimeShiftPeriod] = shift.Period()
+		}
+	}
+
+	// the shifted frames are not paginated by the frontend, which pages the time range of the query
+	if frame.Meta == nil {
+		return
+	}
+	if meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta); ok && meta.NextToken != "" {
+		meta.NextToken = ""
+		meta.Truncated = true
+		frame.Meta.Custom = meta
+	}
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GlobToRegexp compiles a glob pattern, where * matches any sequence of characters
+// and ? matches a single character, to a regular expression matching the whole name
+func GlobToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "OEE", name: "OEE", match: true},
+		{pattern: "OEE", name: "OEE Target", match: false},
+		{pattern: "OEE*", name: "OEE Target", match: true},
+		{pattern: "*Temp?", name: "Motor Temp1", match: true},
+		{pattern: "*Temp?", name: "Motor Temp", match: false},
+		{pattern: "/plant1/line*/temp", name: "/plant1/line2/temp", match: true},
+		{pattern: "a.b", name: "axb", match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			actual := GlobToRegexp(tt.pattern).MatchString(tt.name)
+			if actual != tt.match {
+				t.Errorf("expected %v, got %v", tt.match, actual)
+			}
+		})
+	}
+}
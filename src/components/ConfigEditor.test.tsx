@@ -64,4 +64,22 @@ describe('non-edge configuration', () => {
     expect(screen.queryByText('Edge settings')).not.toBeInTheDocument();
     expect(screen.getByText('Authentication Provider')).toBeInTheDocument();
   });
+  it('should show the shift calendar of the settings', () => {
+    render(
+      <ConfigEditor
+        {...defaultProps}
+        options={{
+          ...datasourceOptions,
+          jsonData: {
+            defaultRegion: 'us-east-2',
+            shiftCalendar: { shifts: [{ name: 'Day', start: '06:00', end: '18:00' }] },
+          },
+        }}
+      />
+    );
+    expect(screen.getByTestId('shift-calendar-settings')).toBeInTheDocument();
+    expect(screen.getByLabelText('Shift calendar')).toHaveValue(
+      JSON.stringify({ shifts: [{ name: 'Day', start: '06:00', end: '18:00' }] }, null, 2)
+    );
+  });
 });
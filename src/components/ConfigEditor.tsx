@@ -7,13 +7,14 @@ import {
   updateDatasourcePluginJsonDataOption,
   updateDatasourcePluginSecureJsonDataOption,
 } from '@grafana/data';
-import { SitewiseOptions, SitewiseSecureJsonData } from '../types';
+import { ShiftCalendar, SitewiseOptions, SitewiseSecureJsonData } from '../types';
 import { ConnectionConfig, ConnectionConfigProps, Divider } from '@grafana/aws-sdk';
 import { config } from '@grafana/runtime';
 import { Alert, Button, Field, Input, SecureSocksProxySettings, Select } from '@grafana/ui';
 import { supportedRegions } from '../regions';
 import { ConfigSection } from '@grafana/plugin-ui';
 import { gte } from 'semver';
+import { ShiftCalendarInput } from './ShiftCalendarInput';
 
 // safely remove readonly to please prop types expecting mutable list
 const standardRegions = supportedRegions.map((r) => r);
@@ -34,6 +35,7 @@ export function ConfigEditor(props: Props) {
   return (
     <div className="width-30">
       <ConnectionConfig {...props} standardRegions={standardRegions} />
+      <ShiftCalendarSettings {...props} />
       {config.secureSocksDSProxyEnabled && gte(config.buildInfo.version, '10.0.0') && (
         <SecureSocksProxySettings options={props.options} onOptionsChange={props.onOptionsChange} />
       )}
@@ -183,9 +185,35 @@ function EdgeConfig(props: Props) {
           )}
         </Field>
       </ConfigSection>
+      <ShiftCalendarSettings {...props} />
       {config.secureSocksDSProxyEnabled && gte(config.buildInfo.version, '10.0.0') && (
         <SecureSocksProxySettings options={props.options} onOptionsChange={props.onOptionsChange} />
       )}
     </div>
   );
 }
+
+function ShiftCalendarSettings(props: Props) {
+  const onShiftCalendarChange = (shiftCalendar?: ShiftCalendar) => {
+    updateDatasourcePluginJsonDataOption(props, 'shiftCalendar', shiftCalendar);
+  };
+
+  return (
+    <>
+      <Divider />
+      <ConfigSection title="Shift calendar" data-testid="shift-calendar-settings">
+        <Field
+          label="Shift calendar"
+          description="Used by the SHIFT resolution of the aggregate queries without a shift calendar"
+          htmlFor="shiftCalendar"
+        >
+          <ShiftCalendarInput
+            id="shiftCalendar"
+            value={props.options.jsonData.shiftCalendar}
+            onChange={onShiftCalendarChange}
+          />
+        </Field>
+      </ConfigSection>
+    </>
+  );
+}
@@ -0,0 +1,74 @@
+import React, { useState } from 'react';
+import { FieldValidationMessage, TextArea } from '@grafana/ui';
+import { ShiftCalendar } from '../types';
+
+const PLACEHOLDER = JSON.stringify(
+  {
+    timezone: 'Europe/Berlin',
+    shifts: [
+      { name: 'Day', start: '06:00', end: '18:00' },
+      { name: 'Night', start: '18:00', end: '06:00' },
+    ],
+  },
+  null,
+  2
+);
+
+/**
+ * Parses the JSON of a shift calendar, an empty text clears the calendar
+ */
+export function parseShiftCalendar(text: string): ShiftCalendar | undefined {
+  if (!text.trim()) {
+    return undefined;
+  }
+  const calendar = JSON.parse(text);
+  if (!Array.isArray(calendar?.shifts)) {
+    throw new Error('expected a list of shifts');
+  }
+  return calendar;
+}
+
+function formatShiftCalendar(calendar?: ShiftCalendar): string {
+  return calendar ? JSON.stringify(calendar, null, 2) : '';
+}
+
+/**
+ * JSON editor of a shift calendar, the calendar is only updated once the text is valid
+ */
+export function ShiftCalendarInput({
+  id,
+  value,
+  onChange,
+}: {
+  id: string;
+  value?: ShiftCalendar;
+  onChange: (value?: ShiftCalendar) => void;
+}) {
+  const [text, setText] = useState(formatShiftCalendar(value));
+  const [error, setError] = useState<string>();
+
+  const onBlur = () => {
+    try {
+      onChange(parseShiftCalendar(text));
+      setError(undefined);
+    } catch (e) {
+      setError(`Invalid shift calendar: ${e instanceof Error ? e.message : e}`);
+    }
+  };
+
+  return (
+    <>
+      <TextArea
+        id={id}
+        aria-label="Shift calendar"
+        rows={8}
+        value={text}
+        placeholder={PLACEHOLDER}
+        invalid={!!error}
+        onChange={(e) => setText(e.currentTarget.value)}
+        onBlur={onBlur}
+      />
+      {error && <FieldValidationMessage>{error}</FieldValidationMessage>}
+    </>
+  );
+}
@@ -28,6 +28,27 @@ export const aggReg = new Registry(() => [
   { id: AggregateType.MINIMUM, name: 'Min', isValid: OnlyNumbers },
   { id: AggregateType.SUM, name: 'Sum', isValid: OnlyNumbers },
   { id: AggregateType.STANDARD_DEVIATION, name: 'Stddev', description: 'Standard Deviation', isValid: OnlyNumbers },
+  {
+    id: AggregateType.MEDIAN,
+    name: 'Median',
+    description: 'The 50th percentile, computed from the raw values',
+    isValid: OnlyNumbers,
+  },
+  { id: AggregateType.P90, name: 'P90', description: 'Computed from the raw values', isValid: OnlyNumbers },
+  { id: AggregateType.P95, name: 'P95', description: 'Computed from the raw values', isValid: OnlyNumbers },
+  { id: AggregateType.P99, name: 'P99', description: 'Computed from the raw values', isValid: OnlyNumbers },
+  {
+    id: AggregateType.TIME_WEIGHTED_AVERAGE,
+    name: 'Time weighted average',
+    description: 'Computed from the raw values',
+    isValid: OnlyNumbers,
+  },
+  {
+    id: AggregateType.TIME_WEIGHTED_INTEGRAL,
+    name: 'Time weighted integral',
+    description: 'Computed from the raw values, in value-seconds',
+    isValid: OnlyNumbers,
+  },
 ]);
 
 export class AggregatePicker extends PureComponent<Props> {
@@ -1,11 +1,12 @@
 import React from 'react';
 import { SelectableValue } from '@grafana/data';
-import { AggregateType, SiteWiseResolution, AssetPropertyAggregatesQuery } from 'types';
+import { AggregateType, SiteWiseResolution, AssetPropertyAggregatesQuery, ShiftCalendar } from 'types';
 import { Select } from '@grafana/ui';
 import { EditorField, EditorFieldGroup } from '@grafana/plugin-ui';
 import { getDefaultAggregate } from 'queryInfo';
 import { useOptionsWithVariables } from 'common/useOptionsWithVariables';
 import { AggregatePicker } from './AggregatePicker';
+import { ShiftCalendarInput } from 'components/ShiftCalendarInput';
 
 const RESOLUTIONS: Array<SelectableValue<string>> = [
   {
@@ -19,6 +20,11 @@ const RESOLUTIONS: Array<SelectableValue<string>> = [
   { value: SiteWiseResolution.FifteenMin as string, label: '15 Minutes', description: '1 point every 15 minutes' },
   { value: SiteWiseResolution.Hour as string, label: 'Hour', description: '1 point every hour' },
   { value: SiteWiseResolution.Day as string, label: 'Day', description: '1 point every day' },
+  {
+    value: SiteWiseResolution.Shift as string,
+    label: 'Shift',
+    description: '1 point every shift of the shift calendar',
+  },
 ];
 
 export const AggregationSettings = ({
@@ -38,6 +44,10 @@ export const AggregationSettings = ({
     onChange({ ...query, resolution: sel.value as SiteWiseResolution });
   };
 
+  const onShiftCalendarChange = (shiftCalendar?: ShiftCalendar) => {
+    onChange({ ...query, shiftCalendar });
+  };
+
   return (
     <EditorFieldGroup>
       <EditorField label="Aggregate" htmlFor="aggregate-picker" width={40}>
@@ -59,6 +69,17 @@ export const AggregationSettings = ({
           menuPlacement="auto"
         />
       </EditorField>
+      {query.resolution === SiteWiseResolution.Shift && (
+        <EditorField
+          label="Shift calendar"
+          htmlFor="shiftCalendar"
+          tooltip="Uses the shift calendar of the datasource settings when empty"
+          optional
+          width={40}
+        >
+          <ShiftCalendarInput id="shiftCalendar" value={query.shiftCalendar} onChange={onShiftCalendarChange} />
+        </EditorField>
+      )}
     </EditorFieldGroup>
   );
 };
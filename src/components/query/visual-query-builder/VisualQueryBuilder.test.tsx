@@ -2,7 +2,7 @@ import React from 'react';
 import { render, screen, waitFor } from '@testing-library/react';
 import { DataQueryRequest, DataSourceInstanceSettings, QueryEditorProps } from '@grafana/data';
 import { DataSource } from 'SitewiseDataSource';
-import { QueryType, SitewiseOptions, SitewiseQuery } from 'types';
+import { QueryType, SiteWiseResolution, SitewiseOptions, SitewiseQuery } from 'types';
 import { VisualQueryBuilder } from './VisualQueryBuilder';
 import { of } from 'rxjs';
 import userEvent from '@testing-library/user-event';
@@ -76,6 +76,19 @@ describe('VisualQueryBuilder', () => {
     });
   });
 
+  it('should display the shift calendar for the SHIFT resolution of PropertyAggregate', async () => {
+    await setup({
+      queryType: QueryType.PropertyAggregate,
+      propertyIds: ['prop'],
+      assetIds: ['asset'],
+      resolution: SiteWiseResolution.Shift,
+    });
+    await waitFor(() => {
+      expect(screen.getByText('Shift')).toBeInTheDocument();
+      expect(screen.getByText('Shift calendar')).toBeInTheDocument();
+    });
+  });
+
   it('should display correct fields for query type PropertyAggregate and using Property alias', async () => {
     await setup({
       queryType: QueryType.PropertyAggregate,
@@ -18,6 +18,11 @@ export enum QueryType {
   PropertyInterpolated = 'PropertyInterpolated',
   ListTimeSeries = 'ListTimeSeries',
   ExecuteQuery = 'ExecuteQuery',
+  PropertyAnnotations = 'PropertyAnnotations',
+  ListAlarms = 'ListAlarms',
+  AlarmHistory = 'AlarmHistory',
+  AssetHierarchy = 'AssetHierarchy',
+  DescribeAssetProperty = 'DescribeAssetProperty',
 }
 
 export enum SiteWiseQuality {
@@ -47,6 +52,7 @@ export enum SiteWiseResolution {
   Hour = '1h',
   TenHour = '10h',
   Day = '1d',
+  Shift = 'SHIFT', // buckets of the shift calendar
 }
 
 export enum AggregateType {
@@ -56,11 +62,19 @@ export enum AggregateType {
   MINIMUM = 'MINIMUM',
   SUM = 'SUM',
   STANDARD_DEVIATION = 'STANDARD_DEVIATION',
+  // computed from the raw values, MEDIAN is the 50th percentile
+  MEDIAN = 'MEDIAN',
+  P90 = 'P90',
+  P95 = 'P95',
+  P99 = 'P99',
+  TIME_WEIGHTED_AVERAGE = 'TIME_WEIGHTED_AVERAGE',
+  TIME_WEIGHTED_INTEGRAL = 'TIME_WEIGHTED_INTEGRAL',
 }
 
 export interface SitewiseQuery extends DataQuery {
   queryType: QueryType;
   region?: Region; // aws region string
+  regions?: Region[]; // fan the query out to several regions, the series are labelled with their region
   responseFormat?: SiteWiseResponseFormat;
 
   // QueryEditor
@@ -84,11 +98,41 @@ export interface SitewiseQuery extends DataQuery {
   propertyAlias?: string;
   // One or more properties to fetch data
   propertyAliases?: string[];
+  // Query the property of every asset of the model, selected by propertyIds or propertyName
+  assetModelId?: string;
+  // Without a model, query the properties matching the name or glob pattern below the assets
+  propertyName?: string;
+  hierarchyDepth?: number; // defaults to 3
+  // Query the data streams whose alias matches the glob pattern, or the regular expression
+  propertyAliasPattern?: string;
+  propertyAliasRegex?: boolean;
+  maxPropertyAliasMatches?: number; // defaults to 100
   quality?: SiteWiseQuality;
+  // Several qualities, quality is used when empty
+  qualities?: SiteWiseQuality[];
+  qualityMode?: 'split' | 'nullNotGood';
+  // Downsample the raw values of history queries to the max data points of the panel
+  downsample?: 'lttb' | 'minMax';
+  // Fill the gaps of history and interpolated queries, values after a gap longer than the threshold are stale
+  fill?: 'none' | 'previous' | 'null' | 'zero';
+  stalenessThreshold?: string; // duration, for example 5m
   resolution?: SiteWiseResolution;
   lastObservation?: boolean;
   flattenL4e?: boolean;
+  // Flatten the JSON documents of string and struct properties, the paths are discovered when empty
+  flattenJson?: boolean;
+  jsonPaths?: string[]; // for example status.code or readings.0.value
+  jsonDiscoveryRows?: number; // defaults to 100
   maxPageAggregations?: number;
+  // Follow next tokens in the backend until the range is complete or a budget is exhausted
+  autoPaginate?: boolean;
+  autoPaginateTimeoutMs?: number;
+  autoPaginateMaxDataPoints?: number;
+  // Query the shifted time range, for example -7d or -1y, and compare it with the time range
+  timeShift?: string;
+  timeShiftCompare?: boolean;
+  maxRawDataPoints?: number; // cap of the raw values of the percentile and time weighted aggregates
+  timeWeightedInterpolation?: 'STEP' | 'LINEAR'; // defaults to STEP
   clientCache?: boolean;
 }
 
@@ -133,6 +177,16 @@ export function isListAssociatedAssetsQuery(q?: SitewiseQuery): q is ListAssocia
   return q?.queryType === QueryType.ListAssociatedAssets;
 }
 
+export interface AssetHierarchyQuery extends SitewiseQuery {
+  queryType: QueryType.AssetHierarchy;
+  depth?: number; // number of levels to walk, defaults to 3
+  traversalDirection?: 'CHILD' | 'PARENT';
+}
+
+export function isAssetHierarchyQuery(q?: SitewiseQuery): q is AssetHierarchyQuery {
+  return q?.queryType === QueryType.AssetHierarchy;
+}
+
 /**
  * {@link https://docs.aws.amazon.com/iot-sitewise/latest/APIReference/API_GetAssetPropertyValue.html}
  * {@link https://github.com/grafana/iot-sitewise-datasource/blob/main/pkg/models/property.go#L15}
@@ -165,6 +219,9 @@ export interface AssetPropertyAggregatesQuery extends SitewiseQuery {
 
   resolution?: SiteWiseResolution;
   aggregates: AggregateType[]; // at least one
+  timezone?: string; // aligns 1d and 1w aggregates to local days
+  dayStartOffset?: string; // e.g. 6h
+  shiftCalendar?: ShiftCalendar; // with the SHIFT resolution
 
   timeOrdering?: SiteWiseTimeOrder;
 }
@@ -178,6 +235,9 @@ export function isAssetPropertyAggregatesQuery(q?: SitewiseQuery): q is AssetPro
  */
 export interface AssetPropertyInterpolatedQuery extends SitewiseQuery {
   queryType: QueryType.PropertyInterpolated;
+
+  interpolationType?: 'LINEAR_INTERPOLATION' | 'LOCF_INTERPOLATION';
+  intervalWindowInSeconds?: number; // only with LINEAR_INTERPOLATION
 }
 
 export function isAssetPropertyInterpolatedQuery(q?: SitewiseQuery): q is AssetPropertyInterpolatedQuery {
@@ -265,6 +325,18 @@ export interface SitewiseOptions extends AwsAuthDataSourceJsonData {
   // nothing for now
   edgeAuthMode?: string;
   edgeAuthUser?: string;
+  // Number of queries of a request executed at the same time, defaults to 8
+  maxConcurrentQueries?: number;
+  // Used by the SHIFT resolution of the queries without a shift calendar
+  shiftCalendar?: ShiftCalendar;
+}
+
+/**
+ * Weekly schedule of named shifts, a shift ending at or before its start ends on the next day
+ */
+export interface ShiftCalendar {
+  timezone?: string;
+  shifts: Array<{ name: string; days?: string[]; start: string; end: string }>;
 }
 
 export interface SitewiseSecureJsonData extends AwsAuthDataSourceSecureJsonData {